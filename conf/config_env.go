@@ -9,18 +9,34 @@ import (
 
 // AppConfig presents app conf
 type AppConfig struct {
-	AppEnv           string `envconfig:"APP_ENV" envDefault:"dev"`
-	Port             string `envconfig:"PORT" envDefault:"8088"`
-	LogFormat        string `envconfig:"LOG_FORMAT" envDefault:"text"`
-	DBHost           string `envconfig:"DB_HOST" envDefault:"localhost"`
-	DBPort           string `envconfig:"DB_PORT" envDefault:"5432"`
-	DBUser           string `envconfig:"DB_USER" envDefault:"postgres"`
-	DBPass           string `envconfig:"DB_PASS" envDefault:"1"`
-	DBName           string `envconfig:"DB_NAME" envDefault:"postgres"`
-	EnableDB         string `envconfig:"ENABLE_DB" envDefault:"true"`
-	TwilioAccountSID string `envconfig:"TWILIO_ACCOUNT_SID"`
-	TwilioAuthToken  string `envconfig:"TWILIO_AUTH_TOKEN"`
-	TwilioServiceSID string `envconfig:"VERIFY_SERVICE_SID"`
+	AppEnv           string   `envconfig:"APP_ENV" envDefault:"prd"` // dev enables the development providers
+	Port             string   `envconfig:"PORT" envDefault:"8088"`
+	LogFormat        string   `envconfig:"LOG_FORMAT" envDefault:"text"`
+	DBHost           string   `envconfig:"DB_HOST" envDefault:"localhost"`
+	DBPort           string   `envconfig:"DB_PORT" envDefault:"5432"`
+	DBUser           string   `envconfig:"DB_USER" envDefault:"postgres"`
+	DBPass           string   `envconfig:"DB_PASS" envDefault:"1"`
+	DBName           string   `envconfig:"DB_NAME" envDefault:"postgres"`
+	EnableDB         string   `envconfig:"ENABLE_DB" envDefault:"true"`
+	TwilioAccountSID string   `envconfig:"TWILIO_ACCOUNT_SID"`
+	TwilioAuthToken  string   `envconfig:"TWILIO_AUTH_TOKEN"`
+	TwilioServiceSID string   `envconfig:"VERIFY_SERVICE_SID"`
+	OtpProvider      string   `envconfig:"OTP_PROVIDER" envDefault:"postgres"` // twilio | postgres
+	OtpSender        string   `envconfig:"OTP_SENDER" envDefault:"log"`        // log | file, used by the postgres provider in dev only
+	OtpFilePath      string   `envconfig:"OTP_FILE_PATH" envDefault:"otp.log"`
+	AttemptStore     string   `envconfig:"ATTEMPT_STORE" envDefault:"memory"` // memory | postgres
+	TrustedProxies   []string `envconfig:"TRUSTED_PROXY"`                     // comma separated ips or cidrs of the reverse proxies, none when empty
+	AdminEmail       string   `envconfig:"ADMIN_EMAIL"`                       // first admin, created by the migration
+	AdminPassword    string   `envconfig:"ADMIN_PASSWORD"`
+	MigrateOnStart   bool     `envconfig:"MIGRATE_ON_START"`                 // migrates the tables and seeds the first admin at startup
+	NoShowGrace      int      `envconfig:"NO_SHOW_GRACE" envDefault:"15"`    // minutes, for lots without their own setting
+	NoShowInterval   int      `envconfig:"NO_SHOW_INTERVAL" envDefault:"60"` // seconds between two runs of the no-show worker
+	PaymentProvider  string   `envconfig:"PAYMENT_PROVIDER"`                 // mock, required
+	PaymentMock      bool     `envconfig:"PAYMENT_MOCK_ENABLED"`             // allows the mock provider outside dev
+	PaymentSecret    string   `envconfig:"PAYMENT_SECRET"`                   // signs the webhooks of the provider, required
+	PaymentBaseURL   string   `envconfig:"PAYMENT_BASE_URL"`                 // public url of this server, for pay pages and webhooks
+	PaymentCurrency  string   `envconfig:"PAYMENT_CURRENCY" envDefault:"VND"`
+	PaymentHold      int      `envconfig:"PAYMENT_HOLD" envDefault:"15"` // minutes a booking holds its slot while unpaid
 }
 
 var config *AppConfig
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"parking-server/conf"
+)
+
+const (
+	OTP_PROVIDER_TWILIO   = "twilio"
+	OTP_PROVIDER_POSTGRES = "postgres"
+	OTP_SENDER_LOG        = "log"
+	OTP_SENDER_FILE       = "file"
+)
+
+type OtpProvider interface {
+	SendOtp(ctx context.Context, to string) error
+	CheckOtp(ctx context.Context, to string, code string) (bool, error)
+}
+
+// OtpSender delivers a message to a phone number, it is used by providers that generate codes themselves
+type OtpSender interface {
+	Send(ctx context.Context, to string, message string) error
+}
+
+// NewOtpProvider builds the provider configured by OTP_PROVIDER, the self hosted one is the default
+func NewOtpProvider(cfg *conf.AppConfig, store OtpStore) (OtpProvider, error) {
+	switch cfg.OtpProvider {
+	case OTP_PROVIDER_TWILIO:
+		return NewTwilioClient(cfg), nil
+	case "", OTP_PROVIDER_POSTGRES:
+		sender, err := NewOtpSender(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewPgOtpProvider(store, sender), nil
+	default:
+		return nil, fmt.Errorf("unknown otp provider %q", cfg.OtpProvider)
+	}
+}
+
+// NewOtpSender builds the sender configured by OTP_SENDER. Both senders keep the codes on this server instead
+// of texting them, so they are only built in dev.
+func NewOtpSender(cfg *conf.AppConfig) (OtpSender, error) {
+	if cfg.AppEnv != "dev" {
+		return nil, fmt.Errorf("the log and file otp senders are only available in dev, set OTP_PROVIDER=twilio")
+	}
+	switch cfg.OtpSender {
+	case "", OTP_SENDER_LOG:
+		return &LogSender{}, nil
+	case OTP_SENDER_FILE:
+		path := cfg.OtpFilePath
+		if path == "" {
+			path = "otp.log"
+		}
+		return &FileSender{path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown otp sender %q", cfg.OtpSender)
+	}
+}
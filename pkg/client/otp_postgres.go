@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	otpLength      = 6
+	otpTTL         = 5 * time.Minute
+	otpMaxAttempts = 5
+)
+
+// OtpStore persists the codes of PgOtpProvider, it is implemented by repo.RepoPG
+type OtpStore interface {
+	CreateOtpCode(ctx context.Context, otp *model.OtpCode, tx *gorm.DB) error
+	GetLatestOtpCode(ctx context.Context, phoneNumber string, tx *gorm.DB) (model.OtpCode, error)
+	IncreaseOtpAttempts(ctx context.Context, id uuid.UUID, maxAttempts int, tx *gorm.DB) (bool, error)
+	MarkOtpVerified(ctx context.Context, id uuid.UUID, tx *gorm.DB) error
+}
+
+// PgOtpProvider generates codes itself, only their hash is stored in postgres
+type PgOtpProvider struct {
+	store  OtpStore
+	sender OtpSender
+}
+
+func NewPgOtpProvider(store OtpStore, sender OtpSender) *PgOtpProvider {
+	return &PgOtpProvider{store: store, sender: sender}
+}
+
+func (p *PgOtpProvider) SendOtp(ctx context.Context, to string) error {
+	code, err := generateOtp()
+	if err != nil {
+		return err
+	}
+	otp := &model.OtpCode{
+		PhoneNumber: to,
+		Code:        utils.HashToken(code),
+		ExpiredDate: valid.DayTimePointer(time.Now().Add(otpTTL)),
+	}
+	if err := p.store.CreateOtpCode(ctx, otp, nil); err != nil {
+		return err
+	}
+	return p.sender.Send(ctx, to, fmt.Sprintf("Mã xác thực Parkar của bạn là %s", code))
+}
+
+func (p *PgOtpProvider) CheckOtp(ctx context.Context, to string, code string) (bool, error) {
+	otp, err := p.store.GetLatestOtpCode(ctx, to, nil)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if otp.VerifiedAt != nil || otp.ExpiredDate == nil || otp.ExpiredDate.Before(time.Now()) {
+		return false, nil
+	}
+
+	// every guess counts, so that a code can not be brute forced
+	ok, err := p.store.IncreaseOtpAttempts(ctx, otp.ID, otpMaxAttempts, nil)
+	if err != nil || !ok {
+		return false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(otp.Code), []byte(utils.HashToken(code))) != 1 {
+		return false, nil
+	}
+	if err := p.store.MarkOtpVerified(ctx, otp.ID, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func generateOtp() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < otpLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", otpLength, n), nil
+}
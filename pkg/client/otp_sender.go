@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+// LogSender writes messages to the application log, for development only
+type LogSender struct{}
+
+func (s *LogSender) Send(ctx context.Context, to string, message string) error {
+	logger.WithCtx(ctx, "LogSender").WithField("to", to).Info(message)
+	return nil
+}
+
+// FileSender appends messages to a file, for development and end to end tests
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (s *FileSender) Send(ctx context.Context, to string, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), to, message)
+	return err
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"parking-server/conf"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PAYMENT_PROVIDER_MOCK = "mock"
+)
+
+type PaymentIntentReq struct {
+	PaymentID   uuid.UUID
+	Amount      float64
+	Currency    string
+	Description string
+	ExpiredAt   time.Time
+}
+
+// PaymentIntent is where the payer goes to pay, either by following PayURL or by scanning QRCode
+type PaymentIntent struct {
+	ProviderRef string
+	PayURL      string
+	QRCode      string
+}
+
+// PaymentEvent is the outcome of a charge or a refund, as reported by the provider
+type PaymentEvent struct {
+	EventID     string  `json:"eventId"`
+	ProviderRef string  `json:"providerRef"`
+	Type        string  `json:"type"`   // model.PAYMENT_TX_*
+	Status      string  `json:"status"` // model.PAYMENT_SUCCEEDED or model.PAYMENT_FAILED
+	Amount      float64 `json:"amount"`
+}
+
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, req PaymentIntentReq) (PaymentIntent, error)
+	// ParseWebhook checks the signature of a callback and decodes it
+	ParseWebhook(ctx context.Context, header http.Header, body []byte) (PaymentEvent, error)
+	Refund(ctx context.Context, providerRef string, amount float64) (PaymentEvent, error)
+}
+
+// NewPaymentProvider builds the provider configured by PAYMENT_PROVIDER. The local mock lets anyone mark a
+// payment as paid, so it is only built in dev or when PAYMENT_MOCK_ENABLED opts in.
+func NewPaymentProvider(cfg *conf.AppConfig) (PaymentProvider, error) {
+	if cfg.PaymentSecret == "" {
+		return nil, fmt.Errorf("PAYMENT_SECRET is required to check the webhooks of the payment provider")
+	}
+	switch cfg.PaymentProvider {
+	case "":
+		return nil, fmt.Errorf("PAYMENT_PROVIDER is required")
+	case PAYMENT_PROVIDER_MOCK:
+		if cfg.AppEnv != "dev" && !cfg.PaymentMock {
+			return nil, fmt.Errorf("the mock payment provider is only available in dev or with PAYMENT_MOCK_ENABLED")
+		}
+		baseURL := cfg.PaymentBaseURL
+		if baseURL == "" {
+			baseURL = "http://localhost:" + cfg.Port
+		}
+		return NewMockPaymentProvider(baseURL, cfg.PaymentSecret), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", cfg.PaymentProvider)
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"parking-server/pkg/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+const (
+	MockSignatureHeader = "X-Mock-Signature"
+
+	MOCK_OUTCOME_SUCCESS = "success"
+	MOCK_OUTCOME_FAIL    = "fail"
+)
+
+// MockPaymentProvider is a gateway that runs inside the server, for development and tests. Its pay page
+// is served by the server itself, and paying there posts a signed webhook back to the server, right away
+// or after a delay.
+type MockPaymentProvider struct {
+	baseURL string
+	secret  []byte
+	client  *http.Client
+}
+
+func NewMockPaymentProvider(baseURL string, secret string) *MockPaymentProvider {
+	return &MockPaymentProvider{
+		baseURL: baseURL,
+		secret:  []byte(secret),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *MockPaymentProvider) Name() string {
+	return PAYMENT_PROVIDER_MOCK
+}
+
+func (p *MockPaymentProvider) CreateIntent(ctx context.Context, req PaymentIntentReq) (PaymentIntent, error) {
+	ref := "mock_" + req.PaymentID.String()
+	payURL := fmt.Sprintf("%s/api/v1/payment/mock/%s", p.baseURL, ref)
+	return PaymentIntent{ProviderRef: ref, PayURL: payURL, QRCode: payURL}, nil
+}
+
+func (p *MockPaymentProvider) ParseWebhook(ctx context.Context, header http.Header, body []byte) (PaymentEvent, error) {
+	signature, err := hex.DecodeString(header.Get(MockSignatureHeader))
+	if err != nil || !hmac.Equal(signature, p.sign(body)) {
+		return PaymentEvent{}, errors.New("invalid webhook signature")
+	}
+	var event PaymentEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return PaymentEvent{}, err
+	}
+	return event, nil
+}
+
+func (p *MockPaymentProvider) Refund(ctx context.Context, providerRef string, amount float64) (PaymentEvent, error) {
+	return PaymentEvent{
+		EventID:     "mock_refund_" + uuid.NewString(),
+		ProviderRef: providerRef,
+		Type:        model.PAYMENT_TX_REFUND,
+		Status:      model.PAYMENT_SUCCEEDED,
+		Amount:      amount,
+	}, nil
+}
+
+// Simulate plays the payer on the pay page of the intent: after delay, the webhook of the outcome is
+// posted to the server
+func (p *MockPaymentProvider) Simulate(ctx context.Context, providerRef string, outcome string, delay time.Duration, amount float64) error {
+	status := model.PAYMENT_SUCCEEDED
+	if outcome == MOCK_OUTCOME_FAIL {
+		status = model.PAYMENT_FAILED
+	}
+	body, err := json.Marshal(PaymentEvent{
+		EventID:     "mock_charge_" + uuid.NewString(),
+		ProviderRef: providerRef,
+		Type:        model.PAYMENT_TX_CHARGE,
+		Status:      status,
+		Amount:      amount,
+	})
+	if err != nil {
+		return err
+	}
+	go p.deliver(context.Background(), body, delay)
+	return nil
+}
+
+func (p *MockPaymentProvider) deliver(ctx context.Context, body []byte, delay time.Duration) {
+	log := logger.WithCtx(ctx, "MockPaymentProvider")
+	time.Sleep(delay)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/v1/payment/webhook/"+PAYMENT_PROVIDER_MOCK, bytes.NewReader(body))
+	if err != nil {
+		log.WithError(err).Error("Failed to build mock webhook")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(MockSignatureHeader, hex.EncodeToString(p.sign(body)))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.WithError(err).Error("Failed to deliver mock webhook")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("Mock webhook was answered with %d", resp.StatusCode)
+	}
+}
+
+func (p *MockPaymentProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -1,59 +1,52 @@
 package client
 
 import (
+	"context"
 	"parking-server/conf"
 
 	"github.com/twilio/twilio-go"
 	openapi "github.com/twilio/twilio-go/rest/verify/v2"
 )
 
-var twilioClient *TwilioClient
-
+// TwilioClient delivers and checks codes through Twilio Verify
 type TwilioClient struct {
-	client *twilio.RestClient
+	client     *twilio.RestClient
+	serviceSID string
 }
 
-func init() {
-	cfg := conf.GetConfig()
-
-	twilioClient = &TwilioClient{}
-	twilioClient.client = twilio.NewRestClientWithParams(twilio.ClientParams{
-		Username: cfg.TwilioAccountSID,
-		Password: cfg.TwilioAuthToken,
-	})
+func NewTwilioClient(cfg *conf.AppConfig) *TwilioClient {
+	return &TwilioClient{
+		client: twilio.NewRestClientWithParams(twilio.ClientParams{
+			Username: cfg.TwilioAccountSID,
+			Password: cfg.TwilioAuthToken,
+		}),
+		serviceSID: cfg.TwilioServiceSID,
+	}
 }
 
-func (t *TwilioClient) SendOtp(to string) error {
-	cfg := conf.GetConfig()
-
+func (t *TwilioClient) SendOtp(ctx context.Context, to string) error {
 	params := &openapi.CreateVerificationParams{}
 	params.SetTo(to)
 	params.SetChannel("sms")
 
-	_, err := t.client.VerifyV2.CreateVerification(cfg.TwilioServiceSID, params)
+	_, err := t.client.VerifyV2.CreateVerification(t.serviceSID, params)
 
 	return err
 }
 
-func (t *TwilioClient) CheckOtp(to string, code string) (bool, error) {
-	cfg := conf.GetConfig()
-
+func (t *TwilioClient) CheckOtp(ctx context.Context, to string, code string) (bool, error) {
 	params := &openapi.CreateVerificationCheckParams{}
 	params.SetTo(to)
 	params.SetCode(code)
 
-	resp, err := t.client.VerifyV2.CreateVerificationCheck(cfg.TwilioServiceSID, params)
+	resp, err := t.client.VerifyV2.CreateVerificationCheck(t.serviceSID, params)
 	if err != nil {
 		return false, err
 	}
 
-	if *resp.Status == "approved" {
-		return true, err
+	if resp.Status != nil && *resp.Status == "approved" {
+		return true, nil
 	}
 
 	return false, nil
 }
-
-func GetTwiliioClient() *TwilioClient {
-	return twilioClient
-}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/service"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+
+	"github.com/praslar/lib/common"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+type AdminHandler struct {
+	service service.AdminInterface
+}
+
+func NewAdminHandler(service service.AdminInterface) *AdminHandler {
+	return &AdminHandler{service: service}
+}
+
+func (h *AdminHandler) Login(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	// parse & check valid request
+	var req model.LoginReq
+	if err := r.GinCtx.BindJSON(&req); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+	if err := common.CheckRequireValid(req); err != nil {
+		log.WithError(err).Error("error_400: Fail to check require valid: ", err)
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.service.LoginAdmin(r.Context(), valid.String(req.Email), valid.String(req.Password), clientInfo(r))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{Data: res}}, nil
+}
+
+func (h *AdminHandler) CreateAdmin(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	var req model.AdminReq
+	if err := r.GinCtx.BindJSON(&req); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+	if err := common.CheckRequireValid(req); err != nil {
+		log.WithError(err).Error("error_400: Fail to check require valid: ", err)
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.service.CreateAdmin(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{Data: res}}, nil
+}
+
+func (h *AdminHandler) GetReviewCompanies(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	var req model.ListCompanyReq
+	if err := r.GinCtx.BindQuery(&req); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.service.GetReviewCompanies(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{
+		Data: res.Data,
+		Meta: res.Meta,
+	}}, nil
+}
+
+func (h *AdminHandler) GetReviewParkingLots(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	var req model.GetListParkingLotReq
+	if err := r.GinCtx.BindQuery(&req); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.service.GetReviewParkingLots(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{
+		Data: res.Data,
+		Meta: res.Meta,
+	}}, nil
+}
+
+func (h *AdminHandler) ReviewCompany(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	var req model.ReviewReq
+	if err := r.GinCtx.BindJSON(&req); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+	if err := common.CheckRequireValid(req); err != nil {
+		log.WithError(err).Error("error_400: Fail to check require valid: ", err)
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+	// parse id
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("error_400: Wrong id ")
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+
+	res, err := h.service.ReviewCompany(r.Context(), valid.UUID(id), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{Data: res}}, nil
+}
+
+func (h *AdminHandler) ReviewParkingLot(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	var req model.ReviewReq
+	if err := r.GinCtx.BindJSON(&req); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+	if err := common.CheckRequireValid(req); err != nil {
+		log.WithError(err).Error("error_400: Fail to check require valid: ", err)
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+	// parse id
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("error_400: Wrong id ")
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+
+	res, err := h.service.ReviewParkingLot(r.Context(), valid.UUID(id), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{Data: res}}, nil
+}
+
+func (h *AdminHandler) GetStatusHistory(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	var req model.ListStatusHistoryReq
+	if err := r.GinCtx.BindQuery(&req); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.service.GetStatusHistory(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{
+		Data: res.Data,
+		Meta: res.Meta,
+	}}, nil
+}
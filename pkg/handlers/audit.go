@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/service"
+	"parking-server/pkg/utils"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+type AuditHandler struct {
+	service service.AuditInterface
+}
+
+func NewAuditHandler(service service.AuditInterface) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+func (h *AuditHandler) GetListAuditLog(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	var req model.ListAuditLogReq
+	if err := r.GinCtx.BindQuery(&req); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.service.GetListAuditLog(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{
+		Data: res.Data,
+		Meta: res.Meta,
+	}}, nil
+}
@@ -22,6 +22,10 @@ type AuthHandlerInterface interface {
 	ResetPassword(r *ginext.Request) (*ginext.Response, error)
 	SendOtp(r *ginext.Request) (*ginext.Response, error)
 	VerifyOtp(r *ginext.Request) (*ginext.Response, error)
+	RefreshToken(r *ginext.Request) (*ginext.Response, error)
+	Logout(r *ginext.Request) (*ginext.Response, error)
+	GetSessions(r *ginext.Request) (*ginext.Response, error)
+	RevokeSession(r *ginext.Request) (*ginext.Response, error)
 }
 
 func (h *AuthHandler) Login(r *ginext.Request) (*ginext.Response, error) {
@@ -37,7 +41,7 @@ func (h *AuthHandler) Login(r *ginext.Request) (*ginext.Response, error) {
 		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
 	}
 
-	rs, err := h.service.Login(r.GinCtx, req)
+	rs, err := h.service.Login(r.GinCtx, req, clientInfo(r))
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +51,7 @@ func (h *AuthHandler) Login(r *ginext.Request) (*ginext.Response, error) {
 
 func (h *AuthHandler) ResetPassword(r *ginext.Request) (*ginext.Response, error) {
 	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
-	req := model.Credential{}
+	req := model.ResetPasswordReq{}
 	if err := r.GinCtx.BindJSON(&req); err != nil {
 		log.WithError(err).Error("Invalid input")
 		return nil, ginext.NewError(http.StatusBadRequest, "Invalid input: "+err.Error())
@@ -76,7 +80,7 @@ func (h *AuthHandler) SendOtp(r *ginext.Request) (*ginext.Response, error) {
 		log.WithError(err).Error("Cần nhập đầy đủ thông tin")
 		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
 	}
-	err := h.service.SendOtp(r.GinCtx, req)
+	err := h.service.SendOtp(r.GinCtx, req, clientInfo(r))
 	if err != nil {
 		return nil, err
 	}
@@ -95,9 +99,75 @@ func (h *AuthHandler) VerifyOtp(r *ginext.Request) (*ginext.Response, error) {
 		log.WithError(err).Error("Cần nhập đầy đủ thông tin")
 		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
 	}
-	err := h.service.VerifyOtp(r.GinCtx, req)
+	res, err := h.service.VerifyOtp(r.GinCtx, req, clientInfo(r))
+	if err != nil {
+		return nil, err
+	}
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
+
+func (h *AuthHandler) RefreshToken(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+	req := model.RefreshTokenReq{}
+	if err := r.GinCtx.BindJSON(&req); err != nil {
+		log.WithError(err).Error("Invalid input")
+		return nil, ginext.NewError(http.StatusBadRequest, "Invalid input: "+err.Error())
+	}
+	// check valid req
+	if err := utils.CheckRequireValid(req); err != nil {
+		log.WithError(err).Error("Cần nhập đầy đủ thông tin")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+	rs, err := h.service.RefreshToken(r.GinCtx, req, clientInfo(r))
+	if err != nil {
+		return nil, err
+	}
+	return ginext.NewResponseData(http.StatusOK, rs), nil
+}
+
+func (h *AuthHandler) Logout(r *ginext.Request) (*ginext.Response, error) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+	}
+	if err := h.service.Logout(r.Context(), principal); err != nil {
+		return nil, err
+	}
+	return ginext.NewResponse(http.StatusOK), nil
+}
+
+func (h *AuthHandler) GetSessions(r *ginext.Request) (*ginext.Response, error) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+	}
+	rs, err := h.service.GetSessions(r.Context(), principal)
 	if err != nil {
 		return nil, err
 	}
+	return ginext.NewResponseData(http.StatusOK, rs), nil
+}
+
+func (h *AuthHandler) RevokeSession(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+	}
+	sessionId := utils.ParseIDFromUri(r.GinCtx)
+	if sessionId == nil {
+		log.Error("error_400: Wrong id ")
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+	if err := h.service.RevokeSession(r.Context(), principal, *sessionId); err != nil {
+		return nil, err
+	}
 	return ginext.NewResponse(http.StatusOK), nil
 }
+
+func clientInfo(r *ginext.Request) model.ClientInfo {
+	return model.ClientInfo{
+		UserAgent: r.GinCtx.Request.UserAgent(),
+		IpAddress: r.GinCtx.ClientIP(),
+	}
+}
@@ -56,7 +56,7 @@ func (h *CompanyHandler) Login(r *ginext.Request) (*ginext.Response, error) {
 		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
 	}
 
-	res, err := h.service.LoginCompany(r.Context(), valid.String(req.Email), valid.String(req.Password))
+	res, err := h.service.LoginCompany(r.Context(), valid.String(req.Email), valid.String(req.Password), clientInfo(r))
 	if err != nil {
 		return nil, err
 	}
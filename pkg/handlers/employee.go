@@ -56,7 +56,7 @@ func (h *EmployeeHandler) Login(r *ginext.Request) (*ginext.Response, error) {
 		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
 	}
 
-	res, err := h.service.LoginEmployee(r.Context(), valid.String(req.Email), valid.String(req.Password))
+	res, err := h.service.LoginEmployee(r.Context(), valid.String(req.Email), valid.String(req.Password), clientInfo(r))
 	if err != nil {
 		return nil, err
 	}
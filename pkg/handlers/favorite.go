@@ -27,11 +27,17 @@ type FavoriteHandlerInterface interface {
 
 func (h *FavoriteHandler) Create(r *ginext.Request) (*ginext.Response, error) {
 	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+	userID, err := utils.CurrentUser(r.GinCtx.Request)
+	if err != nil {
+		log.WithError(err).Error("error_401: Error when get current user")
+		return nil, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+	}
 	req := model.FavoriteRequest{}
 	if err := r.GinCtx.BindJSON(&req); err != nil {
 		log.WithError(err).Error("Error when parse req!")
 		return nil, ginext.NewError(http.StatusBadRequest, "Error when parse req: "+err.Error())
 	}
+	req.UserId = userID
 	//check valid
 	if err := utils.CheckRequireValid(req); err != nil {
 		log.WithError(err).Error("Invalid data!")
@@ -45,11 +51,17 @@ func (h *FavoriteHandler) Create(r *ginext.Request) (*ginext.Response, error) {
 }
 func (h *FavoriteHandler) GetOneFavoriteParking(r *ginext.Request) (*ginext.Response, error) {
 	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+	userID, err := utils.CurrentUser(r.GinCtx.Request)
+	if err != nil {
+		log.WithError(err).Error("error_401: Error when get current user")
+		return nil, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+	}
 	req := model.FavoriteRequestV2{}
 	if err := r.GinCtx.BindQuery(&req); err != nil {
 		log.WithError(err).Error("Error when parse req!")
 		return nil, ginext.NewError(http.StatusBadRequest, "Error when parse req: "+err.Error())
 	}
+	req.UserId = valid.StringPointer(userID.String())
 	//check valid
 	if err := utils.CheckRequireValid(req); err != nil {
 		log.WithError(err).Error("Invalid data!")
@@ -63,11 +75,17 @@ func (h *FavoriteHandler) GetOneFavoriteParking(r *ginext.Request) (*ginext.Resp
 }
 func (h *FavoriteHandler) GetAllFavoriteParkingByUser(r *ginext.Request) (*ginext.Response, error) {
 	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+	userID, err := utils.CurrentUser(r.GinCtx.Request)
+	if err != nil {
+		log.WithError(err).Error("error_401: Error when get current user")
+		return nil, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+	}
 	req := model.FavoriteRequestV2{}
 	if err := r.GinCtx.BindQuery(&req); err != nil {
 		log.WithError(err).Error("Error when parse req!")
 		return nil, ginext.NewError(http.StatusBadRequest, "Error when parse req: "+err.Error())
 	}
+	req.UserId = valid.StringPointer(userID.String())
 	res, err := h.service.GetAllFavoriteParkingByUser(r.Context(), valid.String(req.UserId))
 	if err != nil {
 		return nil, err
@@ -1,46 +1,113 @@
 package handlers
 
 import (
+	"context"
 	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
+// the constraint is rebuilt when it predates the pending state
+const ticketNoOverlap = `
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'ticket_no_overlap'
+		AND pg_get_constraintdef(oid) NOT LIKE '%pending%') THEN
+		ALTER TABLE ticket DROP CONSTRAINT ticket_no_overlap;
+	END IF;
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'ticket_no_overlap') THEN
+		ALTER TABLE ticket ADD CONSTRAINT ticket_no_overlap EXCLUDE USING gist (
+			parking_slot_id WITH =,
+			tstzrange(start_time, end_time) WITH &&
+		) WHERE (state IN ('pending', 'new', 'extend', 'ongoing') AND deleted_at IS NULL);
+	END IF;
+END $$`
+
+// one guest vehicle per plate, so that walk-ins of a plate find the same vehicle
+const guestVehicleNumber = `CREATE UNIQUE INDEX IF NOT EXISTS vehicle_guest_number ON vehicle (` + utils.PlateNumberSQL + `)
+	WHERE user_id IS NULL AND deleted_at IS NULL`
+
 type MigrationHandler struct {
-	db *gorm.DB
+	db   *gorm.DB
+	seed func(ctx context.Context) error
 }
 
-func NewMigrationHandler(db *gorm.DB) *MigrationHandler {
-	return &MigrationHandler{db: db}
+// NewMigrationHandler runs seed, if any, once the tables are migrated
+func NewMigrationHandler(db *gorm.DB, seed func(ctx context.Context) error) *MigrationHandler {
+	return &MigrationHandler{db: db, seed: seed}
+}
+
+func (h *MigrationHandler) Migrate(c *gin.Context) {
+	if err := h.Run(c.Request.Context()); err != nil {
+		_ = c.Error(err)
+	}
 }
 
-func (h *MigrationHandler) Migrate(ctx *gin.Context) {
+// Run migrates the tables, then seeds them
+func (h *MigrationHandler) Run(ctx context.Context) error {
 	_ = h.db.Exec("CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\"")
 	_ = h.db.Exec("CREATE EXTENSION IF NOT EXISTS \"postgis\"")
 	_ = h.db.Exec("CREATE EXTENSION IF NOT EXISTS \"unaccent\"")
+	// ticket_no_overlap needs it
+	if err := h.db.Exec("CREATE EXTENSION IF NOT EXISTS \"btree_gist\"").Error; err != nil {
+		return errors.Wrap(err, "Failed to create extension btree_gist")
+	}
 
 	models := []interface{}{
+		model.Admin{},
+		model.AuditLog{},
 		model.Block{},
 		model.Company{},
 		model.Favorite{},
+		model.LoginAttempt{},
 		model.LongTermTicket{},
+		model.Notification{},
+		model.OtpCode{},
 		model.ParkingLot{},
 		model.ParkingSlot{},
+		model.PasswordResetToken{},
+		model.Payment{},
+		model.PaymentTransaction{},
 		model.RefreshToken{},
 		model.Setting{},
+		model.StatusHistory{},
 		model.Ticket{},
+		model.TicketCharge{},
 		model.TicketExtend{},
+		model.TicketStateHistory{},
 		model.TimeFrame{},
 		model.User{},
 		model.Vehicle{},
 		model.Employee{},
+		model.Wallet{},
+		model.WalletTransaction{},
+		model.LedgerEntry{},
+		model.Voucher{},
+		model.VoucherRedemption{},
+		model.Settlement{},
+		model.SettlementLine{},
+		model.PricingRule{},
 	}
 	for _, m := range models {
 		err := h.db.AutoMigrate(m)
 		if err != nil {
-			_ = ctx.Error(err)
-			return
+			return err
 		}
 	}
+	// last line of defense against double booking, behind the slot lock taken when booking.
+	// It cannot be added while overlapping tickets exist, which must then be resolved by hand before migrating again.
+	if err := h.db.Exec(ticketNoOverlap).Error; err != nil {
+		return errors.Wrap(err, "Failed to add ticket_no_overlap constraint")
+	}
+	// fails while a plate has several guest vehicles, which must then be merged by hand before migrating again
+	if err := h.db.Exec(guestVehicleNumber).Error; err != nil {
+		return errors.Wrap(err, "Failed to add vehicle_guest_number index")
+	}
+	if h.seed != nil {
+		return h.seed(ctx)
+	}
+	return nil
 }
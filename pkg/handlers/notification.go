@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/service"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+type NotificationHandler struct {
+	service service.NotificationInterface
+}
+
+func NewNotificationHandler(service service.NotificationInterface) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+func (h *NotificationHandler) GetListNotification(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	var req model.ListNotificationReq
+	if err := r.GinCtx.BindQuery(&req); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.service.GetListNotification(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{
+		Data: res.Data,
+		Meta: res.Meta,
+	}}, nil
+}
+
+func (h *NotificationHandler) ReadNotification(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	// parse id
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("error_400: Wrong id ")
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+
+	if err := h.service.ReadNotification(r.Context(), valid.UUID(id)); err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponse(http.StatusOK), nil
+}
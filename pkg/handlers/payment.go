@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/service"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+	"strconv"
+	"time"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+type PaymentHandler struct {
+	service service.PaymentServiceInterface
+}
+
+func NewPaymentHandler(service service.PaymentServiceInterface) *PaymentHandler {
+	return &PaymentHandler{service: service}
+}
+
+func (h *PaymentHandler) CreatePayment(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	req := model.CreatePaymentReq{}
+	if err := r.GinCtx.BindJSON(&req); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.service.CreatePayment(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusCreated, res), nil
+}
+
+func (h *PaymentHandler) GetOnePayment(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("error_400: Wrong id ")
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+
+	res, err := h.service.GetOnePayment(r.Context(), valid.UUID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
+
+// HandleWebhook receives the callbacks of the payment provider, their signature is checked on the raw body
+func (h *PaymentHandler) HandleWebhook(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	body, err := r.GinCtx.GetRawData()
+	if err != nil {
+		log.WithError(err).Error("error_400: Error when read webhook body")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.service.HandleWebhook(r.Context(), r.GinCtx.Param("provider"), r.GinCtx.Request.Header, body); err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, map[string]bool{"received": true}), nil
+}
+
+// SimulateMockPayment is the pay page of the mock provider, e.g. ?outcome=fail&delay=30 for a failure
+// reported 30 seconds later
+func (h *PaymentHandler) SimulateMockPayment(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	delay := 0
+	if raw := r.GinCtx.Query("delay"); raw != "" {
+		var err error
+		if delay, err = strconv.Atoi(raw); err != nil {
+			log.WithError(err).Error("error_400: Wrong delay")
+			return nil, ginext.NewError(http.StatusBadRequest, "Wrong delay")
+		}
+	}
+
+	res, err := h.service.SimulateMockPayment(r.Context(), r.GinCtx.Param("ref"), r.GinCtx.Query("outcome"), time.Duration(delay)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusAccepted, res), nil
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"parking-server/pkg/service"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+type SettingHandler struct {
+	service service.SettingInterface
+}
+
+func NewSettingHandler(service service.SettingInterface) *SettingHandler {
+	return &SettingHandler{service: service}
+}
+
+func (h *SettingHandler) GetParkingLotSetting(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("error_400: Wrong id ")
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+
+	res, err := h.service.GetParkingLotSetting(r.Context(), valid.UUID(id), r.GinCtx.Param("key"))
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
+
+func (h *SettingHandler) UpdateParkingLotSetting(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("error_400: Wrong id ")
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+
+	var value json.RawMessage
+	if err := r.GinCtx.BindJSON(&value); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.service.UpdateParkingLotSetting(r.Context(), valid.UUID(id), r.GinCtx.Param("key"), value)
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
+
+func (h *SettingHandler) GetCompanySetting(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("error_400: Wrong id ")
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+
+	res, err := h.service.GetCompanySetting(r.Context(), valid.UUID(id), r.GinCtx.Param("key"))
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
+
+func (h *SettingHandler) UpdateCompanySetting(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("error_400: Wrong id ")
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+
+	var value json.RawMessage
+	if err := r.GinCtx.BindJSON(&value); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.service.UpdateCompanySetting(r.Context(), valid.UUID(id), r.GinCtx.Param("key"), value)
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
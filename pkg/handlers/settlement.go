@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/service"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+type SettlementHandler struct {
+	service service.SettlementInterface
+}
+
+func NewSettlementHandler(service service.SettlementInterface) *SettlementHandler {
+	return &SettlementHandler{service: service}
+}
+
+func (h *SettlementHandler) GetListSettlement(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	var req model.ListSettlementReq
+	if err := r.GinCtx.BindQuery(&req); err != nil {
+		log.WithError(err).Error("error_400: Error when get parse req")
+		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	res, err := h.service.GetListSettlement(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{
+		Data: res.Data,
+		Meta: res.Meta,
+	}}, nil
+}
+
+func (h *SettlementHandler) GetOneSettlement(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("error_400: Wrong id ")
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+
+	res, err := h.service.GetOneSettlement(r.Context(), valid.UUID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{Data: res}}, nil
+}
+
+func (h *SettlementHandler) GetCurrentSettlement(r *ginext.Request) (*ginext.Response, error) {
+	companyID, _ := uuid.Parse(r.GinCtx.Query("companyId"))
+
+	res, err := h.service.GetCurrentSettlement(r.Context(), companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{Data: res}}, nil
+}
+
+// ExportSettlement answers with the CSV file itself rather than a JSON body
+func (h *SettlementHandler) ExportSettlement(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
+
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("error_400: Wrong id ")
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+
+	b, name, err := h.service.ExportSettlement(r.Context(), valid.UUID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	r.GinCtx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	r.GinCtx.Data(http.StatusOK, "text/csv; charset=utf-8", b)
+	return nil, nil
+}
@@ -28,12 +28,21 @@ type TicketHandlerInterface interface {
 	ExtendTicket(r *ginext.Request) (*ginext.Response, error)
 	GetAllTicketCompany(r *ginext.Request) (*ginext.Response, error)
 	ReviewTicket(r *ginext.Request) (*ginext.Response, error)
+	QuoteTicket(r *ginext.Request) (*ginext.Response, error)
+	GetTicketTimeline(r *ginext.Request) (*ginext.Response, error)
+	GetListLongTermTicket(r *ginext.Request) (*ginext.Response, error)
+	GetOneLongTermTicket(r *ginext.Request) (*ginext.Response, error)
+	CancelLongTermTicket(r *ginext.Request) (*ginext.Response, error)
+	PreviewCheckout(r *ginext.Request) (*ginext.Response, error)
+	CreateWalkInTicket(r *ginext.Request) (*ginext.Response, error)
+	GateCheckIn(r *ginext.Request) (*ginext.Response, error)
+	GateCheckOut(r *ginext.Request) (*ginext.Response, error)
 }
 
 func (h *TicketHandler) CreateTicket(r *ginext.Request) (*ginext.Response, error) {
 	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
 	// check x-user-id
-	_, err := utils.CurrentUser(r.GinCtx.Request)
+	userID, err := utils.CurrentUser(r.GinCtx.Request)
 	if err != nil {
 		log.WithError(err).Error("error_401: Error when get current user")
 		return nil, ginext.NewError(http.StatusBadRequest, utils.MessageError()[http.StatusUnauthorized])
@@ -43,6 +52,7 @@ func (h *TicketHandler) CreateTicket(r *ginext.Request) (*ginext.Response, error
 		log.WithError(err).Error("Error when parse req!")
 		return nil, ginext.NewError(http.StatusBadRequest, "Error when parse req: "+err.Error())
 	}
+	req.UserId = &userID
 	res, err := h.service.CreateTicket(r.Context(), &req)
 	if err != nil {
 		return nil, err
@@ -50,6 +60,56 @@ func (h *TicketHandler) CreateTicket(r *ginext.Request) (*ginext.Response, error
 	return ginext.NewResponseData(http.StatusCreated, res), nil
 }
 
+func (h *TicketHandler) CreateWalkInTicket(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+	req := model.WalkInTicketReq{}
+	if err := r.GinCtx.BindJSON(&req); err != nil {
+		log.WithError(err).Error("Error when parse req!")
+		return nil, ginext.NewError(http.StatusBadRequest, "Error when parse req: "+err.Error())
+	}
+	// check valid
+	if err := utils.CheckRequireValid(req); err != nil {
+		log.WithError(err).Error("Invalid data!")
+		return nil, ginext.NewError(http.StatusBadRequest, "Invalid data: "+err.Error())
+	}
+	res, err := h.service.CreateWalkInTicket(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ginext.NewResponseData(http.StatusCreated, res), nil
+}
+
+func (h *TicketHandler) GateCheckIn(r *ginext.Request) (*ginext.Response, error) {
+	return h.gateProcedure(r, model.PROCEDURE_CHECK_IN)
+}
+
+func (h *TicketHandler) GateCheckOut(r *ginext.Request) (*ginext.Response, error) {
+	return h.gateProcedure(r, model.PROCEDURE_CHECK_OUT)
+}
+
+func (h *TicketHandler) gateProcedure(r *ginext.Request, procedure string) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+	req := model.GateProcedureReq{}
+	if err := r.GinCtx.BindJSON(&req); err != nil {
+		log.WithError(err).Error("Error when parse req!")
+		return nil, ginext.NewError(http.StatusBadRequest, "Error when parse req: "+err.Error())
+	}
+	// check valid
+	if err := utils.CheckRequireValid(req); err != nil {
+		log.WithError(err).Error("Invalid data!")
+		return nil, ginext.NewError(http.StatusBadRequest, "Invalid data: "+err.Error())
+	}
+	res, err := h.service.GateProcedure(r.Context(), req, procedure)
+	if err != nil {
+		if res != nil && len(res.Candidates) > 0 {
+			// several tickets match the plate, let the staff pick one
+			return &ginext.Response{Code: http.StatusConflict, Body: &ginext.GeneralBody{Data: res}}, nil
+		}
+		return nil, err
+	}
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
+
 func (h *TicketHandler) ProcedureWithTicket(r *ginext.Request) (*ginext.Response, error) {
 	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
 	req := model.ProcedureReq{}
@@ -79,6 +139,10 @@ func (h *TicketHandler) ExtendTicket(r *ginext.Request) (*ginext.Response, error
 	}
 	res, err := h.service.ExtendTicket(r.Context(), &req)
 	if err != nil {
+		if res != nil && res.Alternative != nil {
+			// the slot is taken, let the driver retry with the free one
+			return &ginext.Response{Code: http.StatusConflict, Body: &ginext.GeneralBody{Data: res}}, nil
+		}
 		return nil, err
 	}
 	return ginext.NewResponseData(http.StatusCreated, res), nil
@@ -86,11 +150,17 @@ func (h *TicketHandler) ExtendTicket(r *ginext.Request) (*ginext.Response, error
 
 func (h *TicketHandler) GetAllTicket(r *ginext.Request) (*ginext.Response, error) {
 	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+	userID, err := utils.CurrentUser(r.GinCtx.Request)
+	if err != nil {
+		log.WithError(err).Error("error_401: Error when get current user")
+		return nil, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+	}
 	req := model.GetListTicketParam{}
 	if err := r.GinCtx.BindQuery(&req); err != nil {
 		log.WithError(err).Error("Error when parse req!")
 		return nil, ginext.NewError(http.StatusBadRequest, "Error when parse req: "+err.Error())
 	}
+	req.UserId = valid.StringPointer(userID.String())
 	// check valid
 	if err := utils.CheckRequireValid(req); err != nil {
 		log.WithError(err).Error("Invalid data!")
@@ -100,7 +170,10 @@ func (h *TicketHandler) GetAllTicket(r *ginext.Request) (*ginext.Response, error
 	if err != nil {
 		return nil, err
 	}
-	return ginext.NewResponseData(http.StatusOK, res), nil
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{
+		Data: res.Data,
+		Meta: res.Meta,
+	}}, nil
 }
 
 func (h *TicketHandler) GetOneTicketWithExtend(r *ginext.Request) (*ginext.Response, error) {
@@ -126,11 +199,11 @@ func (h *TicketHandler) CancelTicket(r *ginext.Request) (*ginext.Response, error
 		log.WithError(err).Error("Error when parse req!")
 		return nil, ginext.NewError(http.StatusBadRequest, "Error when parse req: "+err.Error())
 	}
-	err := h.service.CancelTicket(r.Context(), req.TicketId)
+	res, err := h.service.CancelTicket(r.Context(), req.TicketId)
 	if err != nil {
 		return nil, err
 	}
-	return ginext.NewResponseData(http.StatusOK, req.TicketId), nil
+	return ginext.NewResponseData(http.StatusOK, res), nil
 }
 
 func (h *TicketHandler) GetAllTicketCompany(r *ginext.Request) (*ginext.Response, error) {
@@ -152,7 +225,10 @@ func (h *TicketHandler) GetAllTicketCompany(r *ginext.Request) (*ginext.Response
 		return nil, err
 	}
 
-	return ginext.NewResponseData(http.StatusOK, res), nil
+	return &ginext.Response{Code: http.StatusOK, Body: &ginext.GeneralBody{
+		Data: res.Data,
+		Meta: res.Meta,
+	}}, nil
 }
 
 func (h *TicketHandler) ReviewTicket(r *ginext.Request) (*ginext.Response, error) {
@@ -190,3 +266,96 @@ func (h *TicketHandler) ReviewTicket(r *ginext.Request) (*ginext.Response, error
 
 	return ginext.NewResponseData(http.StatusCreated, "ok"), nil
 }
+
+func (h *TicketHandler) QuoteTicket(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+	req := model.QuoteReq{}
+	if err := r.GinCtx.BindJSON(&req); err != nil {
+		log.WithError(err).Error("Error when parse req!")
+		return nil, ginext.NewError(http.StatusBadRequest, "Error when parse req: "+err.Error())
+	}
+	// check valid
+	if err := utils.CheckRequireValid(req); err != nil {
+		log.WithError(err).Error("Invalid data!")
+		return nil, ginext.NewError(http.StatusBadRequest, "Invalid data: "+err.Error())
+	}
+	res, err := h.service.QuoteTicket(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
+
+func (h *TicketHandler) GetTicketTimeline(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+
+	ticketId := utils.ParseIDFromUri(r.GinCtx)
+	if ticketId == nil {
+		log.Error("Ticket id is required")
+		return nil, ginext.NewError(http.StatusBadRequest, "Ticket id is required")
+	}
+	res, err := h.service.GetTicketTimeline(r.Context(), valid.UUID(ticketId).String())
+	if err != nil {
+		return nil, err
+	}
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
+
+func (h *TicketHandler) GetListLongTermTicket(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+	req := model.ListLongTermTicketReq{}
+	if err := r.GinCtx.BindQuery(&req); err != nil {
+		log.WithError(err).Error("Error when parse req!")
+		return nil, ginext.NewError(http.StatusBadRequest, "Error when parse req: "+err.Error())
+	}
+	res, err := h.service.GetListLongTermTicket(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
+
+func (h *TicketHandler) GetOneLongTermTicket(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("Long term ticket id is required")
+		return nil, ginext.NewError(http.StatusBadRequest, "Long term ticket id is required")
+	}
+	res, err := h.service.GetOneLongTermTicket(r.Context(), valid.UUID(id))
+	if err != nil {
+		return nil, err
+	}
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
+
+func (h *TicketHandler) CancelLongTermTicket(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+
+	id := utils.ParseIDFromUri(r.GinCtx)
+	if id == nil {
+		log.Error("Long term ticket id is required")
+		return nil, ginext.NewError(http.StatusBadRequest, "Long term ticket id is required")
+	}
+	res, err := h.service.CancelLongTermTicket(r.Context(), valid.UUID(id))
+	if err != nil {
+		return nil, err
+	}
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
+
+func (h *TicketHandler) PreviewCheckout(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, utils.GetCurrentCaller(h, 0))
+
+	ticketId := utils.ParseIDFromUri(r.GinCtx)
+	if ticketId == nil {
+		log.Error("Ticket id is required")
+		return nil, ginext.NewError(http.StatusBadRequest, "Ticket id is required")
+	}
+	res, err := h.service.PreviewCheckout(r.Context(), valid.UUID(ticketId).String())
+	if err != nil {
+		return nil, err
+	}
+	return ginext.NewResponseData(http.StatusOK, res), nil
+}
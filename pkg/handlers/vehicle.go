@@ -32,7 +32,7 @@ func (h *VehicleHandler) CreateVehicle(r *ginext.Request) (*ginext.Response, err
 	log := logger.WithCtx(r.Context(), utils.GetCurrentCaller(h, 0))
 
 	// check x-user-id
-	_, err := utils.CurrentUser(r.GinCtx.Request)
+	userID, err := utils.CurrentUser(r.GinCtx.Request)
 	if err != nil {
 		log.WithError(err).Error("error_401: Error when get current user")
 		return nil, ginext.NewError(http.StatusBadRequest, utils.MessageError()[http.StatusUnauthorized])
@@ -44,6 +44,7 @@ func (h *VehicleHandler) CreateVehicle(r *ginext.Request) (*ginext.Response, err
 		log.WithError(err).Error("error_400: Error when get parse req")
 		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
 	}
+	req.UserID = &userID
 	if err := common.CheckRequireValid(req); err != nil {
 		log.WithError(err).Error("error_400: Fail to check require valid: ", err)
 		return nil, ginext.NewError(http.StatusBadRequest, err.Error())
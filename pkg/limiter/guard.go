@@ -0,0 +1,89 @@
+package limiter
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Guard groups the limiters protecting the login and otp endpoints
+type Guard struct {
+	login     *Limiter
+	ip        *Limiter
+	otpSend   *Limiter
+	otpVerify *Limiter
+}
+
+func NewGuard(store Store) *Guard {
+	return &Guard{
+		login: New("login", store, Policy{
+			MaxFailures: 5, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour,
+		}),
+		// an ip may be shared by many users, e.g. behind a NAT
+		ip: New("ip", store, Policy{
+			MaxFailures: 30, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour,
+		}),
+		otpSend: New("otp-send", store, Policy{
+			MaxFailures: 3, Window: 15 * time.Minute, BaseLockout: 5 * time.Minute, MaxLockout: 24 * time.Hour,
+		}),
+		otpVerify: New("otp-verify", store, Policy{
+			MaxFailures: 5, Window: 15 * time.Minute, BaseLockout: 5 * time.Minute, MaxLockout: time.Hour,
+		}),
+	}
+}
+
+// CheckLogin rejects the login when either the account or the client ip is locked
+func (g *Guard) CheckLogin(ctx context.Context, identifier string, ip string) error {
+	if err := g.login.Check(ctx, normalize(identifier)); err != nil {
+		return err
+	}
+	return g.ip.Check(ctx, ip)
+}
+
+func (g *Guard) FailLogin(ctx context.Context, identifier string, ip string) error {
+	if err := g.login.Fail(ctx, normalize(identifier)); err != nil {
+		return err
+	}
+	return g.ip.Fail(ctx, ip)
+}
+
+// ResetLogin clears the account counter, the ip one keeps running
+func (g *Guard) ResetLogin(ctx context.Context, identifier string) error {
+	return g.login.Reset(ctx, normalize(identifier))
+}
+
+// HitOtpSend throttles the codes sent to a phone number and from an ip, every send counts
+func (g *Guard) HitOtpSend(ctx context.Context, phoneNumber string, ip string) error {
+	if err := g.otpSend.Check(ctx, phoneNumber); err != nil {
+		return err
+	}
+	if err := g.ip.Check(ctx, ip); err != nil {
+		return err
+	}
+	if err := g.otpSend.Fail(ctx, phoneNumber); err != nil {
+		return err
+	}
+	return g.ip.Fail(ctx, ip)
+}
+
+func (g *Guard) CheckOtpVerify(ctx context.Context, phoneNumber string, ip string) error {
+	if err := g.otpVerify.Check(ctx, phoneNumber); err != nil {
+		return err
+	}
+	return g.ip.Check(ctx, ip)
+}
+
+func (g *Guard) FailOtpVerify(ctx context.Context, phoneNumber string, ip string) error {
+	if err := g.otpVerify.Fail(ctx, phoneNumber); err != nil {
+		return err
+	}
+	return g.ip.Fail(ctx, ip)
+}
+
+func (g *Guard) ResetOtpVerify(ctx context.Context, phoneNumber string) error {
+	return g.otpVerify.Reset(ctx, phoneNumber)
+}
+
+func normalize(identifier string) string {
+	return strings.ToLower(strings.TrimSpace(identifier))
+}
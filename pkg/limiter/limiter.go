@@ -0,0 +1,86 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"parking-server/pkg/utils"
+	"time"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+// Policy locks an identifier once MaxFailures is reached inside Window, every further failure doubles
+// the lockout, starting at BaseLockout and capped at MaxLockout
+type Policy struct {
+	MaxFailures int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+type Limiter struct {
+	name   string
+	store  Store
+	policy Policy
+}
+
+func New(name string, store Store, policy Policy) *Limiter {
+	return &Limiter{name: name, store: store, policy: policy}
+}
+
+// Check returns a 429 error while the identifier is locked
+func (l *Limiter) Check(ctx context.Context, identifier string) error {
+	if identifier == "" {
+		return nil
+	}
+	attempt, err := l.store.Get(ctx, l.key(identifier))
+	if err != nil {
+		return err
+	}
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(time.Now()) {
+		return ginext.NewError(http.StatusTooManyRequests, utils.MessageError()[http.StatusTooManyRequests])
+	}
+	return nil
+}
+
+// Fail records a failure and locks the identifier when the policy says so
+func (l *Limiter) Fail(ctx context.Context, identifier string) error {
+	if identifier == "" {
+		return nil
+	}
+	attempt, err := l.store.Increase(ctx, l.key(identifier), l.policy.Window)
+	if err != nil {
+		return err
+	}
+	if attempt.Failures < l.policy.MaxFailures {
+		return nil
+	}
+	lockout := l.lockout(attempt.Failures - l.policy.MaxFailures)
+	logger.WithCtx(ctx, "Limiter").WithField("identifier", l.key(identifier)).
+		WithField("failures", attempt.Failures).Warn("Too many attempts, locked for " + lockout.String())
+	return l.store.Lock(ctx, l.key(identifier), time.Now().Add(lockout))
+}
+
+func (l *Limiter) Reset(ctx context.Context, identifier string) error {
+	if identifier == "" {
+		return nil
+	}
+	return l.store.Reset(ctx, l.key(identifier))
+}
+
+func (l *Limiter) lockout(exceeded int) time.Duration {
+	lockout := l.policy.BaseLockout
+	for i := 0; i < exceeded && lockout < l.policy.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > l.policy.MaxLockout {
+		lockout = l.policy.MaxLockout
+	}
+	return lockout
+}
+
+func (l *Limiter) key(identifier string) string {
+	return fmt.Sprintf("%s:%s", l.name, identifier)
+}
@@ -0,0 +1,109 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/utils"
+	"testing"
+	"time"
+
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+func isLocked(err error) bool {
+	var apiErr ginext.ApiError
+	return errors.As(err, &apiErr) && apiErr.Code() == http.StatusTooManyRequests
+}
+
+func TestLimiterLocksAfterMaxFailures(t *testing.T) {
+	utils.LoadMessageError()
+	ctx := context.Background()
+	l := New("test", NewMemoryStore(), Policy{MaxFailures: 3, Window: time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour})
+
+	for i := 1; i <= 3; i++ {
+		if err := l.Check(ctx, "user"); err != nil {
+			t.Fatalf("check before failure %d: %v, want it allowed", i, err)
+		}
+		if err := l.Fail(ctx, "user"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Check(ctx, "user"); !isLocked(err) {
+		t.Fatalf("got %v after 3 failures, want a 429", err)
+	}
+	if err := l.Check(ctx, "other"); err != nil {
+		t.Errorf("got %v for another identifier, want it allowed", err)
+	}
+	if err := l.Reset(ctx, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Check(ctx, "user"); err != nil {
+		t.Errorf("got %v after reset, want it allowed", err)
+	}
+}
+
+func TestLimiterIgnoresEmptyIdentifier(t *testing.T) {
+	ctx := context.Background()
+	l := New("test", NewMemoryStore(), Policy{MaxFailures: 1, Window: time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour})
+	for i := 0; i < 3; i++ {
+		if err := l.Fail(ctx, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Check(ctx, ""); err != nil {
+		t.Errorf("got %v, want an empty identifier never locked", err)
+	}
+}
+
+func TestLimiterLockout(t *testing.T) {
+	l := New("test", nil, Policy{BaseLockout: time.Minute, MaxLockout: time.Hour})
+	tests := []struct {
+		exceeded int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{3, 8 * time.Minute},
+		{5, 32 * time.Minute},
+		{6, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := l.lockout(tt.exceeded); got != tt.want {
+			t.Errorf("lockout(%d) = %v, want %v", tt.exceeded, got, tt.want)
+		}
+	}
+}
+
+func TestGuardLogin(t *testing.T) {
+	utils.LoadMessageError()
+	ctx := context.Background()
+	g := NewGuard(NewMemoryStore())
+
+	// the account counter ignores case and spaces, whatever ip the attempts come from
+	for i := 0; i < 5; i++ {
+		if err := g.FailLogin(ctx, " User@Parkar.vn ", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.CheckLogin(ctx, "user@parkar.vn", "10.0.0.2"); !isLocked(err) {
+		t.Errorf("got %v, want the account locked", err)
+	}
+	if err := g.ResetLogin(ctx, "USER@parkar.vn"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.CheckLogin(ctx, "user@parkar.vn", "10.0.0.1"); err != nil {
+		t.Errorf("got %v after reset, want the login allowed", err)
+	}
+
+	// an ip failing on many accounts is locked on its own
+	for i := 0; i < 30; i++ {
+		if err := g.FailLogin(ctx, string(rune('a'+i%26))+"@parkar.vn", "10.0.0.3"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.CheckLogin(ctx, "fresh@parkar.vn", "10.0.0.3"); !isLocked(err) {
+		t.Errorf("got %v, want the ip locked", err)
+	}
+}
@@ -0,0 +1,134 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"parking-server/pkg/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	STORE_MEMORY   = "memory"
+	STORE_POSTGRES = "postgres"
+)
+
+// Store keeps the attempt counters, MemoryStore serves a single node and PgStore a cluster
+type Store interface {
+	Get(ctx context.Context, identifier string) (model.LoginAttempt, error)
+	Increase(ctx context.Context, identifier string, window time.Duration) (model.LoginAttempt, error)
+	Lock(ctx context.Context, identifier string, until time.Time) error
+	Reset(ctx context.Context, identifier string) error
+}
+
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]*model.LoginAttempt
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: map[string]*model.LoginAttempt{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, identifier string) (model.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if attempt, ok := s.attempts[identifier]; ok {
+		return *attempt, nil
+	}
+	return model.LoginAttempt{Identifier: identifier}, nil
+}
+
+func (s *MemoryStore) Increase(ctx context.Context, identifier string, window time.Duration) (model.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.prune(now, window)
+
+	attempt, ok := s.attempts[identifier]
+	if !ok {
+		attempt = &model.LoginAttempt{Identifier: identifier}
+		s.attempts[identifier] = attempt
+	}
+	if attempt.LastFailedAt != nil && attempt.LastFailedAt.Before(now.Add(-window)) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailedAt = &now
+	return *attempt, nil
+}
+
+func (s *MemoryStore) Lock(ctx context.Context, identifier string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if attempt, ok := s.attempts[identifier]; ok {
+		attempt.LockedUntil = &until
+	}
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, identifier string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, identifier)
+	return nil
+}
+
+// prune drops counters that are neither locked nor inside the window, so the map does not grow forever
+func (s *MemoryStore) prune(now time.Time, window time.Duration) {
+	for key, attempt := range s.attempts {
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			continue
+		}
+		if attempt.LastFailedAt != nil && attempt.LastFailedAt.After(now.Add(-window)) {
+			continue
+		}
+		delete(s.attempts, key)
+	}
+}
+
+// PgAttemptRepo is the part of repo.PGInterface used by PgStore
+type PgAttemptRepo interface {
+	GetLoginAttempt(ctx context.Context, identifier string, tx *gorm.DB) (model.LoginAttempt, error)
+	IncreaseLoginAttempt(ctx context.Context, identifier string, window time.Duration, tx *gorm.DB) (model.LoginAttempt, error)
+	LockLoginAttempt(ctx context.Context, identifier string, until time.Time, tx *gorm.DB) error
+	ResetLoginAttempt(ctx context.Context, identifier string, tx *gorm.DB) error
+}
+
+type PgStore struct {
+	repo PgAttemptRepo
+}
+
+func NewPgStore(repo PgAttemptRepo) *PgStore {
+	return &PgStore{repo: repo}
+}
+
+func (s *PgStore) Get(ctx context.Context, identifier string) (model.LoginAttempt, error) {
+	return s.repo.GetLoginAttempt(ctx, identifier, nil)
+}
+
+func (s *PgStore) Increase(ctx context.Context, identifier string, window time.Duration) (model.LoginAttempt, error) {
+	return s.repo.IncreaseLoginAttempt(ctx, identifier, window, nil)
+}
+
+func (s *PgStore) Lock(ctx context.Context, identifier string, until time.Time) error {
+	return s.repo.LockLoginAttempt(ctx, identifier, until, nil)
+}
+
+func (s *PgStore) Reset(ctx context.Context, identifier string) error {
+	return s.repo.ResetLoginAttempt(ctx, identifier, nil)
+}
+
+// NewStore builds the store configured by ATTEMPT_STORE, the in-memory one is the default
+func NewStore(name string, repo PgAttemptRepo) (Store, error) {
+	switch name {
+	case "", STORE_MEMORY:
+		return NewMemoryStore(), nil
+	case STORE_POSTGRES:
+		return NewPgStore(repo), nil
+	default:
+		return nil, fmt.Errorf("unknown attempt store %q", name)
+	}
+}
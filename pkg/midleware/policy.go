@@ -0,0 +1,28 @@
+package midleware
+
+import (
+	"net/http"
+	"parking-server/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// Allow lets the request through only when the authenticated principal has one of the given types.
+// Ownership of the touched records is checked by the services.
+func Allow(types ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := utils.PrincipalFromContext(c.Request.Context())
+		if !ok {
+			abortUnauthorized(c)
+			return
+		}
+		if !principal.Is(types...) {
+			_ = c.Error(ginext.NewError(http.StatusForbidden, utils.MessageError()[http.StatusForbidden]))
+			c.Status(http.StatusForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,11 +1,102 @@
 package midleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/utils"
+	"strings"
 
-func verifyToken(c *gin.Context) {
-	//get access token
-	token := c.Request.Header.Get("x-access-token")
-	if token != "" {
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/common"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
 
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionId uuid.UUID) (bool, error)
+}
+
+// VerifyToken validates the access token of every request, except the routes listed in publicRoutes
+// (keyed by gin full path), and injects the authenticated principal into the gin and request context.
+// Tokens of a revoked session are rejected even before they expire.
+func VerifyToken(publicRoutes map[string]bool, sessions SessionChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// the request id is read from the request context by the logger and the audit log
+		if requestID := c.GetString(common.HeaderXRequestID); requestID != "" {
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), common.HeaderXRequestID, requestID))
+		}
+		if c.FullPath() == "" || publicRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+		log := logger.WithCtx(c, "VerifyToken")
+
+		token := getAccessToken(c)
+		if token == "" {
+			abortUnauthorized(c)
+			return
+		}
+
+		claims, err := utils.ParseToken(token)
+		if err != nil {
+			log.WithError(err).Error("error_401: invalid access token")
+			abortUnauthorized(c)
+			return
+		}
+
+		id, err := uuid.Parse(claims.ID)
+		if err != nil {
+			log.WithError(err).Error("error_401: invalid subject in access token")
+			abortUnauthorized(c)
+			return
+		}
+		sessionId, err := uuid.Parse(claims.SessionID)
+		if err != nil {
+			log.WithError(err).Error("error_401: invalid session in access token")
+			abortUnauthorized(c)
+			return
+		}
+
+		active, err := sessions.IsSessionActive(c.Request.Context(), sessionId)
+		if err != nil {
+			log.WithError(err).Error("error_500: failed to check session")
+			_ = c.Error(ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError]))
+			c.Abort()
+			return
+		}
+		if !active {
+			abortUnauthorized(c)
+			return
+		}
+
+		principal := &utils.Principal{ID: id, SessionID: sessionId, Type: claims.Type, Role: claims.Role}
+		if claims.CompanyID != "" {
+			if principal.CompanyID, err = uuid.Parse(claims.CompanyID); err != nil {
+				log.WithError(err).Error("error_401: invalid company in access token")
+				abortUnauthorized(c)
+				return
+			}
+		}
+		// never let the client speak for itself
+		c.Request.Header.Del("x-user-id")
+		c.Set(utils.PrincipalKey, principal)
+		c.Request = c.Request.WithContext(utils.WithPrincipal(c.Request.Context(), principal))
+
+		c.Next()
+	}
+}
+
+func getAccessToken(c *gin.Context) string {
+	auth := c.Request.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
 	}
+	return c.Request.Header.Get("x-access-token")
+}
+
+func abortUnauthorized(c *gin.Context) {
+	_ = c.Error(ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized]))
+	c.Status(http.StatusUnauthorized)
+	c.Abort()
 }
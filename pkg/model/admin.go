@@ -0,0 +1,31 @@
+package model
+
+// Admin is a platform operator, it reviews the companies and parking lots
+type Admin struct {
+	BaseModel
+	Name     string `json:"name"`
+	Email    string `json:"email" gorm:"uniqueIndex;not null"`
+	Password string `json:"-" gorm:"not null"`
+	Status   string `json:"status" gorm:"default:active"`
+}
+
+func (admin *Admin) TableName() string {
+	return "admin"
+}
+
+type AdminReq struct {
+	Name     *string `json:"name"`
+	Email    *string `json:"email" valid:"Required"`
+	Password *string `json:"password" valid:"Required"`
+}
+
+type AdminLoginRes struct {
+	Admin
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type ReviewReq struct {
+	Decision *string `json:"decision" valid:"Required"` // approve | reject
+	Reason   *string `json:"reason"`
+}
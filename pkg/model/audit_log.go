@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// AuditLog is one row created, updated or deleted through gorm, written by the audit callbacks of repo
+type AuditLog struct {
+	BaseModel
+	ActorID    *uuid.UUID `json:"actorId" gorm:"type:uuid;index"`
+	ActorType  string     `json:"actorType"`
+	CompanyID  *uuid.UUID `json:"companyId" gorm:"type:uuid;index"`
+	EntityType string     `json:"entityType" gorm:"index:idx_audit_log_entity"`
+	EntityID   string     `json:"entityId" gorm:"index:idx_audit_log_entity"`
+	Action     string     `json:"action"`
+	Before     *string    `json:"before" gorm:"type:jsonb" swaggertype:"object"`
+	After      *string    `json:"after" gorm:"type:jsonb" swaggertype:"object"`
+	RequestID  string     `json:"requestId" gorm:"index"`
+}
+
+func (a *AuditLog) TableName() string {
+	return "audit_log"
+}
+
+type ListAuditLogReq struct {
+	CompanyID  *string    `json:"company_id" form:"company_id"`
+	EntityType *string    `json:"entity_type" form:"entity_type"`
+	EntityID   *string    `json:"entity_id" form:"entity_id"`
+	ActorID    *string    `json:"actor_id" form:"actor_id"`
+	Action     *string    `json:"action" form:"action"`
+	From       *time.Time `json:"from" form:"from"`
+	To         *time.Time `json:"to" form:"to"`
+	Page       int        `json:"page" form:"page"`
+	PageSize   int        `json:"pageSize" form:"pageSize"`
+}
+
+type ListAuditLogRes struct {
+	Data []AuditLog      `json:"data,omitempty"`
+	Meta ginext.BodyMeta `json:"meta" swaggertype:"object"`
+}
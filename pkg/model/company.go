@@ -19,6 +19,12 @@ func (company *Company) TableName() string {
 	return "company"
 }
 
+type CompanyLoginRes struct {
+	Company
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
 type CompanyReq struct {
 	ID          *uuid.UUID `json:"id"`
 	Name        *string    `json:"companyName" valid:"Required"`
@@ -45,6 +51,7 @@ type ListCompanyRes struct {
 
 type ListCompanyReq struct {
 	Name     *string `json:"name" form:"name"`
+	Status   *string `json:"status" form:"status"`
 	Sort     string  `json:"sort" form:"sort"`
 	Page     int     `json:"page" form:"page"`
 	PageSize int     `json:"pageSize" form:"pageSize"`
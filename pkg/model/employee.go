@@ -22,6 +22,12 @@ func (e *Employee) TableName() string {
 	return "employees"
 }
 
+type EmployeeLoginRes struct {
+	Employee
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
 type EmployeeReq struct {
 	ID             *uuid.UUID `json:"id"`
 	Name           *string    `json:"name" valid:"Required"`
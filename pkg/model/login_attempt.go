@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+// LoginAttempt tracks the failures of one identifier (phone, email, ip...) for the postgres limiter store
+type LoginAttempt struct {
+	BaseModel
+	Identifier   string     `json:"identifier" gorm:"uniqueIndex;not null"`
+	Failures     int        `json:"failures" gorm:"default:0"`
+	LastFailedAt *time.Time `json:"lastFailedAt"`
+	LockedUntil  *time.Time `json:"lockedUntil"`
+}
+
+func (a *LoginAttempt) TableName() string {
+	return "login_attempt"
+}
@@ -1,21 +1,45 @@
 package model
 
 import (
-	"github.com/google/uuid"
 	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	LONG_TERM_DAILY  = "DAILY"  // every day
+	LONG_TERM_CYCLE  = "CYCLE"  // every Interval days
+	LONG_TERM_CUSTOM = "CUSTOM" // on the given Weekdays of every week
+
+	LONG_TERM_ACTIVE = "active"
+	LONG_TERM_CANCEL = "cancel"
 )
 
+// LongTermTicket is a series of tickets repeating the window StartTime - EndTime of the first occurrence
+// until RepeatUntil
 type LongTermTicket struct {
 	BaseModel
 	Type          string     `json:"type"`
 	StartTime     *time.Time `json:"start_time"`
 	EndTime       *time.Time `json:"end_time"`
+	RepeatUntil   *time.Time `json:"repeat_until"`
+	Interval      int        `json:"interval,omitempty"`
+	Weekdays      string     `json:"weekdays,omitempty"` // comma separated, 0 is sunday
+	UserId        *uuid.UUID `json:"user_id" gorm:"type:uuid;index"`
 	VehicleId     *uuid.UUID `json:"vehicle_id" gorm:"type:uuid"`
 	ParkingLotId  *uuid.UUID `json:"parking_lot_id" gorm:"type:uuid"`
 	ParkingSlotId *uuid.UUID `json:"parking_slot_id" gorm:"type:uuid"`
 	TimeFrameId   *uuid.UUID `json:"time_frame_id" gorm:"type:uuid"`
+	State         string     `json:"state" gorm:"default:active"`
+	Total         float64    `json:"total"`
+	Tickets       []Ticket   `json:"tickets,omitempty" gorm:"foreignKey:LongTermTicketId"`
 }
 
 func (ltt *LongTermTicket) TableName() string {
 	return "long_term_ticket"
 }
+
+type ListLongTermTicketReq struct {
+	UserId *string `json:"userId" form:"userId"`
+	State  *string `json:"state" form:"state"`
+}
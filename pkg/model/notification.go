@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// Notification is an in-app message for a company or a user
+type Notification struct {
+	BaseModel
+	CompanyID *uuid.UUID `json:"companyId,omitempty" gorm:"type:uuid;index"`
+	UserID    *uuid.UUID `json:"userId,omitempty" gorm:"type:uuid;index"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	ReadAt    *time.Time `json:"readAt"`
+}
+
+func (n *Notification) TableName() string {
+	return "notification"
+}
+
+type ListNotificationReq struct {
+	CompanyID *string `json:"-" form:"-"`
+	UserID    *string `json:"-" form:"-"`
+	Unread    bool    `json:"unread" form:"unread"`
+	Page      int     `json:"page" form:"page"`
+	PageSize  int     `json:"pageSize" form:"pageSize"`
+}
+
+type ListNotificationRes struct {
+	Data []Notification  `json:"data,omitempty"`
+	Meta ginext.BodyMeta `json:"meta" swaggertype:"object"`
+}
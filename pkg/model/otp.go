@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// OtpCode is a one time password issued by the self hosted otp provider
+type OtpCode struct {
+	BaseModel
+	PhoneNumber string     `json:"phoneNumber" gorm:"index;not null"`
+	Code        string     `json:"-" gorm:"not null"` // sha256 of the code sent to the phone
+	ExpiredDate *time.Time `json:"expiredDate"`
+	Attempts    int        `json:"attempts" gorm:"default:0"`
+	VerifiedAt  *time.Time `json:"verifiedAt,omitempty"`
+}
+
+func (o *OtpCode) TableName() string {
+	return "otp_code"
+}
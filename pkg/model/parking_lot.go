@@ -9,17 +9,18 @@ import (
 
 type ParkingLot struct {
 	BaseModel
-	Name        string      `json:"name" gorm:"not null"`
-	Description string      `json:"description"`
-	Address     string      `json:"address"`
-	StartTime   time.Time   `json:"startTime"`
-	EndTime     time.Time   `json:"endTime"`
-	Lat         float64     `json:"lat"`
-	Long        float64     `json:"long"`
-	CompanyID   uuid.UUID   `json:"companyID" gorm:"type:uuid"`
-	TimeFrames  []TimeFrame `json:"timeFrames,omitempty" gorm:"foreignKey:ParkingLotId"`
-	Blocks      []Block     `json:"blocks,omitempty" gorm:"foreignKey:ParkingLotID"`
-	Status      string      `json:"status" gorm:"default:pending"`
+	Name         string        `json:"name" gorm:"not null"`
+	Description  string        `json:"description"`
+	Address      string        `json:"address"`
+	StartTime    time.Time     `json:"startTime"`
+	EndTime      time.Time     `json:"endTime"`
+	Lat          float64       `json:"lat"`
+	Long         float64       `json:"long"`
+	CompanyID    uuid.UUID     `json:"companyID" gorm:"type:uuid"`
+	TimeFrames   []TimeFrame   `json:"timeFrames,omitempty" gorm:"foreignKey:ParkingLotId"`
+	PricingRules []PricingRule `json:"pricingRules,omitempty" gorm:"foreignKey:ParkingLotId"`
+	Blocks       []Block       `json:"blocks,omitempty" gorm:"foreignKey:ParkingLotID"`
+	Status       string        `json:"status" gorm:"default:pending"`
 }
 
 func (ParkingLot) TableName() string {
@@ -56,6 +57,7 @@ type ListParkingLotRes struct {
 type GetListParkingLotReq struct {
 	CompanyID *string  `json:"company_id" form:"company_id"`
 	Name      *string  `json:"name" form:"name"`
+	Status    *string  `json:"status" form:"status"`
 	Lat       *float64 `json:"lat" form:"lat"`
 	Long      *float64 `json:"long" form:"long"`
 	Sort      string   `json:"sort" form:"sort"`
@@ -74,11 +76,14 @@ type UpdateParkingLotReq struct {
 	Long        float64     `json:"long"`
 	TimeFrames  []TimeFrame `json:"timeFrames"`
 	Blocks      []Block     `json:"blocks" `
+	// replaces the rules of the lot, left as they are when omitted
+	PricingRules []PricingRule `json:"pricingRules"`
 }
 
 type ChangeStatusReq struct {
 	ID     *uuid.UUID `json:"id"`
-	Status *string    `json:"status" validate:"required,oneof=active inactive pending"`
+	Status *string    `json:"status" validate:"required,oneof=active inactive pending rejected"`
+	Reason *string    `json:"reason"`
 }
 
 type GetParkingLotsInfoByIds struct {
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+)
+
+// PasswordResetToken is issued by a successful otp verification and consumed by the password reset
+type PasswordResetToken struct {
+	BaseModel
+	Token       string     `json:"-" gorm:"uniqueIndex"` // sha256 of the token handed to the client
+	PhoneNumber string     `json:"phoneNumber" gorm:"index;not null"`
+	ExpiredDate *time.Time `json:"expiredDate"`
+	UsedAt      *time.Time `json:"usedAt,omitempty"`
+}
+
+func (t *PasswordResetToken) TableName() string {
+	return "password_reset_token"
+}
+
+type ResetPasswordReq struct {
+	ResetToken *string `json:"reset_token" valid:"Required"`
+	Password   *string `json:"password" valid:"Required"`
+}
+
+type VerifyOtpRes struct {
+	ResetToken string `json:"reset_token"`
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// statuses of a payment
+const (
+	PAYMENT_PENDING   = "pending"
+	PAYMENT_SUCCEEDED = "succeeded"
+	PAYMENT_FAILED    = "failed"
+	PAYMENT_REFUNDED  = "refunded"
+)
+
+// what a payment is for, it decides the state its tickets move to once paid
+const (
+	PAYMENT_FOR_BOOKING   = "booking"
+	PAYMENT_FOR_EXTENSION = "extension"
+	PAYMENT_FOR_LONG_TERM = "long_term"
+	PAYMENT_FOR_TOP_UP    = "top_up" // credited to the wallet of the user
+)
+
+// types of a payment transaction
+const (
+	PAYMENT_TX_CHARGE = "charge"
+	PAYMENT_TX_REFUND = "refund"
+)
+
+// payment status of a ticket
+const (
+	TICKET_UNPAID   = "unpaid"
+	TICKET_PAID     = "paid"
+	TICKET_REFUNDED = "refunded"
+)
+
+// Payment is the money asked for a ticket, an extension or a whole long term series, collected by a provider
+type Payment struct {
+	BaseModel
+	UserID           *uuid.UUID           `json:"userId" gorm:"type:uuid;index"`
+	TicketID         *uuid.UUID           `json:"ticketId,omitempty" gorm:"type:uuid;index"`
+	LongTermTicketID *uuid.UUID           `json:"longTermTicketId,omitempty" gorm:"type:uuid;index"`
+	Purpose          string               `json:"purpose"`
+	Provider         string               `json:"provider"`
+	ProviderRef      string               `json:"providerRef" gorm:"uniqueIndex"`
+	Amount           float64              `json:"amount"`
+	Refunded         float64              `json:"refunded"`
+	RefundDue        float64              `json:"refundDue"` // still to refund, retried until the provider gives it back
+	Currency         string               `json:"currency"`
+	Status           string               `json:"status" gorm:"index"`
+	PayURL           string               `json:"payUrl"`
+	QRCode           string               `json:"qrCode"`
+	ExpiredAt        *time.Time           `json:"expiredAt"`
+	Transactions     []PaymentTransaction `json:"transactions,omitempty" gorm:"foreignKey:PaymentID"`
+}
+
+func (p *Payment) TableName() string {
+	return "payment"
+}
+
+// PaymentTransaction is one event reported by the provider. EventID is unique so a webhook delivered twice
+// is applied once.
+type PaymentTransaction struct {
+	BaseModel
+	PaymentID uuid.UUID `json:"paymentId" gorm:"type:uuid;not null;index"`
+	EventID   string    `json:"eventId" gorm:"uniqueIndex;not null"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status"`
+	Amount    float64   `json:"amount"`
+	Payload   *string   `json:"payload,omitempty" gorm:"type:jsonb"`
+}
+
+func (t *PaymentTransaction) TableName() string {
+	return "payment_transaction"
+}
+
+// CreatePaymentReq asks for a new payment of a ticket or series still waiting for it
+type CreatePaymentReq struct {
+	TicketId         *uuid.UUID `json:"ticketId"`
+	LongTermTicketId *uuid.UUID `json:"longTermTicketId"`
+}
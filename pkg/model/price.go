@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type QuoteReq struct {
+	ParkingLotId *uuid.UUID `json:"parkingLotId" valid:"Required"`
+	StartTime    *time.Time `json:"startTime" valid:"Required"`
+	EndTime      *time.Time `json:"endTime" valid:"Required"`
+}
+
+// PriceItem is one time frame of a quote, bought Quantity times
+type PriceItem struct {
+	TimeFrameId uuid.UUID `json:"timeFrameId"`
+	Duration    int       `json:"duration"`
+	Cost        float64   `json:"cost"`
+	Quantity    int       `json:"quantity"`
+	Amount      float64   `json:"amount"`
+}
+
+// PriceSegment is a part of a stay within one day and under one pricing rule, with its share of the
+// time frame price scaled by the rule
+type PriceSegment struct {
+	StartTime     time.Time  `json:"startTime"`
+	EndTime       time.Time  `json:"endTime"`
+	PricingRuleId *uuid.UUID `json:"pricingRuleId,omitempty"`
+	Multiplier    float64    `json:"multiplier"`
+	Amount        float64    `json:"amount"`
+}
+
+// PriceQuote is the cheapest combination of time frames covering a stay. When the lot has pricing rules or
+// a daily cap, Total is the sum of the segments less what the cap took off.
+type PriceQuote struct {
+	ParkingLotId uuid.UUID      `json:"parkingLotId"`
+	StartTime    time.Time      `json:"startTime"`
+	EndTime      time.Time      `json:"endTime"`
+	Duration     int            `json:"duration"`
+	Items        []PriceItem    `json:"items"`
+	Segments     []PriceSegment `json:"segments,omitempty"`
+	Capped       float64        `json:"capped,omitempty"`
+	Total        float64        `json:"total"`
+}
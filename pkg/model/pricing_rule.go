@@ -0,0 +1,23 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// PricingRule scales the time frame price of the part of a stay it covers, e.g. 1.5 at rush hour.
+// Where rules overlap the one of highest Priority wins; outside any rule the time frame price applies.
+type PricingRule struct {
+	BaseModel
+	ParkingLotId uuid.UUID `json:"parkingLotId" gorm:"type:uuid;not null;index"`
+	Name         string    `json:"name"`
+	Weekdays     string    `json:"weekdays"`  // comma separated, 0 is sunday; empty is every day
+	Holiday      bool      `json:"holiday"`   // applies on the holidays of the lot instead of its weekdays
+	StartTime    string    `json:"startTime"` // "15:04", empty along with EndTime for the whole day
+	EndTime      string    `json:"endTime"`   // before StartTime when the window runs past midnight
+	Multiplier   float64   `json:"multiplier"`
+	Priority     int       `json:"priority"`
+}
+
+func (r *PricingRule) TableName() string {
+	return "pricing_rule"
+}
@@ -1,13 +1,47 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type RefreshToken struct {
 	BaseModel
-	Token       string     `json:"token"`
-	ExpiredDate *time.Time `json:"expired_date"`
+	Token         string     `json:"-" gorm:"uniqueIndex"`          // sha256 of the token handed to the client
+	UserId        uuid.UUID  `json:"userId" gorm:"type:uuid;index"` // id of the user, company or employee, see PrincipalType
+	PrincipalType string     `json:"principalType" gorm:"default:user"`
+	SessionId     uuid.UUID  `json:"sessionId" gorm:"type:uuid;index"` // token family, shared by every rotation of one login
+	ExpiredDate   *time.Time `json:"expired_date"`
+	UsedAt        *time.Time `json:"usedAt,omitempty"`
+	RevokedAt     *time.Time `json:"revokedAt,omitempty"`
+	UserAgent     string     `json:"userAgent"`
+	IpAddress     string     `json:"ipAddress"`
 }
 
 func (rt *RefreshToken) TableName() string {
 	return "refresh_token"
 }
+
+type ClientInfo struct {
+	UserAgent string
+	IpAddress string
+}
+
+type RefreshTokenReq struct {
+	RefreshToken *string `json:"refreshToken" valid:"Required"`
+}
+
+type TokenRes struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type SessionRes struct {
+	SessionId   uuid.UUID  `json:"sessionId"`
+	UserAgent   string     `json:"userAgent"`
+	IpAddress   string     `json:"ipAddress"`
+	LastUsedAt  time.Time  `json:"lastUsedAt"`
+	ExpiredDate *time.Time `json:"expiredDate"`
+	IsCurrent   bool       `json:"isCurrent"`
+}
@@ -17,3 +17,55 @@ type Setting struct {
 func (s *Setting) TableName() string {
 	return "setting"
 }
+
+const (
+	SETTING_NO_SHOW_GRACE_PERIOD = "no_show_grace_period"
+	SETTING_OVERSTAY_PENALTY     = "overstay_penalty"
+	SETTING_CANCELLATION_POLICY  = "cancellation_policy"
+	SETTING_HOLIDAYS             = "holidays"
+	SETTING_DAILY_CAP            = "daily_cap"
+
+	// company wide, saved without a lot
+	SETTING_PLATFORM_COMMISSION = "platform_commission"
+)
+
+// NoShowGracePeriod is how long after its start time a ticket that was not checked in expires
+type NoShowGracePeriod struct {
+	Minutes int `json:"minutes"`
+}
+
+// OverstayPenalty prices the time a car stays after its booked end. Without it, or with a zero rate,
+// the overstay is priced with the time frames of the lot.
+type OverstayPenalty struct {
+	RatePerHour float64 `json:"ratePerHour"` // any started hour is billed in full
+	Grace       int     `json:"grace"`       // minutes of overstay that are free
+}
+
+// CancellationPolicy decides how much of a ticket is refunded when it is cancelled, from how long before
+// its start it is cancelled. Without it, tickets are fully refunded until they start.
+type CancellationPolicy struct {
+	FreeBefore      int          `json:"freeBefore"`      // minutes before the start from which the refund is full
+	Tiers           []RefundTier `json:"tiers"`           // partial refunds, when cancelled later than FreeBefore
+	AllowAfterStart bool         `json:"allowAfterStart"` // tickets not checked in may be cancelled after their start, without refund
+}
+
+// RefundTier refunds Percent of the ticket when it is cancelled at least Before minutes before its start
+type RefundTier struct {
+	Before  int     `json:"before"`
+	Percent float64 `json:"percent"`
+}
+
+// Holidays are the days a lot prices with its holiday rules
+type Holidays struct {
+	Dates []string `json:"dates"` // "2006-01-02"
+}
+
+// DailyCap is the most a stay is charged for any one calendar day, zero for no cap
+type DailyCap struct {
+	Amount float64 `json:"amount"`
+}
+
+// PlatformCommission is the part of its earnings a company leaves to the platform. Without it, none is taken.
+type PlatformCommission struct {
+	Percent float64 `json:"percent"`
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+const (
+	SETTLEMENT_OPEN   = "open"   // the period is running, the statement is computed on demand
+	SETTLEMENT_CLOSED = "closed" // saved at the end of the period and never recomputed
+
+	SETTLEMENT_LINE_BOOKING    = "booking"
+	SETTLEMENT_LINE_EXTENSION  = "extension"
+	SETTLEMENT_LINE_CHARGE     = "charge"
+	SETTLEMENT_LINE_REFUND     = "refund"
+	SETTLEMENT_LINE_COMMISSION = "commission"
+)
+
+// Settlement is what a company earned on its lots over [PeriodStart, PeriodEnd), a calendar month.
+// Payout is Gross less the commission of the platform, Gross is the tickets and charges less the refunds.
+type Settlement struct {
+	BaseModel
+	CompanyID         uuid.UUID        `json:"companyId" gorm:"type:uuid;not null;uniqueIndex:idx_settlement_company_period"`
+	PeriodStart       time.Time        `json:"periodStart" gorm:"not null;uniqueIndex:idx_settlement_company_period"`
+	PeriodEnd         time.Time        `json:"periodEnd" gorm:"not null"`
+	Status            string           `json:"status"`
+	Currency          string           `json:"currency"`
+	Bookings          float64          `json:"bookings"`
+	Extensions        float64          `json:"extensions"`
+	Charges           float64          `json:"charges"`
+	Refunds           float64          `json:"refunds"`
+	Gross             float64          `json:"gross"`
+	CommissionPercent float64          `json:"commissionPercent"`
+	Commission        float64          `json:"commission"`
+	Payout            float64          `json:"payout"`
+	ClosedAt          *time.Time       `json:"closedAt,omitempty"`
+	Lines             []SettlementLine `json:"lines,omitempty" gorm:"foreignKey:SettlementID"`
+}
+
+func (s *Settlement) TableName() string {
+	return "settlement"
+}
+
+// SettlementLine is one amount of a statement, refunds and commission are negative
+type SettlementLine struct {
+	BaseModel
+	SettlementID uuid.UUID  `json:"settlementId" gorm:"type:uuid;not null;index"`
+	Type         string     `json:"type"`
+	TicketID     *uuid.UUID `json:"ticketId,omitempty" gorm:"type:uuid"`
+	ParkingLotID *uuid.UUID `json:"parkingLotId,omitempty" gorm:"type:uuid"`
+	OccurredAt   time.Time  `json:"occurredAt"`
+	Amount       float64    `json:"amount"`
+	Description  string     `json:"description"`
+}
+
+func (l *SettlementLine) TableName() string {
+	return "settlement_line"
+}
+
+// SettlementDue tells from when a company has not been settled yet
+type SettlementDue struct {
+	CompanyID uuid.UUID `json:"companyId"`
+	Since     time.Time `json:"since"`
+}
+
+type ListSettlementReq struct {
+	CompanyID *string `json:"companyId" form:"companyId"`
+	Page      int     `json:"page" form:"page"`
+	PageSize  int     `json:"pageSize" form:"pageSize"`
+}
+
+type ListSettlementRes struct {
+	Data []Settlement    `json:"data,omitempty"`
+	Meta ginext.BodyMeta `json:"meta" swaggertype:"object"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// StatusHistory records every status change of a company or a parking lot
+type StatusHistory struct {
+	BaseModel
+	EntityType string     `json:"entityType" gorm:"index:idx_status_history_entity"` // company | parking_lot
+	EntityID   uuid.UUID  `json:"entityId" gorm:"type:uuid;index:idx_status_history_entity"`
+	FromStatus string     `json:"fromStatus"`
+	ToStatus   string     `json:"toStatus"`
+	Reason     string     `json:"reason"`
+	ActorID    *uuid.UUID `json:"actorId" gorm:"type:uuid"`
+}
+
+func (h *StatusHistory) TableName() string {
+	return "status_history"
+}
+
+type ListStatusHistoryReq struct {
+	EntityType *string `json:"entity_type" form:"entity_type"`
+	EntityID   *string `json:"entity_id" form:"entity_id"`
+	Page       int     `json:"page" form:"page"`
+	PageSize   int     `json:"pageSize" form:"pageSize"`
+}
+
+type ListStatusHistoryRes struct {
+	Data []StatusHistory `json:"data,omitempty"`
+	Meta ginext.BodyMeta `json:"meta" swaggertype:"object"`
+}
@@ -2,30 +2,38 @@ package model
 
 import (
 	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
 	"time"
 )
 
 type Ticket struct {
 	BaseModel
-	UserId           *uuid.UUID   `json:"userId"` // dung cho muc dich truy van
-	VehicleId        *uuid.UUID   `json:"vehicleId"`
-	Vehicle          *Vehicle     `json:"vehicle,omitempty"`
-	ParkingLotId     *uuid.UUID   `json:"parkingLotId" gorm:"type:uuid"`
-	ParkingLot       *ParkingLot  `json:"parkingLot,omitempty"`
-	ParkingSlotId    *uuid.UUID   `json:"parkingSlotId" gorm:"type:uuid"`
-	ParkingSlot      *ParkingSlot `json:"parkingSlot,omitempty"`
-	TimeFrameId      *uuid.UUID   `json:"timeFrameId" gorm:"type:uuid"`
-	TimeFrame        *TimeFrame   `json:"timeFrame,omitempty"`
-	StartTime        *time.Time   `json:"startTime"`
-	EndTime          *time.Time   `json:"endTime"`
-	EntryTime        *time.Time   `json:"entryTime,omitempty"`
-	ExitTime         *time.Time   `json:"exitTime,omitempty"`
-	Total            float64      `json:"total"`
-	State            string       `json:"state"`
-	IsExtend         bool         `json:"isExtend"`
-	LongTermTicketId *uuid.UUID   `json:"longTermTicketId,omitempty" gorm:"type:uuid"`
-	IsGoodReview     *bool        `json:"isGoodReview"`
-	Comment          *string      `json:"comment"`
+	UserId           *uuid.UUID     `json:"userId"` // dung cho muc dich truy van
+	VehicleId        *uuid.UUID     `json:"vehicleId"`
+	Vehicle          *Vehicle       `json:"vehicle,omitempty"`
+	ParkingLotId     *uuid.UUID     `json:"parkingLotId" gorm:"type:uuid"`
+	ParkingLot       *ParkingLot    `json:"parkingLot,omitempty"`
+	ParkingSlotId    *uuid.UUID     `json:"parkingSlotId" gorm:"type:uuid"`
+	ParkingSlot      *ParkingSlot   `json:"parkingSlot,omitempty"`
+	TimeFrameId      *uuid.UUID     `json:"timeFrameId" gorm:"type:uuid"`
+	TimeFrame        *TimeFrame     `json:"timeFrame,omitempty"`
+	StartTime        *time.Time     `json:"startTime"`
+	EndTime          *time.Time     `json:"endTime"`
+	EntryTime        *time.Time     `json:"entryTime,omitempty"`
+	ExitTime         *time.Time     `json:"exitTime,omitempty"`
+	Total            float64        `json:"total"`              // after Discount
+	Discount         float64        `json:"discount,omitempty"` // taken off by the voucher
+	VoucherId        *uuid.UUID     `json:"voucherId,omitempty" gorm:"type:uuid"`
+	Refund           float64        `json:"refund"` // part of Total given back on cancellation
+	State            TicketState    `json:"state"`
+	IsExtend         bool           `json:"isExtend"`
+	LongTermTicketId *uuid.UUID     `json:"longTermTicketId,omitempty" gorm:"type:uuid"`
+	PaymentStatus    string         `json:"paymentStatus" gorm:"default:unpaid"`
+	IsGoodReview     *bool          `json:"isGoodReview"`
+	Comment          *string        `json:"comment"`
+	Price            *PriceQuote    `json:"price,omitempty" gorm:"-"`
+	Payment          *Payment       `json:"payment,omitempty" gorm:"-"` // to pay before the booking is confirmed
+	Charges          []TicketCharge `json:"charges,omitempty" gorm:"foreignKey:TicketID"`
 }
 
 func (t *Ticket) TableName() string {
@@ -35,9 +43,34 @@ func (t *Ticket) TableName() string {
 type CancelTicketRequest struct {
 	TicketId string `json:"ticketId"`
 }
+
+type CancelTicketRes struct {
+	TicketId string  `json:"ticketId"`
+	Percent  float64 `json:"percent"`
+	Refund   float64 `json:"refund"` // of the ticket and its extensions
+}
 type GetListTicketParam struct {
 	UserId *string `json:"userId" form:"userId" valid:"Required"`
-	State  *string `json:"state" form:"state"`
+	TicketFilter
+}
+
+// TicketFilter narrows ticket listings; From/To keep tickets overlapping the range
+type TicketFilter struct {
+	State         *string    `json:"state" form:"state"`
+	From          *time.Time `json:"from" form:"from"`
+	To            *time.Time `json:"to" form:"to"`
+	PlateNumber   *string    `json:"plateNumber" form:"plateNumber"`
+	VehicleType   *string    `json:"vehicleType" form:"vehicleType"`
+	ParkingSlotID *string    `json:"parkingSlotId" form:"parkingSlotId"`
+	BlockID       *string    `json:"blockId" form:"blockId"`
+	Sort          string     `json:"sort" form:"sort"`
+	Page          int        `json:"page" form:"page"`
+	PageSize      int        `json:"pageSize" form:"pageSize"`
+}
+
+type ListTicketRes struct {
+	Data []Ticket        `json:"data,omitempty"`
+	Meta ginext.BodyMeta `json:"meta" swaggertype:"object"`
 }
 type TicketReq struct {
 	VehicleId     *uuid.UUID `json:"vehicleId" valid:"Required"`
@@ -49,29 +82,78 @@ type TicketReq struct {
 	EndTime       *time.Time `json:"endTime" valid:"Required"`
 	EntryTime     *time.Time `json:"entryTime"`
 	ExitTime      *time.Time `json:"exitTime"`
-	Total         *float64   `json:"total"`
 	IsLongTerm    bool       `json:"isLongTerm"`
 	Type          string     `json:"type"`
+	RepeatUntil   *time.Time `json:"repeatUntil"`
+	Interval      int        `json:"interval"`
+	Weekdays      []int      `json:"weekdays"`
+	PayWithWallet bool       `json:"payWithWallet"` // spend the wallet balance instead of paying through the provider
+	VoucherCode   *string    `json:"voucherCode"`
+}
+
+// WalkInTicketReq issues a ticket at the gate for a car that arrives without a booking
+type WalkInTicketReq struct {
+	ParkingLotId *uuid.UUID `json:"parkingLotId" valid:"Required"`
+	Number       *string    `json:"number" valid:"Required"` // plate number
+	Type         *string    `json:"type" valid:"Required"`
+	BlockId      *uuid.UUID `json:"blockId"` // block to try first
+}
+
+// GateProcedureReq finds the ticket to check in or out by the plate of the car at the gate of the lot
+type GateProcedureReq struct {
+	ParkingLotId *uuid.UUID `json:"parkingLotId" valid:"Required"`
+	Number       *string    `json:"number" valid:"Required"` // plate number
+	TicketId     *uuid.UUID `json:"ticketId"`                // picks one of several candidates
 }
+
+// GateProcedureRes holds the ticket that went through the gate, or the candidates to choose from
+type GateProcedureRes struct {
+	Ticket     *Ticket      `json:"ticket,omitempty"`
+	Checkout   *CheckoutRes `json:"checkout,omitempty"`
+	Candidates []Ticket     `json:"candidates,omitempty"`
+	Message    string       `json:"message,omitempty"`
+}
+
 type ExtendTicketReq struct {
 	TicketOriginId *uuid.UUID `json:"ticketOriginId" valid:"Required"`
 	TimeFrameId    *uuid.UUID `json:"timeFrameId" valid:"Required"`
-	StartTime      *time.Time `json:"startTime" valid:"Required"`
+	StartTime      *time.Time `json:"startTime" valid:"Required"` // must be the current end of the ticket
 	EndTime        *time.Time `json:"endTime" valid:"Required"`
-	Total          *float64   `json:"total"`
+	ParkingSlotId  *uuid.UUID `json:"parkingSlotId"` // another slot of the lot, when the current one is taken
+	PayWithWallet  bool       `json:"payWithWallet"`
+}
+
+// ExtendTicketRes holds the extension, or the slot offered instead when the slot of the ticket is taken
+type ExtendTicketRes struct {
+	TicketExtend *TicketExtend `json:"ticketExtend,omitempty"`
+	Ticket       *Ticket       `json:"ticket,omitempty"`
+	Message      string        `json:"message,omitempty"`
+	Alternative  *ParkingSlot  `json:"alternative,omitempty"`
 }
 type TicketResponse struct {
 	Ticket
 	TicketExtend []Ticket `json:"ticketExtend"`
 }
 
+const (
+	PROCEDURE_CHECK_IN  = "check_in"
+	PROCEDURE_CHECK_OUT = "check_out"
+)
+
 type ProcedureReq struct {
 	Type     string `json:"type"`
 	TicketId string `json:"ticketId"`
 }
 type GetListTicketReq struct {
-	ParkingLotID *string `json:"parking_lot_id" form:"parking_lot_id"`
-	State        *string `json:"state" form:"state"`
+	ParkingLotID  *string  `json:"parking_lot_id" form:"parking_lot_id"`
+	ParkingLotIDs []string `json:"parking_lot_ids" form:"parking_lot_ids"` // repeated or comma separated
+	CompanyID     *string  `json:"-" form:"-"`
+	TicketFilter
+}
+
+type ListTicketCompanyRes struct {
+	Data []GetListTicketRes `json:"data,omitempty"`
+	Meta ginext.BodyMeta    `json:"meta" swaggertype:"object"`
 }
 
 type GetListTicketRes struct {
@@ -88,7 +170,7 @@ type GetListTicketRes struct {
 	EntryTime     *time.Time   `json:"entryTime,omitempty"`
 	ExitTime      *time.Time   `json:"exitTime,omitempty"`
 	Total         float64      `json:"total"`
-	State         string       `json:"state"`
+	State         TicketState  `json:"state"`
 	IsExtend      bool         `json:"isExtend"`
 }
 
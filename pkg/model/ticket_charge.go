@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CHARGE_OVERSTAY = "overstay"
+	CHARGE_WALK_IN  = "walk_in"
+)
+
+// TicketCharge is an amount due on a ticket on top of its Total, e.g. for leaving after the booked end
+type TicketCharge struct {
+	BaseModel
+	TicketID    uuid.UUID  `json:"ticketId" gorm:"type:uuid;not null;index"`
+	Type        string     `json:"type"`
+	StartTime   *time.Time `json:"startTime"`
+	EndTime     *time.Time `json:"endTime"`
+	Amount      float64    `json:"amount"`
+	Description string     `json:"description"`
+}
+
+func (c *TicketCharge) TableName() string {
+	return "ticket_charge"
+}
+
+// CheckoutRes is what a car owes when it leaves at ExitTime
+type CheckoutRes struct {
+	TicketID       uuid.UUID      `json:"ticketId"`
+	BookedEnd      time.Time      `json:"bookedEnd"` // end of the ticket or of its last extension
+	ExitTime       time.Time      `json:"exitTime"`
+	Overstay       int            `json:"overstay"` // minutes
+	Paid           float64        `json:"paid"`     // total of the ticket and its extensions
+	Charges        []TicketCharge `json:"charges"`
+	PaidFromWallet float64        `json:"paidFromWallet,omitempty"` // charges taken from the wallet at check-out
+	AmountDue      float64        `json:"amountDue"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TicketState string
+
+const (
+	TicketStatePending   TicketState = "pending" // booked, the slot is held until the payment comes in
+	TicketStateNew       TicketState = "new"
+	TicketStateExtend    TicketState = "extend"
+	TicketStateOngoing   TicketState = "ongoing"
+	TicketStateCompleted TicketState = "completed"
+	TicketStateCancel    TicketState = "cancel"
+	TicketStateExpired   TicketState = "expired" // not checked in within the grace period of the lot
+)
+
+// TicketActiveStates are the states in which a ticket holds its parking slot
+var TicketActiveStates = []TicketState{TicketStatePending, TicketStateNew, TicketStateExtend, TicketStateOngoing}
+
+// ticketTransitions lists the states a ticket may move to from each state
+var ticketTransitions = map[TicketState][]TicketState{
+	TicketStatePending: {TicketStateNew, TicketStateExtend, TicketStateCancel, TicketStateExpired},
+	TicketStateNew:     {TicketStateOngoing, TicketStateCancel, TicketStateExpired},
+	TicketStateExtend:  {TicketStateOngoing, TicketStateCompleted, TicketStateCancel, TicketStateExpired},
+	TicketStateOngoing: {TicketStateCompleted},
+}
+
+func (s TicketState) CanTransitionTo(to TicketState) bool {
+	for _, next := range ticketTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TicketStateHistory is one transition of a ticket. FromState is empty for the creation of the ticket.
+type TicketStateHistory struct {
+	BaseModel
+	TicketID  uuid.UUID   `json:"ticketId" gorm:"type:uuid;not null;index"`
+	FromState TicketState `json:"fromState"`
+	ToState   TicketState `json:"toState" gorm:"not null"`
+	ActorID   *uuid.UUID  `json:"actorId" gorm:"type:uuid"`
+	ActorType string      `json:"actorType"`
+	Reason    *string     `json:"reason,omitempty"`
+}
+
+func (h *TicketStateHistory) TableName() string {
+	return "ticket_state_history"
+}
+
+type TicketTimelineRes struct {
+	TicketID  uuid.UUID            `json:"ticketId"`
+	State     TicketState          `json:"state"`
+	UpdatedAt time.Time            `json:"updatedAt"`
+	History   []TicketStateHistory `json:"history"`
+}
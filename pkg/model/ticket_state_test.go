@@ -0,0 +1,35 @@
+package model
+
+import "testing"
+
+func TestTicketStateCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from TicketState
+		to   TicketState
+		want bool
+	}{
+		{TicketStatePending, TicketStateNew, true},
+		{TicketStatePending, TicketStateExtend, true},
+		{TicketStatePending, TicketStateExpired, true},
+		{TicketStatePending, TicketStateOngoing, false},
+		{TicketStateNew, TicketStateOngoing, true},
+		{TicketStateNew, TicketStateCancel, true},
+		{TicketStateNew, TicketStateExpired, true},
+		{TicketStateNew, TicketStateCompleted, false},
+		{TicketStateExtend, TicketStateOngoing, true},
+		{TicketStateExtend, TicketStateCompleted, true},
+		{TicketStateOngoing, TicketStateCompleted, true},
+		{TicketStateOngoing, TicketStateCancel, false},
+		{TicketStateOngoing, TicketStateNew, false},
+		{TicketStateCompleted, TicketStateOngoing, false},
+		{TicketStateCancel, TicketStateNew, false},
+		{TicketStateExpired, TicketStateNew, false},
+		{TicketStateNew, TicketStateNew, false},
+		{"", TicketStateNew, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%q -> %q: got %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
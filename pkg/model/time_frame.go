@@ -6,7 +6,7 @@ import (
 
 type TimeFrame struct {
 	BaseModel
-	Duration     int       `json:"duration"`
+	Duration     int       `json:"duration"` // hours
 	Cost         float64   `json:"cost"`
 	ParkingLotId uuid.UUID `json:"parkingLotId" gorm:"type:uuid;not null"`
 }
@@ -21,13 +21,15 @@ type TimeFrameReq struct {
 	ParkingLotId uuid.UUID `json:"parkingLotId" valid:"Required"`
 }
 type ListTimeFrameReq struct {
-	Data []TimeFrameReq `json:"data"`
+	Data         []TimeFrameReq `json:"data"`
+	PricingRules []PricingRule  `json:"pricingRules"`
 }
 type GetListTimeFrameParam struct {
 	ParkingLotId *string `json:"parkingLotId" form:"parkingLotId" valid:"Required"`
 }
 type ListTimeFrame struct {
-	Data []TimeFrame `json:"data"`
+	Data         []TimeFrame   `json:"data"`
+	PricingRules []PricingRule `json:"pricingRules,omitempty"`
 }
 
 type TimeFrameRequest struct {
@@ -7,10 +7,10 @@ import (
 
 type Vehicle struct {
 	BaseModel
-	Name   string    `json:"name"`
-	Number string    `json:"number"`
-	Type   string    `json:"type"`
-	UserID uuid.UUID `json:"userId" gorm:"type:uuid;not null"`
+	Name   string     `json:"name"`
+	Number string     `json:"number"`
+	Type   string     `json:"type"`
+	UserID *uuid.UUID `json:"userId" gorm:"type:uuid"` // nil for the guest vehicles of walk-in tickets
 }
 
 func (Vehicle) TableName() string {
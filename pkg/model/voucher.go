@@ -0,0 +1,122 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// kinds of discount of a voucher
+const (
+	VOUCHER_PERCENT       = "percent"       // Value percent off, up to MaxDiscount if set
+	VOUCHER_AMOUNT        = "amount"        // Value off
+	VOUCHER_FIRST_BOOKING = "first_booking" // free for the first booking of a user on the lots of the company
+
+	VOUCHER_ACTIVE   = "active"
+	VOUCHER_INACTIVE = "inactive"
+
+	REDEMPTION_REDEEMED = "redeemed"
+	REDEMPTION_RELEASED = "released" // the ticket was cancelled or not paid in time, the use is given back
+)
+
+// Voucher is a promotion of a company, redeemed with its code when booking on one of its lots
+type Voucher struct {
+	BaseModel
+	CompanyID     uuid.UUID  `json:"companyId" gorm:"type:uuid;not null;uniqueIndex:idx_voucher_company_code"`
+	Code          string     `json:"code" gorm:"not null;uniqueIndex:idx_voucher_company_code"` // upper case
+	Name          string     `json:"name"`
+	Type          string     `json:"type"`
+	Value         float64    `json:"value"`
+	MaxDiscount   float64    `json:"maxDiscount,omitempty"`
+	MinTotal      float64    `json:"minTotal,omitempty"` // of the booking before discount
+	StartAt       *time.Time `json:"startAt"`
+	EndAt         *time.Time `json:"endAt"`
+	UsageLimit    int        `json:"usageLimit"`   // redemptions in all, 0 for no limit
+	PerUserLimit  int        `json:"perUserLimit"` // redemptions by one user, 0 for no limit
+	Used          int        `json:"used"`
+	ParkingLotIds string     `json:"parkingLotIds,omitempty"` // comma separated, empty for every lot of the company
+	TimeFrameIds  string     `json:"timeFrameIds,omitempty"`  // comma separated, the discount only applies to these frames of a quote
+	Status        string     `json:"status" gorm:"default:active"`
+}
+
+func (v *Voucher) TableName() string {
+	return "voucher"
+}
+
+// VoucherRedemption is one use of a voucher, on the ticket it discounted
+type VoucherRedemption struct {
+	BaseModel
+	VoucherID uuid.UUID `json:"voucherId" gorm:"type:uuid;not null;index"`
+	UserID    uuid.UUID `json:"userId" gorm:"type:uuid;not null;index"`
+	TicketID  uuid.UUID `json:"ticketId" gorm:"type:uuid;not null;index"`
+	Code      string    `json:"code"`
+	Discount  float64   `json:"discount"`
+	Status    string    `json:"status" gorm:"default:redeemed"`
+}
+
+func (r *VoucherRedemption) TableName() string {
+	return "voucher_redemption"
+}
+
+type VoucherReq struct {
+	CompanyID     *uuid.UUID  `json:"companyId"`
+	Code          *string     `json:"code" valid:"Required"`
+	Name          *string     `json:"name"`
+	Type          *string     `json:"type" valid:"Required"`
+	Value         *float64    `json:"value"`
+	MaxDiscount   *float64    `json:"maxDiscount"`
+	MinTotal      *float64    `json:"minTotal"`
+	StartAt       *time.Time  `json:"startAt"`
+	EndAt         *time.Time  `json:"endAt"`
+	UsageLimit    *int        `json:"usageLimit"`
+	PerUserLimit  *int        `json:"perUserLimit"`
+	ParkingLotIds []uuid.UUID `json:"parkingLotIds"`
+	TimeFrameIds  []uuid.UUID `json:"timeFrameIds"`
+	Status        *string     `json:"status"`
+}
+
+type ListVoucherReq struct {
+	CompanyID *string `json:"companyId" form:"companyId"`
+	Code      *string `json:"code" form:"code"`
+	Status    *string `json:"status" form:"status"`
+	Page      int     `json:"page" form:"page"`
+	PageSize  int     `json:"pageSize" form:"pageSize"`
+}
+
+type ListVoucherRes struct {
+	Data []Voucher       `json:"data,omitempty"`
+	Meta ginext.BodyMeta `json:"meta" swaggertype:"object"`
+}
+
+type ListRedemptionReq struct {
+	CompanyID *string    `json:"-" form:"-"`
+	VoucherID *string    `json:"voucherId" form:"voucherId"`
+	Status    *string    `json:"status" form:"status"`
+	From      *time.Time `json:"from" form:"from"`
+	To        *time.Time `json:"to" form:"to"`
+	Page      int        `json:"page" form:"page"`
+	PageSize  int        `json:"pageSize" form:"pageSize"`
+}
+
+type ListRedemptionRes struct {
+	Data []VoucherRedemption `json:"data,omitempty"`
+	Meta ginext.BodyMeta     `json:"meta" swaggertype:"object"`
+}
+
+// VoucherReport sums the redemptions of a voucher over a period, released ones left out
+type VoucherReport struct {
+	VoucherID   uuid.UUID `json:"voucherId"`
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Redemptions int       `json:"redemptions"`
+	Users       int       `json:"users"`
+	Discount    float64   `json:"discount"` // given away
+	Revenue     float64   `json:"revenue"`  // total of the discounted tickets, after discount
+}
+
+type VoucherReportReq struct {
+	CompanyID *string    `json:"companyId" form:"companyId"`
+	From      *time.Time `json:"from" form:"from"`
+	To        *time.Time `json:"to" form:"to"`
+}
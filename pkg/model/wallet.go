@@ -0,0 +1,84 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// types of a wallet transaction
+const (
+	WALLET_TX_TOP_UP = "top_up"
+	WALLET_TX_SPEND  = "spend"
+	WALLET_TX_REFUND = "refund"
+)
+
+// accounts of the ledger
+const (
+	LEDGER_ACCOUNT_WALLET  = "wallet"  // balance of a user, WalletID tells whose
+	LEDGER_ACCOUNT_TOP_UP  = "top_up"  // money paid in through the payment provider
+	LEDGER_ACCOUNT_PARKING = "parking" // money spent on tickets and charges
+)
+
+// Wallet is the prepaid balance of a user. The check keeps the balance from going negative whatever the
+// number of concurrent spends.
+type Wallet struct {
+	BaseModel
+	UserID   uuid.UUID `json:"userId" gorm:"type:uuid;not null;uniqueIndex"`
+	Balance  float64   `json:"balance" gorm:"not null;default:0;check:wallet_balance_non_negative,balance >= 0"`
+	Currency string    `json:"currency"`
+}
+
+func (w *Wallet) TableName() string {
+	return "wallet"
+}
+
+// WalletTransaction is one movement of a wallet. Amount is signed, BalanceAfter is the balance it left.
+type WalletTransaction struct {
+	BaseModel
+	WalletID         uuid.UUID     `json:"walletId" gorm:"type:uuid;not null;index"`
+	Type             string        `json:"type"`
+	Amount           float64       `json:"amount"`
+	BalanceAfter     float64       `json:"balanceAfter"`
+	TicketID         *uuid.UUID    `json:"ticketId,omitempty" gorm:"type:uuid;index"`
+	LongTermTicketID *uuid.UUID    `json:"longTermTicketId,omitempty" gorm:"type:uuid;index"`
+	PaymentID        *uuid.UUID    `json:"paymentId,omitempty" gorm:"type:uuid"`
+	Description      string        `json:"description"`
+	Entries          []LedgerEntry `json:"entries,omitempty" gorm:"foreignKey:TransactionID"`
+}
+
+func (t *WalletTransaction) TableName() string {
+	return "wallet_transaction"
+}
+
+// LedgerEntry is one leg of a wallet transaction, the entries of a transaction sum to zero
+type LedgerEntry struct {
+	BaseModel
+	TransactionID uuid.UUID  `json:"transactionId" gorm:"type:uuid;not null;index"`
+	Account       string     `json:"account" gorm:"not null"`
+	WalletID      *uuid.UUID `json:"walletId,omitempty" gorm:"type:uuid;index"`
+	Amount        float64    `json:"amount"`
+}
+
+func (e *LedgerEntry) TableName() string {
+	return "ledger_entry"
+}
+
+type TopUpReq struct {
+	Amount float64 `json:"amount" valid:"Required"`
+}
+
+type ListWalletTransactionReq struct {
+	WalletID *string    `json:"-" form:"-"`
+	Type     *string    `json:"type" form:"type"`
+	From     *time.Time `json:"from" form:"from"`
+	To       *time.Time `json:"to" form:"to"`
+	Page     int        `json:"page" form:"page"`
+	PageSize int        `json:"pageSize" form:"pageSize"`
+}
+
+type ListWalletTransactionRes struct {
+	Data []WalletTransaction `json:"data,omitempty"`
+	Meta ginext.BodyMeta     `json:"meta" swaggertype:"object"`
+}
@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+func (r *RepoPG) CreateAdmin(ctx context.Context, admin *model.Admin, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(admin).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreateAdmin")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+// GetAdminByEmail returns gorm.ErrRecordNotFound as is, so that the login can tell it apart
+func (r *RepoPG) GetAdminByEmail(ctx context.Context, email string, tx *gorm.DB) (res model.Admin, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.Admin{}).Where("email = ?", email).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, err
+		}
+		log.WithError(err).Error("error_500: error when GetAdminByEmail")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+func (r *RepoPG) GetOneAdmin(ctx context.Context, id uuid.UUID, tx *gorm.DB) (res model.Admin, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.Admin{}).Where("id = ?", id).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, ginext.NewError(http.StatusNotFound, err.Error())
+		}
+		log.WithError(err).Error("error_500: error when GetOneAdmin")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+func (r *RepoPG) CountAdmin(ctx context.Context, tx *gorm.DB) (int64, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var total int64
+	if err := tx.Model(&model.Admin{}).Count(&total).Error; err != nil {
+		return 0, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return total, nil
+}
@@ -0,0 +1,401 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/common"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	AUDIT_CREATE = "create"
+	AUDIT_UPDATE = "update"
+	AUDIT_DELETE = "delete"
+
+	auditBeforeKey = "audit:before"
+	// a single statement touching more rows than this is only audited partially
+	auditMaxRows = 500
+)
+
+// tables that are not audited, either because they are logs themselves or because they only hold secrets
+var auditSkipTables = map[string]bool{
+	"audit_log":            true,
+	"login_attempt":        true,
+	"otp_code":             true,
+	"password_reset_token": true,
+	"refresh_token":        true,
+}
+
+var auditMaskColumns = map[string]bool{"password": true, "token": true, "code": true}
+
+// RegisterAudit installs the gorm callbacks that stamp creator_id/updater_id with the principal of the context
+// and record an audit log row for every created, updated or deleted row. The log is written with the same
+// connection, so it is rolled back together with a failed transaction.
+func RegisterAudit(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("audit:stamp_create", auditStampCreate); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("audit:after_create", auditAfterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("audit:before_update", auditBeforeUpdate); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("audit:after_update", auditAfterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("audit:before_delete", auditSnapshot); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Register("audit:after_delete", auditAfterDelete)
+}
+
+func audited(db *gorm.DB) bool {
+	return db.Error == nil && db.Statement.Schema != nil && !auditSkipTables[db.Statement.Table]
+}
+
+func auditStampCreate(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+	principal, ok := utils.PrincipalFromContext(db.Statement.Context)
+	if !ok {
+		return
+	}
+	for _, column := range []string{"creator_id", "updater_id"} {
+		field := db.Statement.Schema.LookUpField(column)
+		if field == nil {
+			continue
+		}
+		eachRow(db, func(row reflect.Value) {
+			if _, zero := field.ValueOf(db.Statement.Context, row); zero {
+				_ = field.Set(db.Statement.Context, row, &principal.ID)
+			}
+		})
+	}
+}
+
+func auditAfterCreate(db *gorm.DB) {
+	if !audited(db) || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return
+	}
+	var ids []interface{}
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	eachRow(db, func(row reflect.Value) {
+		if id, zero := field.ValueOf(db.Statement.Context, row); !zero {
+			ids = append(ids, id)
+		}
+	})
+	rows := auditRowsByID(db, ids)
+	writeAudit(db, AUDIT_CREATE, nil, rows)
+}
+
+func auditBeforeUpdate(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+	if principal, ok := utils.PrincipalFromContext(db.Statement.Context); ok && db.Statement.Schema.LookUpField("updater_id") != nil {
+		db.Statement.SetColumn("updater_id", principal.ID, true)
+	}
+	auditSnapshot(db)
+}
+
+func auditAfterUpdate(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+	before, _ := db.Statement.Settings.Load(auditBeforeKey)
+	rows, _ := before.([]map[string]interface{})
+	var ids []interface{}
+	for _, row := range rows {
+		ids = append(ids, row["id"])
+	}
+	writeAudit(db, AUDIT_UPDATE, rows, auditRowsByID(db, ids))
+}
+
+func auditAfterDelete(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+	before, _ := db.Statement.Settings.Load(auditBeforeKey)
+	rows, _ := before.([]map[string]interface{})
+	writeAudit(db, AUDIT_DELETE, rows, nil)
+}
+
+// auditSnapshot loads the rows matched by the statement before they are changed
+func auditSnapshot(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+	stmt := db.Statement
+	query := db.Session(&gorm.Session{NewDB: true}).Table(stmt.Table)
+	where, hasWhere := stmt.Clauses["WHERE"]
+	if hasWhere {
+		if expr, ok := where.Expression.(clause.Where); ok {
+			query.Statement.AddClause(expr)
+		}
+	}
+	// gorm adds the primary key condition of the model itself later, while building the sql
+	hasPrimaryKey := false
+	if field := stmt.Schema.PrioritizedPrimaryField; field != nil && stmt.ReflectValue.Kind() == reflect.Struct {
+		if id, zero := field.ValueOf(stmt.Context, stmt.ReflectValue); !zero {
+			query = query.Where(clause.Eq{Column: clause.Column{Name: field.DBName}, Value: id})
+			hasPrimaryKey = true
+		}
+	}
+	if !hasWhere && !hasPrimaryKey {
+		return
+	}
+	var rows []map[string]interface{}
+	if err := query.Limit(auditMaxRows).Find(&rows).Error; err != nil {
+		logger.WithCtx(stmt.Context, "audit").WithError(err).Error("Failed to load rows before " + stmt.Table + " change")
+		return
+	}
+	stmt.Settings.Store(auditBeforeKey, rows)
+}
+
+func auditRowsByID(db *gorm.DB, ids []interface{}) []map[string]interface{} {
+	if len(ids) == 0 {
+		return nil
+	}
+	var rows []map[string]interface{}
+	if err := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).
+		Where("id IN ?", ids).Limit(auditMaxRows).Find(&rows).Error; err != nil {
+		logger.WithCtx(db.Statement.Context, "audit").WithError(err).Error("Failed to load rows after " + db.Statement.Table + " change")
+		return nil
+	}
+	return rows
+}
+
+func writeAudit(db *gorm.DB, action string, before []map[string]interface{}, after []map[string]interface{}) {
+	ctx := db.Statement.Context
+	byID := map[string]map[string]interface{}{}
+	for _, row := range after {
+		byID[auditString(row["id"])] = row
+	}
+
+	var logs []model.AuditLog
+	companies := map[string]*uuid.UUID{}
+	add := func(before, after map[string]interface{}) {
+		row := after
+		if row == nil {
+			row = before
+		}
+		if action == AUDIT_UPDATE {
+			before, after = auditDiff(before, after)
+			if len(after) == 0 {
+				return
+			}
+		}
+		log := model.AuditLog{
+			EntityType: db.Statement.Table,
+			EntityID:   auditString(row["id"]),
+			Action:     action,
+			Before:     auditJSON(before),
+			After:      auditJSON(after),
+			RequestID:  requestID(ctx),
+			CompanyID:  auditCompanyID(db, companies, row),
+		}
+		if principal, ok := utils.PrincipalFromContext(ctx); ok {
+			log.ActorID = &principal.ID
+			log.ActorType = principal.Type
+			log.CreatorID = &principal.ID
+			if log.CompanyID == nil && principal.CompanyID != uuid.Nil {
+				log.CompanyID = &principal.CompanyID
+			}
+		}
+		logs = append(logs, log)
+	}
+	if action == AUDIT_CREATE {
+		for _, row := range after {
+			add(nil, row)
+		}
+	} else {
+		for _, row := range before {
+			add(row, byID[auditString(row["id"])])
+		}
+	}
+	if len(logs) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&logs).Error; err != nil {
+		logger.WithCtx(ctx, "audit").WithError(err).Error("Failed to write audit log of " + db.Statement.Table)
+	}
+}
+
+// auditDiff keeps the columns whose value changed
+func auditDiff(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	b, a := map[string]interface{}{}, map[string]interface{}{}
+	for key, value := range after {
+		if key == "updated_at" {
+			continue
+		}
+		if auditString(before[key]) != auditString(value) {
+			b[key], a[key] = before[key], value
+		}
+	}
+	return b, a
+}
+
+func auditJSON(row map[string]interface{}) *string {
+	if row == nil {
+		return nil
+	}
+	clean := make(map[string]interface{}, len(row))
+	for key, value := range row {
+		if auditMaskColumns[key] {
+			clean[key] = "***"
+			continue
+		}
+		switch v := value.(type) {
+		case []byte:
+			clean[key] = string(v)
+		case [16]byte:
+			clean[key] = uuid.UUID(v).String()
+		default:
+			clean[key] = v
+		}
+	}
+	b, err := json.Marshal(clean)
+	if err != nil {
+		return nil
+	}
+	return valid.StringPointer(string(b))
+}
+
+// auditCompanyID finds the company owning the row: by its own company_id, or through its lot for the rows
+// of a lot (ticket, block, time frame...) and through its block for the slots. found caches the lookups.
+func auditCompanyID(db *gorm.DB, found map[string]*uuid.UUID, row map[string]interface{}) *uuid.UUID {
+	table := db.Statement.Table
+	value := row["company_id"]
+	if table == "company" {
+		value = row["id"]
+	}
+	if id, err := uuid.Parse(auditString(value)); err == nil && id != uuid.Nil {
+		return &id
+	}
+
+	query, key := "", ""
+	if lotID, err := uuid.Parse(auditString(row["parking_lot_id"])); err == nil {
+		query, key = "select company_id from parking_lot where id = ?", lotID.String()
+	} else if blockID, err := uuid.Parse(auditString(row["block_id"])); err == nil {
+		query = "select l.company_id from block b join parking_lot l on l.id = b.parking_lot_id where b.id = ?"
+		key = blockID.String()
+	}
+	if query == "" {
+		return nil
+	}
+	if id, ok := found[query+key]; ok {
+		return id
+	}
+	var companyID *uuid.UUID
+	if err := db.Session(&gorm.Session{NewDB: true}).Raw(query, key).Scan(&companyID).Error; err != nil {
+		logger.WithCtx(db.Statement.Context, "audit").WithError(err).Error("Failed to find the company of " + table)
+	}
+	if companyID != nil && *companyID == uuid.Nil {
+		companyID = nil
+	}
+	found[query+key] = companyID
+	return companyID
+}
+
+func auditString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case [16]byte:
+		return uuid.UUID(v).String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func requestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(common.HeaderXRequestID).(string)
+	return id
+}
+
+func eachRow(db *gorm.DB, f func(row reflect.Value)) {
+	value := db.Statement.ReflectValue
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			row := reflect.Indirect(value.Index(i))
+			if row.Kind() == reflect.Struct {
+				f(row)
+			}
+		}
+	case reflect.Struct:
+		f(value)
+	}
+}
+
+func (r *RepoPG) GetListAuditLog(ctx context.Context, req model.ListAuditLogReq) (res model.ListAuditLogRes, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+
+	tx, cancel := r.DBWithTimeout(ctx)
+	defer cancel()
+
+	tx = tx.Model(&model.AuditLog{})
+
+	if req.CompanyID != nil {
+		tx = tx.Where("company_id = ?", valid.String(req.CompanyID))
+	}
+
+	if req.EntityType != nil {
+		tx = tx.Where("entity_type = ?", valid.String(req.EntityType))
+	}
+
+	if req.EntityID != nil {
+		tx = tx.Where("entity_id = ?", valid.String(req.EntityID))
+	}
+
+	if req.ActorID != nil {
+		tx = tx.Where("actor_id = ?", valid.String(req.ActorID))
+	}
+
+	if req.Action != nil {
+		tx = tx.Where("action = ?", valid.String(req.Action))
+	}
+
+	if req.From != nil {
+		tx = tx.Where("created_at >= ?", req.From)
+	}
+
+	if req.To != nil {
+		tx = tx.Where("created_at < ?", req.To)
+	}
+
+	var total int64 = 0
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
+
+	if err := tx.Count(&total).Order("created_at desc").Limit(pageSize).Offset(r.GetOffset(page, pageSize)).Find(&res.Data).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetListAuditLog")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	if res.Meta, err = r.GetPaginationInfo("", nil, int(total), page, pageSize); err != nil {
+		log.WithError(err).Error("error_500: failed to get pagination")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	return res, nil
+}
@@ -2,10 +2,17 @@ package repo
 
 import (
 	"context"
-	"gitlab.com/goxp/cloud0/ginext"
-	"gorm.io/gorm"
+	"errors"
 	"net/http"
 	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func (r *RepoPG) CreateRefreshToken(ctx context.Context, refreshToken *model.RefreshToken, tx *gorm.DB) error {
@@ -19,3 +26,103 @@ func (r *RepoPG) CreateRefreshToken(ctx context.Context, refreshToken *model.Ref
 	}
 	return nil
 }
+
+// GetRefreshTokenForUpdate locks the row so that two concurrent refreshes of the same token can not both succeed
+func (r *RepoPG) GetRefreshTokenForUpdate(ctx context.Context, tokenHash string, tx *gorm.DB) (model.RefreshToken, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res model.RefreshToken
+	if err := tx.Model(&model.RefreshToken{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token = ?", tokenHash).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.WithError(err).Error("error_401: refresh token not found")
+			return res, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+		}
+		log.WithError(err).Error("error_500: failed to GetRefreshTokenForUpdate")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+func (r *RepoPG) UpdateRefreshToken(ctx context.Context, refreshToken *model.RefreshToken, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.RefreshToken{}).Where("id = ?", refreshToken.ID).Updates(&refreshToken).Error; err != nil {
+		return ginext.NewError(http.StatusInternalServerError, "Error when update refresh token: "+err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) RevokeSession(ctx context.Context, sessionId uuid.UUID, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.RefreshToken{}).
+		Where("session_id = ? and revoked_at is null", sessionId).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to RevokeSession")
+		return ginext.NewError(http.StatusInternalServerError, "Error when revoke session: "+err.Error())
+	}
+	return nil
+}
+
+// RevokeAllSessions revokes every session of a principal, e.g. after a password change
+func (r *RepoPG) RevokeAllSessions(ctx context.Context, userId uuid.UUID, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.RefreshToken{}).
+		Where("user_id = ? and revoked_at is null", userId).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to RevokeAllSessions")
+		return ginext.NewError(http.StatusInternalServerError, "Error when revoke sessions: "+err.Error())
+	}
+	return nil
+}
+
+// GetActiveSessionsByUser returns the live token of every session, a session has at most one
+func (r *RepoPG) GetActiveSessionsByUser(ctx context.Context, userId uuid.UUID, tx *gorm.DB) ([]model.RefreshToken, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res []model.RefreshToken
+	if err := tx.Model(&model.RefreshToken{}).
+		Where("user_id = ?", userId).
+		Where("used_at is null and revoked_at is null and expired_date > ?", time.Now()).
+		Order("created_at desc").
+		Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetActiveSessionsByUser")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+func (r *RepoPG) IsSessionActive(ctx context.Context, sessionId uuid.UUID) (bool, error) {
+	tx, cancel := r.DBWithTimeout(ctx)
+	defer cancel()
+
+	var total int64
+	if err := tx.Model(&model.RefreshToken{}).
+		Where("session_id = ?", sessionId).
+		Where("revoked_at is null and expired_date > ?", time.Now()).
+		Count(&total).Error; err != nil {
+		return false, err
+	}
+	return total > 0, nil
+}
@@ -39,6 +39,7 @@ type PGInterface interface {
 	DBWithTimeout(ctx context.Context) (*gorm.DB, context.CancelFunc)
 	DB() (db *gorm.DB)
 	Transaction(ctx context.Context, f func(rp PGInterface) error) error
+	Savepoint(ctx context.Context, name string, f func() error) error
 
 	// user
 	GetOneUserByPhone(ctx context.Context, phoneNumber string, tx *gorm.DB) (*model.User, error)
@@ -50,13 +51,16 @@ type PGInterface interface {
 	// favorite
 	GetAllFavoriteParkingByUser(ctx context.Context, userId string, tx *gorm.DB) (res []model.Favorite, err error)
 	CreateFavorite(ctx context.Context, favorite *model.Favorite, tx *gorm.DB) error
-	DeleteOneFavorite(ctx context.Context, id uuid.UUID, tx *gorm.DB) error
+	DeleteOneFavorite(ctx context.Context, id uuid.UUID, userId uuid.UUID, tx *gorm.DB) error
 	GetOne(ctx context.Context, req model.FavoriteRequestV2, tx *gorm.DB) (model.Favorite, error)
 
 	// time frame
 	GetAllTimeFrame(ctx context.Context, req model.GetListTimeFrameParam, tx *gorm.DB) (res *model.ListTimeFrame, err error)
 	CreateMultiTimeFrame(ctx context.Context, timeFrame []model.TimeFrame, tx *gorm.DB) (err error)
 	DeleteTimeFrameByParkingLotID(ctx context.Context, parkingLotID string, tx *gorm.DB) (err error)
+	GetPricingRules(ctx context.Context, parkingLotID uuid.UUID, tx *gorm.DB) ([]model.PricingRule, error)
+	CreatePricingRules(ctx context.Context, rules []model.PricingRule, tx *gorm.DB) error
+	DeletePricingRulesByParkingLotID(ctx context.Context, parkingLotID uuid.UUID, tx *gorm.DB) error
 
 	CreateTimeframe(ctx context.Context, req *model.TimeFrame) error
 	GetOneTimeframe(ctx context.Context, id uuid.UUID) (model.TimeFrame, error)
@@ -65,20 +69,125 @@ type PGInterface interface {
 
 	// ticket
 	CreateTicket(ctx context.Context, req *model.Ticket, tx *gorm.DB) error
-	GetAllTicket(ctx context.Context, req model.GetListTicketParam, tx *gorm.DB) ([]model.Ticket, error)
+	GetTicketsByPlate(ctx context.Context, number string, tx *gorm.DB) ([]model.Ticket, error)
+	HasOngoingTicket(ctx context.Context, vehicleID uuid.UUID, tx *gorm.DB) (bool, error)
+	LockParkingSlot(ctx context.Context, id uuid.UUID, tx *gorm.DB) error
+	HasOverlapTicket(ctx context.Context, parkingSlotID uuid.UUID, start, end time.Time, tx *gorm.DB) (bool, error)
+	UpdateTicketState(ctx context.Context, ticket *model.Ticket, from model.TicketState, tx *gorm.DB) error
+	CreateTicketStateHistory(ctx context.Context, history *model.TicketStateHistory, tx *gorm.DB) error
+	GetListTicketStateHistory(ctx context.Context, ticketID uuid.UUID, tx *gorm.DB) ([]model.TicketStateHistory, error)
+	CreateTicketCharge(ctx context.Context, charge *model.TicketCharge, tx *gorm.DB) error
+	GetAllTicket(ctx context.Context, req model.GetListTicketParam, tx *gorm.DB) (model.ListTicketRes, error)
 	GetOneTicket(ctx context.Context, id string, tx *gorm.DB) (model.Ticket, error)
+	GetOneTicketForUpdate(ctx context.Context, id uuid.UUID, tx *gorm.DB) (model.Ticket, error)
+	MarkTicketExtended(ctx context.Context, id uuid.UUID, tx *gorm.DB) error
 	GetOneTicketWithExtend(ctx context.Context, id string, tx *gorm.DB) (model.Ticket, error)
 	GetListExtendTicketByOrigin(ctx context.Context, idParent string, tx *gorm.DB) ([]model.Ticket, error)
 	UpdateTicket(ctx context.Context, ticket *model.Ticket, tx *gorm.DB) error
-	GetAllTicketCompany(ctx context.Context, req model.GetListTicketReq) (res []model.GetListTicketRes, err error)
+	GetAllTicketCompany(ctx context.Context, req model.GetListTicketReq) (model.ListTicketCompanyRes, error)
 
 	// ticket extend
 	CreateTicketExtend(ctx context.Context, req *model.TicketExtend, tx *gorm.DB) error
+	IsTicketExtension(ctx context.Context, id uuid.UUID, tx *gorm.DB) (bool, error)
+
 	// long term ticket
 	CreateLongTermTicket(ctx context.Context, ltTicket *model.LongTermTicket, tx *gorm.DB) error
+	GetOneLongTermTicket(ctx context.Context, id uuid.UUID, tx *gorm.DB) (model.LongTermTicket, error)
+	GetListLongTermTicket(ctx context.Context, req model.ListLongTermTicketReq, tx *gorm.DB) ([]model.LongTermTicket, error)
+	UpdateLongTermTicket(ctx context.Context, ltTicket *model.LongTermTicket, tx *gorm.DB) error
 
 	// token
 	CreateRefreshToken(ctx context.Context, refreshToken *model.RefreshToken, tx *gorm.DB) error
+	GetRefreshTokenForUpdate(ctx context.Context, tokenHash string, tx *gorm.DB) (model.RefreshToken, error)
+	UpdateRefreshToken(ctx context.Context, refreshToken *model.RefreshToken, tx *gorm.DB) error
+	RevokeSession(ctx context.Context, sessionId uuid.UUID, tx *gorm.DB) error
+	GetActiveSessionsByUser(ctx context.Context, userId uuid.UUID, tx *gorm.DB) ([]model.RefreshToken, error)
+	RevokeAllSessions(ctx context.Context, userId uuid.UUID, tx *gorm.DB) error
+	CreatePasswordResetToken(ctx context.Context, token *model.PasswordResetToken, tx *gorm.DB) error
+	GetPasswordResetTokenForUpdate(ctx context.Context, tokenHash string, tx *gorm.DB) (model.PasswordResetToken, error)
+	UpdatePasswordResetToken(ctx context.Context, token *model.PasswordResetToken, tx *gorm.DB) error
+
+	// otp
+	CreateOtpCode(ctx context.Context, otp *model.OtpCode, tx *gorm.DB) error
+	GetLatestOtpCode(ctx context.Context, phoneNumber string, tx *gorm.DB) (model.OtpCode, error)
+	IncreaseOtpAttempts(ctx context.Context, id uuid.UUID, maxAttempts int, tx *gorm.DB) (bool, error)
+	MarkOtpVerified(ctx context.Context, id uuid.UUID, tx *gorm.DB) error
+
+	// admin
+	CreateAdmin(ctx context.Context, admin *model.Admin, tx *gorm.DB) error
+	GetAdminByEmail(ctx context.Context, email string, tx *gorm.DB) (model.Admin, error)
+	GetOneAdmin(ctx context.Context, id uuid.UUID, tx *gorm.DB) (model.Admin, error)
+	CountAdmin(ctx context.Context, tx *gorm.DB) (int64, error)
+
+	// status history
+	CreateStatusHistory(ctx context.Context, history *model.StatusHistory, tx *gorm.DB) error
+	GetListStatusHistory(ctx context.Context, req model.ListStatusHistoryReq) (model.ListStatusHistoryRes, error)
+
+	// notification
+	CreateNotification(ctx context.Context, notification *model.Notification, tx *gorm.DB) error
+	GetListNotification(ctx context.Context, req model.ListNotificationReq) (model.ListNotificationRes, error)
+
+	// no-show
+	TryAdvisoryXactLock(ctx context.Context, key int64, tx *gorm.DB) (bool, error)
+	GetNoShowTickets(ctx context.Context, defaultGrace time.Duration, limit int, tx *gorm.DB) ([]model.Ticket, error)
+
+	// payment
+	CreatePayment(ctx context.Context, payment *model.Payment, tx *gorm.DB) error
+	UpdatePayment(ctx context.Context, payment *model.Payment, tx *gorm.DB) error
+	GetOnePayment(ctx context.Context, id uuid.UUID, tx *gorm.DB) (model.Payment, error)
+	GetPaymentByRef(ctx context.Context, provider string, ref string, tx *gorm.DB) (model.Payment, error)
+	GetPaidPayment(ctx context.Context, ticketID uuid.UUID, longTermTicketID *uuid.UUID, tx *gorm.DB) (*model.Payment, error)
+	HasPaymentTransaction(ctx context.Context, eventID string, tx *gorm.DB) (bool, error)
+	CreatePaymentTransaction(ctx context.Context, transaction *model.PaymentTransaction, tx *gorm.DB) error
+	UpdateTicketPaymentStatus(ctx context.Context, ids []uuid.UUID, status string, tx *gorm.DB) error
+	GetUnpaidTickets(ctx context.Context, hold time.Duration, limit int, tx *gorm.DB) ([]model.Ticket, error)
+	GetRefundDuePayments(ctx context.Context, before time.Time, limit int, tx *gorm.DB) ([]model.Payment, error)
+
+	// wallet
+	GetWallet(ctx context.Context, userID uuid.UUID, tx *gorm.DB) (*model.Wallet, error)
+	GetOrCreateWallet(ctx context.Context, userID uuid.UUID, currency string, tx *gorm.DB) (model.Wallet, error)
+	AddWalletBalance(ctx context.Context, walletID uuid.UUID, delta float64, tx *gorm.DB) (float64, error)
+	CreateWalletTransaction(ctx context.Context, transaction *model.WalletTransaction, tx *gorm.DB) error
+	GetWalletSpend(ctx context.Context, ticketID uuid.UUID, longTermTicketID *uuid.UUID, tx *gorm.DB) (*model.WalletTransaction, error)
+	GetListWalletTransaction(ctx context.Context, req model.ListWalletTransactionReq, tx *gorm.DB) (model.ListWalletTransactionRes, error)
+
+	// voucher
+	CreateVoucher(ctx context.Context, voucher *model.Voucher, tx *gorm.DB) error
+	UpdateVoucher(ctx context.Context, voucher *model.Voucher, tx *gorm.DB) error
+	GetOneVoucher(ctx context.Context, id uuid.UUID, tx *gorm.DB) (model.Voucher, error)
+	GetVoucherByCode(ctx context.Context, companyID uuid.UUID, code string, tx *gorm.DB) (model.Voucher, error)
+	GetListVoucher(ctx context.Context, req model.ListVoucherReq, tx *gorm.DB) (model.ListVoucherRes, error)
+	DeleteVoucher(ctx context.Context, id uuid.UUID, tx *gorm.DB) error
+	AddVoucherUse(ctx context.Context, id uuid.UUID, delta int, tx *gorm.DB) error
+	CountUserRedemptions(ctx context.Context, voucherID uuid.UUID, userID uuid.UUID, tx *gorm.DB) (int64, error)
+	CountUserBookings(ctx context.Context, userID uuid.UUID, companyID uuid.UUID, tx *gorm.DB) (int64, error)
+	CreateVoucherRedemption(ctx context.Context, redemption *model.VoucherRedemption, tx *gorm.DB) error
+	ReleaseVoucherRedemption(ctx context.Context, ticketID uuid.UUID, tx *gorm.DB) (bool, error)
+	GetListRedemption(ctx context.Context, req model.ListRedemptionReq, tx *gorm.DB) (model.ListRedemptionRes, error)
+	GetVoucherReport(ctx context.Context, req model.VoucherReportReq, tx *gorm.DB) ([]model.VoucherReport, error)
+
+	// settlement
+	GetSettlementLines(ctx context.Context, companyID uuid.UUID, start, end time.Time, tx *gorm.DB) ([]model.SettlementLine, error)
+	CreateSettlement(ctx context.Context, settlement *model.Settlement, tx *gorm.DB) error
+	GetOneSettlement(ctx context.Context, id uuid.UUID, tx *gorm.DB) (model.Settlement, error)
+	GetListSettlement(ctx context.Context, req model.ListSettlementReq, tx *gorm.DB) (model.ListSettlementRes, error)
+	GetSettlementsDue(ctx context.Context, tx *gorm.DB) ([]model.SettlementDue, error)
+
+	// setting
+	GetSetting(ctx context.Context, parkingLotID uuid.UUID, key string, tx *gorm.DB) (model.Setting, error)
+	GetCompanySetting(ctx context.Context, companyID uuid.UUID, key string, tx *gorm.DB) (model.Setting, error)
+	SaveSetting(ctx context.Context, setting *model.Setting, tx *gorm.DB) error
+
+	// audit log
+	GetListAuditLog(ctx context.Context, req model.ListAuditLogReq) (model.ListAuditLogRes, error)
+	MarkNotificationRead(ctx context.Context, id uuid.UUID, req model.ListNotificationReq, tx *gorm.DB) error
+
+	// login attempt
+	GetLoginAttempt(ctx context.Context, identifier string, tx *gorm.DB) (model.LoginAttempt, error)
+	IncreaseLoginAttempt(ctx context.Context, identifier string, window time.Duration, tx *gorm.DB) (model.LoginAttempt, error)
+	LockLoginAttempt(ctx context.Context, identifier string, until time.Time, tx *gorm.DB) error
+	ResetLoginAttempt(ctx context.Context, identifier string, tx *gorm.DB) error
+	IsSessionActive(ctx context.Context, sessionId uuid.UUID) (bool, error)
 
 	// Parking lot
 	CreateParkingLot(ctx context.Context, req *model.ParkingLot) error
@@ -102,6 +211,7 @@ type PGInterface interface {
 	GetOneParkingSlot(ctx context.Context, id uuid.UUID) (model.ParkingSlot, error)
 	GetListParkingSlot(ctx context.Context, req model.ListParkingSlotReq) (model.ListParkingSlotRes, error)
 	GetAvailableParkingSlot(ctx context.Context, req model.AvailableParkingSlotReq) (model.ListParkingSlotRes, error)
+	GetFreeParkingSlot(ctx context.Context, parkingLotID uuid.UUID, preferBlockID uuid.UUID, start, end time.Time, tx *gorm.DB) (*model.ParkingSlot, error)
 	UpdateParkingSlot(ctx context.Context, req *model.ParkingSlot) error
 	DeleteParkingSlot(ctx context.Context, id uuid.UUID) error
 
@@ -111,6 +221,8 @@ type PGInterface interface {
 	GetListVehicle(ctx context.Context, req model.ListVehicleReq) (model.ListVehicleRes, error)
 	UpdateVehicle(ctx context.Context, req *model.Vehicle) error
 	DeleteVehicle(ctx context.Context, id uuid.UUID) error
+	CreateGuestVehicle(ctx context.Context, vehicle *model.Vehicle, tx *gorm.DB) error
+	GetGuestVehicle(ctx context.Context, number string, tx *gorm.DB) (*model.Vehicle, error)
 
 	// company
 	CreateCompany(ctx context.Context, req *model.Company) error
@@ -163,6 +275,25 @@ func (r *RepoPG) Transaction(ctx context.Context, f func(rp PGInterface) error)
 	return nil
 }
 
+// Savepoint runs f within the current transaction under a savepoint: when f fails, only what f did is
+// rolled back and the transaction can go on
+func (r *RepoPG) Savepoint(ctx context.Context, name string, f func() error) error {
+	log := logger.WithCtx(ctx, "RepoPG.Savepoint")
+	tx := r.db.WithContext(ctx)
+	if err := tx.SavePoint(name).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to create savepoint")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	if err := f(); err != nil {
+		if rbErr := tx.RollbackTo(name).Error; rbErr != nil {
+			log.WithError(rbErr).Error("error_500: failed to roll back to savepoint")
+			return ginext.NewError(http.StatusInternalServerError, rbErr.Error())
+		}
+		return err
+	}
+	return nil
+}
+
 func (r *RepoPG) DB() *gorm.DB {
 	return r.db
 }
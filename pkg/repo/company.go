@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"parking-server/pkg/model"
 	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
 )
 
 func (r *RepoPG) CreateCompany(ctx context.Context, req *model.Company) error {
@@ -73,6 +74,10 @@ func (r *RepoPG) GetListCompany(ctx context.Context, req model.ListCompanyReq) (
 
 	tx = tx.Model(&model.Company{})
 
+	if req.Status != nil {
+		tx = tx.Where("status = ?", valid.String(req.Status))
+	}
+
 	if req.Sort != "" {
 		tx = tx.Order(req.Sort)
 	} else {
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"time"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+// GetLoginAttempt returns a zero attempt when the identifier has no failure recorded
+func (r *RepoPG) GetLoginAttempt(ctx context.Context, identifier string, tx *gorm.DB) (model.LoginAttempt, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	res := model.LoginAttempt{Identifier: identifier}
+	if err := tx.Model(&model.LoginAttempt{}).Where("identifier = ?", identifier).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, nil
+		}
+		log.WithError(err).Error("error_500: failed to GetLoginAttempt")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+// IncreaseLoginAttempt atomically counts a failure, the counter restarts when the last failure is older than window
+func (r *RepoPG) IncreaseLoginAttempt(ctx context.Context, identifier string, window time.Duration, tx *gorm.DB) (model.LoginAttempt, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	now := time.Now()
+	var res model.LoginAttempt
+	if err := tx.Raw(`INSERT INTO login_attempt (identifier, failures, last_failed_at) VALUES (?, 1, ?)
+		ON CONFLICT (identifier) DO UPDATE SET
+			failures = CASE WHEN login_attempt.last_failed_at < ? THEN 1 ELSE login_attempt.failures + 1 END,
+			last_failed_at = EXCLUDED.last_failed_at,
+			updated_at = EXCLUDED.last_failed_at
+		RETURNING *`, identifier, now, now.Add(-window)).Scan(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to IncreaseLoginAttempt")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+func (r *RepoPG) LockLoginAttempt(ctx context.Context, identifier string, until time.Time, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.LoginAttempt{}).Where("identifier = ?", identifier).
+		Update("locked_until", until).Error; err != nil {
+		return ginext.NewError(http.StatusInternalServerError, "Error when lock login attempt: "+err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) ResetLoginAttempt(ctx context.Context, identifier string, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.LoginAttempt{}).Where("identifier = ?", identifier).
+		Updates(map[string]interface{}{"failures": 0, "locked_until": nil}).Error; err != nil {
+		return ginext.NewError(http.StatusInternalServerError, "Error when reset login attempt: "+err.Error())
+	}
+	return nil
+}
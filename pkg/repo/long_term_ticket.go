@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+func (r *RepoPG) GetOneLongTermTicket(ctx context.Context, id uuid.UUID, tx *gorm.DB) (model.LongTermTicket, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res model.LongTermTicket
+	if err := tx.Where("id = ?", id).Preload("Tickets", func(db *gorm.DB) *gorm.DB {
+		return db.Order("start_time")
+	}).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.WithError(err).Error("error_404: not found")
+			return res, ginext.NewError(http.StatusNotFound, err.Error())
+		}
+		log.WithError(err).Error("error_500: failed to GetOneLongTermTicket")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+func (r *RepoPG) GetListLongTermTicket(ctx context.Context, req model.ListLongTermTicketReq, tx *gorm.DB) ([]model.LongTermTicket, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	tx = tx.Model(&model.LongTermTicket{})
+	if req.UserId != nil {
+		tx = tx.Where("user_id = ?", valid.String(req.UserId))
+	}
+	if req.State != nil {
+		tx = tx.Where("state = ?", valid.String(req.State))
+	}
+	var res []model.LongTermTicket
+	if err := tx.Order("start_time desc").Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetListLongTermTicket")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+func (r *RepoPG) UpdateLongTermTicket(ctx context.Context, ltTicket *model.LongTermTicket, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.LongTermTicket{}).Where("id = ?", ltTicket.ID).Omit("Tickets").Updates(ltTicket).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to UpdateLongTermTicket")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
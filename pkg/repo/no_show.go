@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"time"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+// TryAdvisoryXactLock takes the postgres advisory lock key until the end of the transaction, without waiting.
+// It reports false when another session holds the lock.
+func (r *RepoPG) TryAdvisoryXactLock(ctx context.Context, key int64, tx *gorm.DB) (bool, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var locked bool
+	if err := tx.Raw("select pg_try_advisory_xact_lock(?)", key).Scan(&locked).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to TryAdvisoryXactLock")
+		return false, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return locked, nil
+}
+
+// GetNoShowTickets locks and returns new tickets not checked in within the grace period of their lot,
+// or defaultGrace for lots without the setting
+func (r *RepoPG) GetNoShowTickets(ctx context.Context, defaultGrace time.Duration, limit int, tx *gorm.DB) ([]model.Ticket, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	query := `select t.* from ticket t
+			left join setting s on s.parking_lot_id = t.parking_lot_id and s.key = ? and s.deleted_at is null
+			where t.state = ?
+			  and t.entry_time is null
+			  and t.deleted_at is null
+			  and t.start_time + make_interval(mins => coalesce((s.value->>'minutes')::int, ?)) < now()
+			order by t.start_time
+			limit ?
+			for update of t skip locked`
+	var res []model.Ticket
+	if err := tx.Raw(query, model.SETTING_NO_SHOW_GRACE_PERIOD, model.TicketStateNew, int(defaultGrace.Minutes()), limit).
+		Scan(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetNoShowTickets")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+func (r *RepoPG) CreateNotification(ctx context.Context, notification *model.Notification, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(notification).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreateNotification")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) GetListNotification(ctx context.Context, req model.ListNotificationReq) (res model.ListNotificationRes, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+
+	tx, cancel := r.DBWithTimeout(ctx)
+	defer cancel()
+
+	tx = tx.Model(&model.Notification{})
+
+	if req.CompanyID != nil {
+		tx = tx.Where("company_id = ?", valid.String(req.CompanyID))
+	}
+
+	if req.UserID != nil {
+		tx = tx.Where("user_id = ?", valid.String(req.UserID))
+	}
+
+	if req.Unread {
+		tx = tx.Where("read_at is null")
+	}
+
+	var total int64 = 0
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
+
+	if err := tx.Count(&total).Order("created_at desc").Limit(pageSize).Offset(r.GetOffset(page, pageSize)).Find(&res.Data).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetListNotification")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	if res.Meta, err = r.GetPaginationInfo("", nil, int(total), page, pageSize); err != nil {
+		log.WithError(err).Error("error_500: failed to get pagination")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	return res, nil
+}
+
+// MarkNotificationRead marks the notification read if it belongs to the owner given in req
+func (r *RepoPG) MarkNotificationRead(ctx context.Context, id uuid.UUID, req model.ListNotificationReq, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if req.CompanyID == nil && req.UserID == nil {
+		return ginext.NewError(http.StatusForbidden, utils.MessageError()[http.StatusForbidden])
+	}
+	tx = tx.Model(&model.Notification{}).Where("id = ? and read_at is null", id)
+	if req.CompanyID != nil {
+		tx = tx.Where("company_id = ?", valid.String(req.CompanyID))
+	}
+	if req.UserID != nil {
+		tx = tx.Where("user_id = ?", valid.String(req.UserID))
+	}
+	rs := tx.Update("read_at", time.Now())
+	if rs.Error != nil {
+		return ginext.NewError(http.StatusInternalServerError, "Error when update notification: "+rs.Error.Error())
+	}
+	return nil
+}
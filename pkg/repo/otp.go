@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+func (r *RepoPG) CreateOtpCode(ctx context.Context, otp *model.OtpCode, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(otp).Error; err != nil {
+		return ginext.NewError(http.StatusInternalServerError, "Error when create otp: "+err.Error())
+	}
+	return nil
+}
+
+// GetLatestOtpCode returns the last code sent to the phone number, only that one can be verified
+func (r *RepoPG) GetLatestOtpCode(ctx context.Context, phoneNumber string, tx *gorm.DB) (model.OtpCode, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res model.OtpCode
+	if err := tx.Model(&model.OtpCode{}).Where("phone_number = ?", phoneNumber).
+		Order("created_at desc").Take(&res).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			log.WithError(err).Error("error_500: failed to GetLatestOtpCode")
+		}
+		return res, err
+	}
+	return res, nil
+}
+
+// IncreaseOtpAttempts counts a verification attempt, it returns false once maxAttempts is reached
+func (r *RepoPG) IncreaseOtpAttempts(ctx context.Context, id uuid.UUID, maxAttempts int, tx *gorm.DB) (bool, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	rs := tx.Model(&model.OtpCode{}).
+		Where("id = ? and attempts < ? and verified_at is null", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if rs.Error != nil {
+		return false, ginext.NewError(http.StatusInternalServerError, "Error when update otp: "+rs.Error.Error())
+	}
+	return rs.RowsAffected > 0, nil
+}
+
+func (r *RepoPG) MarkOtpVerified(ctx context.Context, id uuid.UUID, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.OtpCode{}).Where("id = ?", id).Update("verified_at", time.Now()).Error; err != nil {
+		return ginext.NewError(http.StatusInternalServerError, "Error when update otp: "+err.Error())
+	}
+	return nil
+}
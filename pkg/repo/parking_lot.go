@@ -35,7 +35,10 @@ func (r *RepoPG) GetOneParkingLot(ctx context.Context, id uuid.UUID) (res model.
 	tx, cancel := r.DBWithTimeout(ctx)
 	defer cancel()
 
-	if err = tx.Model(&model.ParkingLot{}).Where("id = ?", id).Preload("TimeFrames").Preload("Blocks").Take(&res).Error; err != nil {
+	if err = tx.Model(&model.ParkingLot{}).Where("id = ?", id).Preload("TimeFrames").
+		Preload("PricingRules", func(db *gorm.DB) *gorm.DB {
+			return db.Order("priority desc, created_at")
+		}).Preload("Blocks").Take(&res).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.WithError(err).Error("error_404: not found")
 			return res, ginext.NewError(http.StatusNotFound, err.Error())
@@ -139,6 +142,20 @@ func (r *RepoPG) UpdateParkingLotV2(ctx context.Context,
 			}
 		}
 
+		// rules are replaced as a whole, and only when given
+		if parkingLot.PricingRules != nil {
+			if err := tx.Where("parking_lot_id = ?", parkingLot.ID).Delete(&model.PricingRule{}).Error; err != nil {
+				log.WithError(err).Error("error_500: error when DeletePricingRule")
+				return ginext.NewError(http.StatusInternalServerError, err.Error())
+			}
+			if len(parkingLot.PricingRules) >= 1 {
+				if err := tx.Model(&model.PricingRule{}).Create(&parkingLot.PricingRules).Error; err != nil {
+					log.WithError(err).Error("error_500: error when CreatePricingRule")
+					return ginext.NewError(http.StatusInternalServerError, err.Error())
+				}
+			}
+		}
+
 		if len(newBlocks) >= 1 {
 			if err := tx.Model(&model.Block{}).Create(&newBlocks).Error; err != nil {
 				log.WithError(err).Error("error_500: error when CreateBlock")
@@ -146,7 +163,7 @@ func (r *RepoPG) UpdateParkingLotV2(ctx context.Context,
 			}
 		}
 
-		if err := tx.Model(&model.ParkingLot{}).Where("id = ?", parkingLot.ID).Save(&parkingLot).Error; err != nil {
+		if err := tx.Model(&model.ParkingLot{}).Where("id = ?", parkingLot.ID).Omit("PricingRules").Save(&parkingLot).Error; err != nil {
 			log.WithError(err).Error("error_500: error when UpdateParkingLot")
 			return ginext.NewError(http.StatusInternalServerError, err.Error())
 		}
@@ -185,6 +202,10 @@ func (r *RepoPG) GetListParkingLotCompany(ctx context.Context, req model.GetList
 		tx = tx.Where("company_id = ?", valid.String(req.CompanyID))
 	}
 
+	if req.Status != nil {
+		tx = tx.Where("status = ?", valid.String(req.Status))
+	}
+
 	if req.Name != nil {
 		name := utils.TransformString(valid.String(req.Name), false)
 		tx = tx.Where("unaccent(name) ilike ?", name+"%")
@@ -244,8 +265,7 @@ SELECT pl.id AS id,
   (SELECT Count(*)
    FROM ticket t
    WHERE t.parking_lot_id = pl.id
-     AND t.state NOT IN ('cancel',
-                         'completed')) AS booked_slots,
+     AND t.state IN ?) AS booked_slots,
   (SELECT Count(*) AS total_booked_slots
    FROM ticket t
    WHERE t.parking_lot_id = pl.id) AS total_booked_slots,
@@ -264,7 +284,7 @@ WHERE pl.status = 'active'
   AND pl.id IN (%s)
         `, ids)
 
-	if err := r.db.Raw(query).Scan(&res).Error; err != nil {
+	if err := r.db.Raw(query, model.TicketActiveStates).Scan(&res).Error; err != nil {
 		log.WithError(err).Error("error_500: failed to get pagination")
 		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
 	}
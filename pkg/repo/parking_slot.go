@@ -7,6 +7,7 @@ import (
 	"gitlab.com/goxp/cloud0/ginext"
 	"gitlab.com/goxp/cloud0/logger"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"net/http"
 	"parking-server/pkg/model"
 	"parking-server/pkg/utils"
@@ -103,17 +104,17 @@ func (r *RepoPG) GetAvailableParkingSlot(ctx context.Context, req model.Availabl
 									join block b on sl.block_id = b.id
 									where sl.id  not in ( select t.parking_slot_id as id 
 									                      from ticket t
-															where t.state in ('new', 'extend', 'ongoing')
+															where t.state in ?
 															  and t.parking_lot_id = ?
 															  and t.start_time < ?
-															  and t.end_time > ?) 
+															  and coalesce(t.end_time, 'infinity') > ?) 
 									  and b.parking_lot_id = ?
 									order by
 										b.code,
 										sl.created_at`)
 	req.Start = valid.DayTimePointer(valid.DayTime(req.Start).Add(1 * time.Second))
 	req.End = valid.DayTimePointer(valid.DayTime(req.End).Add(-1 * time.Second))
-	if err := tx.Raw(query, req.ParkingLotId, req.End, req.Start, req.ParkingLotId).Scan(&res.Data).Error; err != nil {
+	if err := tx.Raw(query, model.TicketActiveStates, req.ParkingLotId, req.End, req.Start, req.ParkingLotId).Scan(&res.Data).Error; err != nil {
 		log.WithError(err).Error("error_500: failed to GetAvailableParkingSlot")
 		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
 	}
@@ -144,3 +145,33 @@ func (r *RepoPG) DeleteParkingSlot(ctx context.Context, id uuid.UUID) error {
 	}
 	return nil
 }
+
+// GetFreeParkingSlot returns a slot of the lot with no active ticket overlapping [start, end), preferring
+// the slots of preferBlockID. It returns nil when the lot is full.
+func (r *RepoPG) GetFreeParkingSlot(ctx context.Context, parkingLotID uuid.UUID, preferBlockID uuid.UUID, start, end time.Time, tx *gorm.DB) (*model.ParkingSlot, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res []model.ParkingSlot
+	if err := tx.Model(&model.ParkingSlot{}).
+		Joins("join block b on b.id = parking_slot.block_id and b.deleted_at is null").
+		Where("b.parking_lot_id = ?", parkingLotID).
+		Where(`not exists (select 1 from ticket t
+				where t.parking_slot_id = parking_slot.id
+				  and t.state in ?
+				  and t.deleted_at is null
+				  and t.start_time < ?
+				  and coalesce(t.end_time, 'infinity') > ?)`, model.TicketActiveStates, end, start).
+		Order(clause.Expr{SQL: "parking_slot.block_id = ? desc, b.code, parking_slot.created_at", Vars: []interface{}{preferBlockID}}).
+		Limit(1).Preload("Block").Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetFreeParkingSlot")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return &res[0], nil
+}
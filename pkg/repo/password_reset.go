@@ -0,0 +1,58 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *RepoPG) CreatePasswordResetToken(ctx context.Context, token *model.PasswordResetToken, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(token).Error; err != nil {
+		return ginext.NewError(http.StatusInternalServerError, "Error when create reset token: "+err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) GetPasswordResetTokenForUpdate(ctx context.Context, tokenHash string, tx *gorm.DB) (model.PasswordResetToken, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res model.PasswordResetToken
+	if err := tx.Model(&model.PasswordResetToken{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token = ?", tokenHash).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.WithError(err).Error("error_401: reset token not found")
+			return res, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+		}
+		log.WithError(err).Error("error_500: failed to GetPasswordResetTokenForUpdate")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+func (r *RepoPG) UpdatePasswordResetToken(ctx context.Context, token *model.PasswordResetToken, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.PasswordResetToken{}).Where("id = ?", token.ID).Updates(token).Error; err != nil {
+		return ginext.NewError(http.StatusInternalServerError, "Error when update reset token: "+err.Error())
+	}
+	return nil
+}
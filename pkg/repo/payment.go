@@ -0,0 +1,192 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *RepoPG) CreatePayment(ctx context.Context, payment *model.Payment, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(payment).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreatePayment")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) UpdatePayment(ctx context.Context, payment *model.Payment, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.Payment{}).Where("id = ?", payment.ID).
+		Select("status", "refunded", "refund_due", "updater_id", "updated_at").Updates(payment).Error; err != nil {
+		log.WithError(err).Error("error_500: error when UpdatePayment")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) GetOnePayment(ctx context.Context, id uuid.UUID, tx *gorm.DB) (model.Payment, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res model.Payment
+	if err := tx.Model(&model.Payment{}).Where("id = ?", id).Preload("Transactions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.WithError(err).Error("error_404: payment not found")
+			return res, ginext.NewError(http.StatusNotFound, err.Error())
+		}
+		log.WithError(err).Error("error_500: failed to GetOnePayment")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+// GetPaymentByRef returns the payment of the provider reference. Inside a transaction the payment stays locked
+// until commit, so the events of one payment are applied one after another.
+func (r *RepoPG) GetPaymentByRef(ctx context.Context, provider string, ref string, tx *gorm.DB) (model.Payment, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res model.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&model.Payment{}).
+		Where("provider = ? and provider_ref = ?", provider, ref).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.WithError(err).Error("error_404: payment not found")
+			return res, ginext.NewError(http.StatusNotFound, err.Error())
+		}
+		log.WithError(err).Error("error_500: failed to GetPaymentByRef")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+// GetPaidPayment returns the payment that paid for the ticket, on its own or with its series, nil if none
+func (r *RepoPG) GetPaidPayment(ctx context.Context, ticketID uuid.UUID, longTermTicketID *uuid.UUID, tx *gorm.DB) (*model.Payment, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	tx = tx.Model(&model.Payment{}).Where("status in ?", []string{model.PAYMENT_SUCCEEDED, model.PAYMENT_REFUNDED})
+	if longTermTicketID != nil {
+		tx = tx.Where("ticket_id = ? or long_term_ticket_id = ?", ticketID, longTermTicketID)
+	} else {
+		tx = tx.Where("ticket_id = ?", ticketID)
+	}
+	var res []model.Payment
+	if err := tx.Order("created_at desc").Limit(1).Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetPaidPayment")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return &res[0], nil
+}
+
+// HasPaymentTransaction reports whether the event of the provider was already applied
+func (r *RepoPG) HasPaymentTransaction(ctx context.Context, eventID string, tx *gorm.DB) (bool, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var total int64
+	if err := tx.Model(&model.PaymentTransaction{}).Where("event_id = ?", eventID).Count(&total).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to HasPaymentTransaction")
+		return false, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return total > 0, nil
+}
+
+func (r *RepoPG) CreatePaymentTransaction(ctx context.Context, transaction *model.PaymentTransaction, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreatePaymentTransaction")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) UpdateTicketPaymentStatus(ctx context.Context, ids []uuid.UUID, status string, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.Ticket{}).Where("id in ?", ids).Update("payment_status", status).Error; err != nil {
+		log.WithError(err).Error("error_500: error when UpdateTicketPaymentStatus")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+// GetUnpaidTickets locks and returns pending tickets booked more than hold ago
+func (r *RepoPG) GetUnpaidTickets(ctx context.Context, hold time.Duration, limit int, tx *gorm.DB) ([]model.Ticket, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res []model.Ticket
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Model(&model.Ticket{}).
+		Where("state = ? and created_at < ?", model.TicketStatePending, time.Now().Add(-hold)).
+		Order("created_at").Limit(limit).Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetUnpaidTickets")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+// GetRefundDuePayments returns the payments with a refund due since before the given time
+func (r *RepoPG) GetRefundDuePayments(ctx context.Context, before time.Time, limit int, tx *gorm.DB) ([]model.Payment, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res []model.Payment
+	if err := tx.Model(&model.Payment{}).Where("refund_due > 0 and updated_at < ?", before).
+		Order("updated_at").Limit(limit).Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetRefundDuePayments")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
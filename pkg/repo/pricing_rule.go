@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+// GetPricingRules returns the rules of the lot, highest priority first
+func (r *RepoPG) GetPricingRules(ctx context.Context, parkingLotID uuid.UUID, tx *gorm.DB) ([]model.PricingRule, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res []model.PricingRule
+	if err := tx.Where("parking_lot_id = ?", parkingLotID).Order("priority desc, created_at").Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetPricingRules")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+func (r *RepoPG) CreatePricingRules(ctx context.Context, rules []model.PricingRule, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	if len(rules) == 0 {
+		return nil
+	}
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(&rules).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreatePricingRules")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) DeletePricingRulesByParkingLotID(ctx context.Context, parkingLotID uuid.UUID, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Where("parking_lot_id = ?", parkingLotID).Delete(&model.PricingRule{}).Error; err != nil {
+		log.WithError(err).Error("error_500: error when DeletePricingRulesByParkingLotID")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
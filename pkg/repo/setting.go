@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+func (r *RepoPG) GetSetting(ctx context.Context, parkingLotID uuid.UUID, key string, tx *gorm.DB) (model.Setting, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res model.Setting
+	if err := tx.Where("parking_lot_id = ? and key = ?", parkingLotID, key).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, ginext.NewError(http.StatusNotFound, err.Error())
+		}
+		log.WithError(err).Error("error_500: failed to GetSetting")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+// GetCompanySetting returns a company wide setting, saved without a lot
+func (r *RepoPG) GetCompanySetting(ctx context.Context, companyID uuid.UUID, key string, tx *gorm.DB) (model.Setting, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res model.Setting
+	if err := tx.Where("company_id = ? and parking_lot_id = ? and key = ?", companyID, uuid.Nil, key).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, ginext.NewError(http.StatusNotFound, err.Error())
+		}
+		log.WithError(err).Error("error_500: failed to GetCompanySetting")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+// SaveSetting creates the setting of the lot or replaces its value
+func (r *RepoPG) SaveSetting(ctx context.Context, setting *model.Setting, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var current model.Setting
+	err := tx.Where("company_id = ? and parking_lot_id = ? and key = ?", setting.CompanyId, setting.ParkingLotId, setting.Key).
+		Take(&current).Error
+	switch {
+	case err == nil:
+		setting.ID = current.ID
+		err = tx.Model(&model.Setting{}).Where("id = ?", current.ID).Update("value", setting.Value).Error
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		err = tx.Omit("Company").Create(setting).Error
+	}
+	if err != nil {
+		log.WithError(err).Error("error_500: failed to SaveSetting")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+// settlementLines lists what a company earned on its lots in [start, end): the paid tickets and extensions that
+// ended (completed, cancelled or expired) in the period, the refunds of those cancelled, and the charges
+// recorded at check-out
+const settlementLines = `
+select case when e.id is null then 'booking' else 'extension' end as type, t.id as ticket_id, t.parking_lot_id,
+	h.created_at as occurred_at, t.total as amount, 'Ticket ' || h.to_state as description
+from ticket t
+join ticket_state_history h on h.ticket_id = t.id and h.to_state in ('completed', 'cancel', 'expired')
+left join ticket_extend e on e.ticket_extend_id = t.id and e.deleted_at is null
+where t.parking_lot_id in (select id from parking_lot where company_id = @company)
+	and h.created_at >= @start and h.created_at < @end
+	and t.payment_status in ('paid', 'refunded') and t.total > 0 and t.deleted_at is null
+union all
+select 'refund', t.id, t.parking_lot_id, h.created_at, -t.refund, 'Refund of cancelled ticket'
+from ticket t
+join ticket_state_history h on h.ticket_id = t.id and h.to_state = 'cancel'
+where t.parking_lot_id in (select id from parking_lot where company_id = @company)
+	and h.created_at >= @start and h.created_at < @end
+	and t.payment_status in ('paid', 'refunded') and t.refund > 0 and t.deleted_at is null
+union all
+select 'charge', t.id, t.parking_lot_id, c.created_at, c.amount, c.description
+from ticket_charge c
+join ticket t on t.id = c.ticket_id
+where t.parking_lot_id in (select id from parking_lot where company_id = @company)
+	and c.created_at >= @start and c.created_at < @end
+	and c.amount > 0 and c.deleted_at is null
+order by occurred_at`
+
+func (r *RepoPG) GetSettlementLines(ctx context.Context, companyID uuid.UUID, start, end time.Time, tx *gorm.DB) ([]model.SettlementLine, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res []model.SettlementLine
+	if err := tx.Raw(settlementLines, map[string]interface{}{
+		"company": companyID,
+		"start":   start,
+		"end":     end,
+	}).Scan(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetSettlementLines")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+// CreateSettlement saves the statement along with its lines, inserted by batches
+func (r *RepoPG) CreateSettlement(ctx context.Context, settlement *model.Settlement, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Session(&gorm.Session{CreateBatchSize: 500}).Create(settlement).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreateSettlement")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) GetOneSettlement(ctx context.Context, id uuid.UUID, tx *gorm.DB) (res model.Settlement, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.Settlement{}).Where("id = ?", id).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("occurred_at, type")
+		}).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, ginext.NewError(http.StatusNotFound, "Settlement not found")
+		}
+		log.WithError(err).Error("error_500: failed to GetOneSettlement")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+// GetListSettlement lists the statements without their lines, latest period first
+func (r *RepoPG) GetListSettlement(ctx context.Context, req model.ListSettlementReq, tx *gorm.DB) (res model.ListSettlementRes, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	tx = tx.Model(&model.Settlement{})
+	if req.CompanyID != nil {
+		tx = tx.Where("company_id = ?", valid.String(req.CompanyID))
+	}
+
+	var total int64 = 0
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
+
+	if err := tx.Count(&total).Order("period_start desc").
+		Limit(pageSize).Offset(r.GetOffset(page, pageSize)).Find(&res.Data).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetListSettlement")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	if res.Meta, err = r.GetPaginationInfo("", nil, int(total), page, pageSize); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// GetSettlementsDue returns, for each active company, the end of its last statement, or when it was created
+// if it has none yet
+func (r *RepoPG) GetSettlementsDue(ctx context.Context, tx *gorm.DB) ([]model.SettlementDue, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res []model.SettlementDue
+	if err := tx.Table("company c").
+		Select("c.id as company_id, coalesce(max(s.period_end), c.created_at) as since").
+		Joins("left join settlement s on s.company_id = c.id and s.deleted_at is null").
+		Where("c.status = ? and c.deleted_at is null", "active").
+		Group("c.id, c.created_at").
+		Scan(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetSettlementsDue")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
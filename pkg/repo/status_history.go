@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+func (r *RepoPG) CreateStatusHistory(ctx context.Context, history *model.StatusHistory, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(history).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreateStatusHistory")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) GetListStatusHistory(ctx context.Context, req model.ListStatusHistoryReq) (res model.ListStatusHistoryRes, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+
+	tx, cancel := r.DBWithTimeout(ctx)
+	defer cancel()
+
+	tx = tx.Model(&model.StatusHistory{})
+
+	if req.EntityType != nil {
+		tx = tx.Where("entity_type = ?", valid.String(req.EntityType))
+	}
+
+	if req.EntityID != nil {
+		tx = tx.Where("entity_id = ?", valid.String(req.EntityID))
+	}
+
+	var total int64 = 0
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
+
+	if err := tx.Count(&total).Order("created_at desc").Limit(pageSize).Offset(r.GetOffset(page, pageSize)).Find(&res.Data).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetListStatusHistory")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	if res.Meta, err = r.GetPaginationInfo("", nil, int(total), page, pageSize); err != nil {
+		log.WithError(err).Error("error_500: failed to get pagination")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	return res, nil
+}
@@ -3,12 +3,19 @@ package repo
 import (
 	"context"
 	"errors"
-	"gitlab.com/goxp/cloud0/ginext"
-	"gitlab.com/goxp/cloud0/logger"
-	"gorm.io/gorm"
 	"net/http"
 	"parking-server/pkg/model"
 	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func (r *RepoPG) CreateTicket(ctx context.Context, ticket *model.Ticket, tx *gorm.DB) error {
@@ -19,6 +26,10 @@ func (r *RepoPG) CreateTicket(ctx context.Context, ticket *model.Ticket, tx *gor
 		defer cancel()
 	}
 	if err := tx.Model(&model.Ticket{}).Create(&ticket).Error; err != nil {
+		if isExclusionViolation(err) {
+			log.WithError(err).Error("error_409: parking slot is already booked - CreateTicket - RepoPG")
+			return ginext.NewError(http.StatusConflict, utils.MessageError()[http.StatusConflict])
+		}
 		log.WithError(err).Error("Error when create ticket - CreateTicket - RepoPG")
 		return ginext.NewError(http.StatusInternalServerError, "Error when create ticket: "+err.Error())
 	}
@@ -35,29 +46,95 @@ func (r *RepoPG) UpdateTicket(ctx context.Context, ticket *model.Ticket, tx *gor
 	}
 	return nil
 }
-func (r *RepoPG) GetAllTicket(ctx context.Context, req model.GetListTicketParam, tx *gorm.DB) (res []model.Ticket, err error) {
+func (r *RepoPG) GetAllTicket(ctx context.Context, req model.GetListTicketParam, tx *gorm.DB) (res model.ListTicketRes, err error) {
 	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
 	var cancel context.CancelFunc
 	if tx == nil {
 		tx, cancel = r.DBWithTimeout(ctx)
 		defer cancel()
 	}
-	tx = tx.Model(&model.Ticket{})
-	if req.State != nil {
-		tx = tx.Where("state = ?", req.State)
+	order, err := ticketOrder(req.Sort)
+	if err != nil {
+		return res, err
 	}
-	if err := tx.Where("user_id = ?", req.UserId).Preload("Vehicle").Preload("ParkingLot").
+	tx = filterTicket(tx.Model(&model.Ticket{}).Where("user_id = ?", req.UserId), req.TicketFilter)
+
+	var total int64 = 0
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
+
+	if err := tx.Count(&total).Order(order).Preload("Vehicle").Preload("ParkingLot").
 		Preload("ParkingSlot", func(db *gorm.DB) *gorm.DB {
 			return db.Unscoped()
 		}).Preload("ParkingSlot.Block", func(db *gorm.DB) *gorm.DB {
 		return db.Unscoped()
 	}).Preload("TimeFrame").
-		Find(&res).Error; err != nil {
+		Limit(pageSize).Offset(r.GetOffset(page, pageSize)).Find(&res.Data).Error; err != nil {
 		log.WithError(err).Error("Error when get all ticket - GetAllTicket - RepoPG")
-		return nil, ginext.NewError(http.StatusInternalServerError, "Error when get all ticket: "+err.Error())
+		return res, ginext.NewError(http.StatusInternalServerError, "Error when get all ticket: "+err.Error())
+	}
+
+	if res.Meta, err = r.GetPaginationInfo("", nil, int(total), page, pageSize); err != nil {
+		return res, err
 	}
 	return res, nil
 }
+
+// ticketSortFields whitelists the columns a ticket listing may be sorted by
+var ticketSortFields = map[string]string{
+	"start_time": "start_time",
+	"end_time":   "end_time",
+	"created_at": "created_at",
+	"total":      "total",
+	"state":      "state",
+}
+
+// ticketOrder turns "field" or "field asc|desc" into an order clause, rejecting anything off the whitelist
+func ticketOrder(sort string) (string, error) {
+	if sort == "" {
+		return "start_time desc", nil
+	}
+	parts := strings.Fields(strings.ToLower(sort))
+	column, ok := ticketSortFields[parts[0]]
+	if !ok || len(parts) > 2 {
+		return "", ginext.NewError(http.StatusBadRequest, "Invalid sort: "+sort)
+	}
+	direction := "asc"
+	if len(parts) == 2 {
+		if parts[1] != "asc" && parts[1] != "desc" {
+			return "", ginext.NewError(http.StatusBadRequest, "Invalid sort: "+sort)
+		}
+		direction = parts[1]
+	}
+	return column + " " + direction + ", id", nil
+}
+
+func filterTicket(tx *gorm.DB, f model.TicketFilter) *gorm.DB {
+	if f.State != nil {
+		tx = tx.Where("state = ?", valid.String(f.State))
+	}
+	if f.From != nil {
+		tx = tx.Where("coalesce(end_time, 'infinity') > ?", f.From)
+	}
+	if f.To != nil {
+		tx = tx.Where("start_time < ?", f.To)
+	}
+	if f.PlateNumber != nil {
+		tx = tx.Where("vehicle_id in (select id from vehicle where "+utils.PlateNumberSQL+" like ?)",
+			"%"+utils.NormalizePlate(valid.String(f.PlateNumber))+"%")
+	}
+	if f.VehicleType != nil {
+		tx = tx.Where("vehicle_id in (select id from vehicle where type = ?)", valid.String(f.VehicleType))
+	}
+	if f.ParkingSlotID != nil {
+		tx = tx.Where("parking_slot_id = ?", valid.String(f.ParkingSlotID))
+	}
+	if f.BlockID != nil {
+		tx = tx.Where("parking_slot_id in (select id from parking_slot where block_id = ?)", valid.String(f.BlockID))
+	}
+	return tx
+}
+
 func (r *RepoPG) GetOneTicket(ctx context.Context, id string, tx *gorm.DB) (model.Ticket, error) {
 	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
 	var cancel context.CancelFunc
@@ -76,6 +153,42 @@ func (r *RepoPG) GetOneTicket(ctx context.Context, id string, tx *gorm.DB) (mode
 	}
 	return res, nil
 }
+
+// GetOneTicketForUpdate locks the ticket until the end of the transaction
+func (r *RepoPG) GetOneTicketForUpdate(ctx context.Context, id uuid.UUID, tx *gorm.DB) (model.Ticket, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res model.Ticket
+	if err := tx.Model(&model.Ticket{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.WithError(err).Error("error_404: not found")
+			return res, ginext.NewError(http.StatusNotFound, err.Error())
+		}
+		log.WithError(err).Error("error_500: failed to GetOneTicketForUpdate")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+// MarkTicketExtended flags the ticket as having extensions, leaving its other fields as they are
+func (r *RepoPG) MarkTicketExtended(ctx context.Context, id uuid.UUID, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.Ticket{}).Where("id = ?", id).Update("is_extend", true).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to MarkTicketExtended")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
 func (r *RepoPG) GetOneTicketWithExtend(ctx context.Context, id string, tx *gorm.DB) (model.Ticket, error) {
 	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
 	var cancel context.CancelFunc
@@ -89,7 +202,7 @@ func (r *RepoPG) GetOneTicketWithExtend(ctx context.Context, id string, tx *gorm
 			return db.Unscoped()
 		}).Preload("ParkingSlot.Block", func(db *gorm.DB) *gorm.DB {
 		return db.Unscoped()
-	}).Preload("TimeFrame").
+	}).Preload("TimeFrame").Preload("Charges").
 		Take(&res).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.WithError(err).Error("error_404: not found")
@@ -135,25 +248,130 @@ func (r *RepoPG) CreateLongTermTicket(ctx context.Context, ltTicket *model.LongT
 	return nil
 }
 
-func (r *RepoPG) GetAllTicketCompany(ctx context.Context, req model.GetListTicketReq) (res []model.GetListTicketRes, err error) {
+func (r *RepoPG) GetAllTicketCompany(ctx context.Context, req model.GetListTicketReq) (res model.ListTicketCompanyRes, err error) {
 	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
 
 	tx, cancel := r.DBWithTimeout(ctx)
 	defer cancel()
 
-	tx = tx.Model(&model.Ticket{})
-	if req.State != nil {
-		tx = tx.Where("state = ?", req.State)
+	order, err := ticketOrder(req.Sort)
+	if err != nil {
+		return res, err
+	}
+	tx = filterTicket(tx.Model(&model.Ticket{}), req.TicketFilter)
+	if len(req.ParkingLotIDs) > 0 {
+		tx = tx.Where("parking_lot_id in ?", req.ParkingLotIDs)
 	}
+	if req.CompanyID != nil {
+		tx = tx.Where("parking_lot_id in (select id from parking_lot where company_id = ?)", valid.String(req.CompanyID))
+	}
+
+	var total int64 = 0
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
 
-	if err := tx.Where("parking_lot_id = ?", req.ParkingLotID).Preload("Vehicle").Preload("ParkingLot").
+	if err := tx.Count(&total).Order(order).Preload("Vehicle").Preload("ParkingLot").
 		Preload("ParkingSlot", func(db *gorm.DB) *gorm.DB {
 			return db.Unscoped()
 		}).Preload("ParkingSlot.Block", func(db *gorm.DB) *gorm.DB {
 		return db.Unscoped()
-	}).Find(&res).Error; err != nil {
+	}).Limit(pageSize).Offset(r.GetOffset(page, pageSize)).Find(&res.Data).Error; err != nil {
 		log.WithError(err).Error("Error when get all ticket - GetAllTicket - RepoPG")
-		return nil, ginext.NewError(http.StatusInternalServerError, "Error when get all ticket: "+err.Error())
+		return res, ginext.NewError(http.StatusInternalServerError, "Error when get all ticket: "+err.Error())
+	}
+
+	if res.Meta, err = r.GetPaginationInfo("", nil, int(total), page, pageSize); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// HasOngoingTicket reports whether the vehicle is parked on a ticket that has not checked out yet
+func (r *RepoPG) HasOngoingTicket(ctx context.Context, vehicleID uuid.UUID, tx *gorm.DB) (bool, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var total int64
+	if err := tx.Model(&model.Ticket{}).Where("vehicle_id = ? and state = ?", vehicleID, model.TicketStateOngoing).
+		Count(&total).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to HasOngoingTicket")
+		return false, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return total > 0, nil
+}
+
+// GetTicketsByPlate returns the recent tickets of the vehicles with the plate number normalized by
+// utils.NormalizePlate. Extensions are left out, their origin ticket (flagged is_extend) goes through the gate.
+// Tickets that ended more than a day ago are left out too, unless still parked.
+func (r *RepoPG) GetTicketsByPlate(ctx context.Context, number string, tx *gorm.DB) ([]model.Ticket, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res []model.Ticket
+	if err := tx.Model(&model.Ticket{}).
+		Where("vehicle_id in (select id from vehicle where "+utils.PlateNumberSQL+" = ? and deleted_at is null)", number).
+		Where("id not in (select ticket_extend_id from ticket_extend where deleted_at is null)").
+		Where("state = ? or coalesce(end_time, 'infinity') > now() - interval '1 day'", model.TicketStateOngoing).
+		Order("start_time").Preload("Vehicle").Preload("ParkingLot").
+		Preload("ParkingSlot", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).Preload("ParkingSlot.Block", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetTicketsByPlate")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
 	}
 	return res, nil
 }
+
+// LockParkingSlot locks the slot row until the end of the transaction, so bookings of one slot run one after another
+func (r *RepoPG) LockParkingSlot(ctx context.Context, id uuid.UUID, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", id).Take(&model.ParkingSlot{}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.WithError(err).Error("error_404: parking slot not found")
+			return ginext.NewError(http.StatusNotFound, err.Error())
+		}
+		log.WithError(err).Error("error_500: failed to LockParkingSlot")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+// HasOverlapTicket reports whether an active ticket of the slot overlaps [start, end)
+func (r *RepoPG) HasOverlapTicket(ctx context.Context, parkingSlotID uuid.UUID, start, end time.Time, tx *gorm.DB) (bool, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var total int64
+	if err := tx.Model(&model.Ticket{}).
+		Where("parking_slot_id = ? and state in ?", parkingSlotID, model.TicketActiveStates).
+		Where("start_time < ? and coalesce(end_time, 'infinity') > ?", end, start).
+		Count(&total).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to HasOverlapTicket")
+		return false, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return total > 0, nil
+}
+
+// pgExclusionViolation is raised by the ticket_no_overlap constraint
+const pgExclusionViolation = "23P01"
+
+func isExclusionViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgExclusionViolation
+}
@@ -0,0 +1,26 @@
+package repo
+
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+func (r *RepoPG) CreateTicketCharge(ctx context.Context, charge *model.TicketCharge, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(charge).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreateTicketCharge")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"github.com/google/uuid"
 	"gitlab.com/goxp/cloud0/ginext"
 	"gitlab.com/goxp/cloud0/logger"
 	"gorm.io/gorm"
@@ -23,3 +24,19 @@ func (r *RepoPG) CreateTicketExtend(ctx context.Context, req *model.TicketExtend
 	}
 	return nil
 }
+
+// IsTicketExtension reports whether the ticket extends another one
+func (r *RepoPG) IsTicketExtension(ctx context.Context, id uuid.UUID, tx *gorm.DB) (bool, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var total int64
+	if err := tx.Model(&model.TicketExtend{}).Where("ticket_extend_id = ?", id).Count(&total).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to IsTicketExtension")
+		return false, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return total > 0, nil
+}
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+// UpdateTicketState saves the ticket only if it is still in state from, so two concurrent transitions
+// of the same ticket cannot both succeed
+func (r *RepoPG) UpdateTicketState(ctx context.Context, ticket *model.Ticket, from model.TicketState, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	result := tx.Model(&model.Ticket{}).Where("id = ? and state = ?", ticket.ID, from).Updates(ticket)
+	if result.Error != nil {
+		log.WithError(result.Error).Error("error_500: error when UpdateTicketState")
+		return ginext.NewError(http.StatusInternalServerError, result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		log.Error("error_409: ticket state was changed by another request")
+		return ginext.NewError(http.StatusConflict, utils.MessageError()[http.StatusConflict])
+	}
+	return nil
+}
+
+func (r *RepoPG) CreateTicketStateHistory(ctx context.Context, history *model.TicketStateHistory, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(history).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreateTicketStateHistory")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) GetListTicketStateHistory(ctx context.Context, ticketID uuid.UUID, tx *gorm.DB) ([]model.TicketStateHistory, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res []model.TicketStateHistory
+	if err := tx.Where("ticket_id = ?", ticketID).Order("created_at").Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: error when GetListTicketStateHistory")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
@@ -7,6 +7,7 @@ import (
 	"gitlab.com/goxp/cloud0/ginext"
 	"gitlab.com/goxp/cloud0/logger"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"net/http"
 	"parking-server/pkg/model"
 	"parking-server/pkg/utils"
@@ -107,3 +108,44 @@ func (r *RepoPG) DeleteVehicle(ctx context.Context, id uuid.UUID) error {
 	}
 	return nil
 }
+
+// CreateGuestVehicle creates the guest vehicle of the plate number, unless the plate already has one
+func (r *RepoPG) CreateGuestVehicle(ctx context.Context, vehicle *model.Vehicle, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: utils.PlateNumberSQL, Raw: true}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "user_id is null and deleted_at is null"}}},
+		DoNothing:   true,
+	}).Create(vehicle).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreateGuestVehicle")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+// GetGuestVehicle returns the guest vehicle with the plate number, nil if none was registered yet. Inside a
+// transaction the vehicle stays locked until commit, so walk-ins of one plate are issued one after another.
+func (r *RepoPG) GetGuestVehicle(ctx context.Context, number string, tx *gorm.DB) (*model.Vehicle, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res []model.Vehicle
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&model.Vehicle{}).
+		Where("user_id is null and "+utils.PlateNumberSQL+" = ?", utils.NormalizePlate(number)).
+		Order("created_at").Limit(1).Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetGuestVehicle")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return &res[0], nil
+}
@@ -0,0 +1,307 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pgUniqueViolation is raised by the unique index on the code of the vouchers of a company
+const pgUniqueViolation = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation
+}
+
+func (r *RepoPG) CreateVoucher(ctx context.Context, voucher *model.Voucher, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(voucher).Error; err != nil {
+		if isUniqueViolation(err) {
+			return ginext.NewError(http.StatusConflict, "Voucher code already exists")
+		}
+		log.WithError(err).Error("error_500: error when CreateVoucher")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+// UpdateVoucher saves the terms of the voucher, its use count is left to AddVoucherUse
+func (r *RepoPG) UpdateVoucher(ctx context.Context, voucher *model.Voucher, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(voucher).Select("code", "name", "type", "value", "max_discount", "min_total", "start_at",
+		"end_at", "usage_limit", "per_user_limit", "parking_lot_ids", "time_frame_ids", "status", "updater_id", "updated_at").
+		Updates(voucher).Error; err != nil {
+		if isUniqueViolation(err) {
+			return ginext.NewError(http.StatusConflict, "Voucher code already exists")
+		}
+		log.WithError(err).Error("error_500: error when UpdateVoucher")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+func (r *RepoPG) GetOneVoucher(ctx context.Context, id uuid.UUID, tx *gorm.DB) (res model.Voucher, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.Voucher{}).Where("id = ?", id).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, ginext.NewError(http.StatusNotFound, "Voucher not found")
+		}
+		log.WithError(err).Error("error_500: failed to GetOneVoucher")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+// GetVoucherByCode locks the voucher until the end of the transaction, so its limits are checked one
+// redemption at a time
+func (r *RepoPG) GetVoucherByCode(ctx context.Context, companyID uuid.UUID, code string, tx *gorm.DB) (res model.Voucher, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.Voucher{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("company_id = ? and code = ?", companyID, code).Take(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, ginext.NewError(http.StatusNotFound, "Voucher not found")
+		}
+		log.WithError(err).Error("error_500: failed to GetVoucherByCode")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+func (r *RepoPG) GetListVoucher(ctx context.Context, req model.ListVoucherReq, tx *gorm.DB) (res model.ListVoucherRes, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	tx = tx.Model(&model.Voucher{})
+	if req.CompanyID != nil {
+		tx = tx.Where("company_id = ?", valid.String(req.CompanyID))
+	}
+	if req.Code != nil {
+		tx = tx.Where("code ilike ?", "%"+valid.String(req.Code)+"%")
+	}
+	if req.Status != nil {
+		tx = tx.Where("status = ?", valid.String(req.Status))
+	}
+
+	var total int64 = 0
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
+
+	if err := tx.Count(&total).Order("created_at desc").
+		Limit(pageSize).Offset(r.GetOffset(page, pageSize)).Find(&res.Data).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetListVoucher")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	if res.Meta, err = r.GetPaginationInfo("", nil, int(total), page, pageSize); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (r *RepoPG) DeleteVoucher(ctx context.Context, id uuid.UUID, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Where("id = ?", id).Delete(&model.Voucher{}).Error; err != nil {
+		log.WithError(err).Error("error_500: error when DeleteVoucher")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+// AddVoucherUse counts delta more uses of the voucher
+func (r *RepoPG) AddVoucherUse(ctx context.Context, id uuid.UUID, delta int, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Model(&model.Voucher{}).Where("id = ?", id).
+		Update("used", gorm.Expr("greatest(used + ?, 0)", delta)).Error; err != nil {
+		log.WithError(err).Error("error_500: error when AddVoucherUse")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+// CountUserRedemptions counts the redemptions of the voucher by the user that were not released
+func (r *RepoPG) CountUserRedemptions(ctx context.Context, voucherID uuid.UUID, userID uuid.UUID, tx *gorm.DB) (int64, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var count int64
+	if err := tx.Model(&model.VoucherRedemption{}).
+		Where("voucher_id = ? and user_id = ? and status = ?", voucherID, userID, model.REDEMPTION_REDEEMED).
+		Count(&count).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to CountUserRedemptions")
+		return 0, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return count, nil
+}
+
+// CountUserBookings counts the tickets the user booked on the lots of the company, cancelled and expired ones
+// left out
+func (r *RepoPG) CountUserBookings(ctx context.Context, userID uuid.UUID, companyID uuid.UUID, tx *gorm.DB) (int64, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var count int64
+	if err := tx.Model(&model.Ticket{}).
+		Where("user_id = ? and state not in ?", userID, []model.TicketState{model.TicketStateCancel, model.TicketStateExpired}).
+		Where("parking_lot_id in (select id from parking_lot where company_id = ?)", companyID).
+		Count(&count).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to CountUserBookings")
+		return 0, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return count, nil
+}
+
+func (r *RepoPG) CreateVoucherRedemption(ctx context.Context, redemption *model.VoucherRedemption, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(redemption).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreateVoucherRedemption")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+// ReleaseVoucherRedemption gives back the redemption of the ticket, it reports false when there was none
+func (r *RepoPG) ReleaseVoucherRedemption(ctx context.Context, ticketID uuid.UUID, tx *gorm.DB) (bool, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	result := tx.Model(&model.VoucherRedemption{}).
+		Where("ticket_id = ? and status = ?", ticketID, model.REDEMPTION_REDEEMED).
+		Update("status", model.REDEMPTION_RELEASED)
+	if result.Error != nil {
+		log.WithError(result.Error).Error("error_500: error when ReleaseVoucherRedemption")
+		return false, ginext.NewError(http.StatusInternalServerError, result.Error.Error())
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *RepoPG) GetListRedemption(ctx context.Context, req model.ListRedemptionReq, tx *gorm.DB) (res model.ListRedemptionRes, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	tx = tx.Model(&model.VoucherRedemption{})
+	if req.CompanyID != nil {
+		tx = tx.Where("voucher_id in (select id from voucher where company_id = ?)", valid.String(req.CompanyID))
+	}
+	if req.VoucherID != nil {
+		tx = tx.Where("voucher_id = ?", valid.String(req.VoucherID))
+	}
+	if req.Status != nil {
+		tx = tx.Where("status = ?", valid.String(req.Status))
+	}
+	if req.From != nil {
+		tx = tx.Where("created_at >= ?", req.From)
+	}
+	if req.To != nil {
+		tx = tx.Where("created_at < ?", req.To)
+	}
+
+	var total int64 = 0
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
+
+	if err := tx.Count(&total).Order("created_at desc").
+		Limit(pageSize).Offset(r.GetOffset(page, pageSize)).Find(&res.Data).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetListRedemption")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	if res.Meta, err = r.GetPaginationInfo("", nil, int(total), page, pageSize); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// GetVoucherReport sums the redemptions of each voucher of the company made in [From, To)
+func (r *RepoPG) GetVoucherReport(ctx context.Context, req model.VoucherReportReq, tx *gorm.DB) ([]model.VoucherReport, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	on := "r.voucher_id = v.id and r.status = ? and r.deleted_at is null"
+	args := []interface{}{model.REDEMPTION_REDEEMED}
+	if req.From != nil {
+		on += " and r.created_at >= ?"
+		args = append(args, req.From)
+	}
+	if req.To != nil {
+		on += " and r.created_at < ?"
+		args = append(args, req.To)
+	}
+	var res []model.VoucherReport
+	if err := tx.Table("voucher v").
+		Select("v.id as voucher_id, v.code, v.name, count(r.id) as redemptions, count(distinct r.user_id) as users, "+
+			"coalesce(sum(r.discount), 0) as discount, coalesce(sum(t.total), 0) as revenue").
+		Joins("left join voucher_redemption r on "+on, args...).
+		Joins("left join ticket t on t.id = r.ticket_id").
+		Where("v.company_id = ? and v.deleted_at is null", valid.String(req.CompanyID)).
+		Group("v.id, v.code, v.name").
+		Order("discount desc, v.code").
+		Scan(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetVoucherReport")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const pgCheckViolation = "23514"
+
+// ErrInsufficientBalance is returned when a debit would take the wallet below zero
+var ErrInsufficientBalance = ginext.NewError(http.StatusPaymentRequired, "Insufficient wallet balance")
+
+// GetWallet returns the wallet of the user, nil if the user has none yet
+func (r *RepoPG) GetWallet(ctx context.Context, userID uuid.UUID, tx *gorm.DB) (*model.Wallet, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	var res []model.Wallet
+	if err := tx.Model(&model.Wallet{}).Where("user_id = ?", userID).Limit(1).Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetWallet")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return &res[0], nil
+}
+
+// GetOrCreateWallet returns the wallet of the user, opening an empty one on first use
+func (r *RepoPG) GetOrCreateWallet(ctx context.Context, userID uuid.UUID, currency string, tx *gorm.DB) (model.Wallet, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	wallet := model.Wallet{UserID: userID, Currency: currency}
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).
+		Create(&wallet).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to create wallet")
+		return wallet, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	if err := tx.Model(&model.Wallet{}).Where("user_id = ?", userID).Take(&wallet).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetOrCreateWallet")
+		return wallet, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return wallet, nil
+}
+
+// AddWalletBalance moves the balance by delta and returns the new balance. A spend larger than the balance
+// is a 402: the update is conditional and the check of the table backs it up.
+func (r *RepoPG) AddWalletBalance(ctx context.Context, walletID uuid.UUID, delta float64, tx *gorm.DB) (float64, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	result := tx.Model(&model.Wallet{}).Where("id = ? and balance + ? >= 0", walletID, delta).
+		Update("balance", gorm.Expr("balance + ?", delta))
+	if result.Error != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(result.Error, &pgErr) && pgErr.Code == pgCheckViolation {
+			return 0, ErrInsufficientBalance
+		}
+		log.WithError(result.Error).Error("error_500: error when AddWalletBalance")
+		return 0, ginext.NewError(http.StatusInternalServerError, result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return 0, ErrInsufficientBalance
+	}
+	var balance float64
+	if err := tx.Model(&model.Wallet{}).Select("balance").Where("id = ?", walletID).Scan(&balance).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to read wallet balance")
+		return 0, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return balance, nil
+}
+
+// CreateWalletTransaction saves the transaction along with its ledger entries
+func (r *RepoPG) CreateWalletTransaction(ctx context.Context, transaction *model.WalletTransaction, tx *gorm.DB) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		log.WithError(err).Error("error_500: error when CreateWalletTransaction")
+		return ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+// GetWalletSpend returns the spend that paid for the ticket, on its own or with its series, nil if none
+func (r *RepoPG) GetWalletSpend(ctx context.Context, ticketID uuid.UUID, longTermTicketID *uuid.UUID, tx *gorm.DB) (*model.WalletTransaction, error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	tx = tx.Model(&model.WalletTransaction{}).Where("type = ?", model.WALLET_TX_SPEND)
+	if longTermTicketID != nil {
+		tx = tx.Where("ticket_id = ? or long_term_ticket_id = ?", ticketID, longTermTicketID)
+	} else {
+		tx = tx.Where("ticket_id = ?", ticketID)
+	}
+	var res []model.WalletTransaction
+	if err := tx.Order("created_at").Limit(1).Find(&res).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetWalletSpend")
+		return nil, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return &res[0], nil
+}
+
+func (r *RepoPG) GetListWalletTransaction(ctx context.Context, req model.ListWalletTransactionReq, tx *gorm.DB) (res model.ListWalletTransactionRes, err error) {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(r, 0))
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+	tx = tx.Model(&model.WalletTransaction{}).Where("wallet_id = ?", valid.String(req.WalletID))
+	if req.Type != nil {
+		tx = tx.Where("type = ?", valid.String(req.Type))
+	}
+	if req.From != nil {
+		tx = tx.Where("created_at >= ?", req.From)
+	}
+	if req.To != nil {
+		tx = tx.Where("created_at < ?", req.To)
+	}
+
+	var total int64 = 0
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
+
+	if err := tx.Count(&total).Order("created_at desc").Preload("Entries").
+		Limit(pageSize).Offset(r.GetOffset(page, pageSize)).Find(&res.Data).Error; err != nil {
+		log.WithError(err).Error("error_500: failed to GetListWalletTransaction")
+		return res, ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	if res.Meta, err = r.GetPaginationInfo("", nil, int(total), page, pageSize); err != nil {
+		return res, err
+	}
+	return res, nil
+}
@@ -1,15 +1,22 @@
 package route
 
 import (
+	"context"
 	"fmt"
 	"parking-server/conf"
+	"parking-server/pkg/client"
 	"parking-server/pkg/handlers"
+	"parking-server/pkg/limiter"
+	"parking-server/pkg/midleware"
 	"parking-server/pkg/repo"
 	service2 "parking-server/pkg/service"
+	"parking-server/pkg/utils"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	swaggerFiles "github.com/swaggo/files"
 	swagger "github.com/swaggo/gin-swagger"
 	"gitlab.com/goxp/cloud0/ginext"
@@ -44,10 +51,30 @@ func NewService() *Service {
 	if s.setting.DbDebugEnable {
 		db = db.Debug()
 	}
+	if err := repo.RegisterAudit(db); err != nil {
+		logrus.Fatal(err)
+	}
 	repoPG := repo.NewPGRepo(db)
 
+	otpProvider, err := client.NewOtpProvider(conf.GetConfig(), repoPG)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	attemptStore, err := limiter.NewStore(conf.GetConfig().AttemptStore, repoPG)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	guard := limiter.NewGuard(attemptStore)
+
+	paymentProvider, err := client.NewPaymentProvider(conf.GetConfig())
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	paymentHold := time.Duration(conf.GetConfig().PaymentHold) * time.Minute
+
 	// service
-	authService := service2.NewAuthService(repoPG)
+	authService := service2.NewAuthService(repoPG, otpProvider, guard)
 	favoriteService := service2.NewFavoriteService(repoPG)
 	lotService := service2.NewParkingLotService(repoPG)
 	blockService := service2.NewBlockService(repoPG)
@@ -55,9 +82,37 @@ func NewService() *Service {
 	vehicleService := service2.NewVehicleService(repoPG)
 	userService := service2.NewUserService(repoPG)
 	timeFrameService := service2.NewTimeFrameService(repoPG)
-	ticketService := service2.NewTicketService(repoPG)
-	companyService := service2.NewCompanyService(repoPG)
-	employeeService := service2.NewEmployeeService(repoPG)
+	paymentService := service2.NewPaymentService(repoPG, paymentProvider, conf.GetConfig().PaymentCurrency, paymentHold)
+	ticketService := service2.NewTicketService(repoPG, paymentService)
+	walletService := service2.NewWalletService(repoPG, paymentService)
+	voucherService := service2.NewVoucherService(repoPG)
+	companyService := service2.NewCompanyService(repoPG, guard)
+	employeeService := service2.NewEmployeeService(repoPG, guard)
+	adminService := service2.NewAdminService(repoPG, guard)
+	notificationService := service2.NewNotificationService(repoPG)
+	auditService := service2.NewAuditService(repoPG)
+	settingService := service2.NewSettingService(repoPG)
+	settlementService := service2.NewSettlementService(repoPG, conf.GetConfig().PaymentCurrency)
+
+	migrateHandler := handlers.NewMigrationHandler(db, func(ctx context.Context) error {
+		return adminService.SeedAdmin(ctx, conf.GetConfig().AdminEmail, conf.GetConfig().AdminPassword)
+	})
+	if conf.GetConfig().MigrateOnStart {
+		if err := migrateHandler.Run(context.Background()); err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
+	// background jobs
+	noShowWorker := service2.NewNoShowWorker(repoPG,
+		time.Duration(conf.GetConfig().NoShowInterval)*time.Second,
+		time.Duration(conf.GetConfig().NoShowGrace)*time.Minute)
+	go noShowWorker.Run(context.Background())
+	paymentHoldWorker := service2.NewPaymentHoldWorker(repoPG, paymentService,
+		time.Duration(conf.GetConfig().NoShowInterval)*time.Second, paymentHold)
+	go paymentHoldWorker.Run(context.Background())
+	settlementWorker := service2.NewSettlementWorker(repoPG, conf.GetConfig().PaymentCurrency)
+	go settlementWorker.Run(context.Background())
 
 	// handler
 	authHandler := handlers.NewAuthHandler(authService)
@@ -71,8 +126,21 @@ func NewService() *Service {
 	ticketHandler := handlers.NewTicketHandler(ticketService)
 	companyHanler := handlers.NewCompanyHandler(companyService)
 	employeeHandler := handlers.NewEmployeeHandler(employeeService)
+	adminHandler := handlers.NewAdminHandler(adminService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	settingHandler := handlers.NewSettingHandler(settingService)
+	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	walletHandler := handlers.NewWalletHandler(walletService)
+	voucherHandler := handlers.NewVoucherHandler(voucherService)
+	settlementHandler := handlers.NewSettlementHandler(settlementService)
 
 	route := s.Router
+	// the client ip keys the login lockout, so X-Forwarded-For is only read from the configured proxies, not from
+	// the private ranges the app trusts when TRUSTED_PROXY is unset
+	if err := route.SetTrustedProxies(conf.GetConfig().TrustedProxies); err != nil {
+		logrus.Fatal(err)
+	}
 	route.Use(func() gin.HandlerFunc {
 		return func(c *gin.Context) {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
@@ -88,11 +156,42 @@ func NewService() *Service {
 	}(),
 	)
 
+	// every route needs an access token, except the ones used to obtain one
+	public := map[string]bool{
+		"/swagger/*any":                     true,
+		"/api/v1/user/login":                true,
+		"/api/v1/user/create":               true,
+		"/api/v1/user/check-phone":          true,
+		"/api/v1/user/send-otp":             true,
+		"/api/v1/user/verify-otp":           true,
+		"/api/v1/user/reset-password":       true,
+		"/api/v1/employee/login":            true,
+		"/api/merchant/company/create":      true,
+		"/api/merchant/company/login":       true,
+		"/api/v1/user/token/refresh":        true,
+		"/api/admin/login":                  true,
+		"/api/v1/payment/webhook/:provider": true,
+	}
+	mockPayment := paymentProvider.Name() == client.PAYMENT_PROVIDER_MOCK
+	if mockPayment {
+		public["/api/v1/payment/mock/:ref"] = true
+	}
+	route.Use(midleware.VerifyToken(public, repoPG))
+
 	v1Api := s.Router.Group("/api/v1")
 	v2Api := s.Router.Group("/api/v2")
 	merchantApi := s.Router.Group("/api/merchant")
+	adminApi := s.Router.Group("/api/admin")
 	swaggerApi := s.Router.Group("/")
 
+	// route policies, ownership is checked in the services
+	anyone := midleware.Allow(utils.PRINCIPAL_USER, utils.PRINCIPAL_COMPANY, utils.PRINCIPAL_EMPLOYEE, utils.PRINCIPAL_ADMIN)
+	user := midleware.Allow(utils.PRINCIPAL_USER, utils.PRINCIPAL_ADMIN)
+	driver := midleware.Allow(utils.PRINCIPAL_USER)
+	owner := midleware.Allow(utils.PRINCIPAL_COMPANY, utils.PRINCIPAL_ADMIN)
+	staff := midleware.Allow(utils.PRINCIPAL_COMPANY, utils.PRINCIPAL_EMPLOYEE, utils.PRINCIPAL_ADMIN)
+	admin := midleware.Allow(utils.PRINCIPAL_ADMIN)
+
 	// swagger
 	swaggerApi.GET("/swagger/*any", swagger.WrapHandler(swaggerFiles.Handler))
 
@@ -101,106 +200,165 @@ func NewService() *Service {
 	v1Api.POST("/user/reset-password", ginext.WrapHandler(authHandler.ResetPassword))
 	v1Api.POST("/user/send-otp", ginext.WrapHandler(authHandler.SendOtp))
 	v1Api.POST("/user/verify-otp", ginext.WrapHandler(authHandler.VerifyOtp))
+	v1Api.POST("/user/token/refresh", ginext.WrapHandler(authHandler.RefreshToken))
+	v1Api.POST("/user/logout", ginext.WrapHandler(authHandler.Logout))
+	v1Api.GET("/user/sessions", ginext.WrapHandler(authHandler.GetSessions))
+	v1Api.DELETE("/user/sessions/:id", ginext.WrapHandler(authHandler.RevokeSession))
 	// v1Api.POST("/user/create", ginext.WrapHandler(userHandler.))
 
 	// user
-	v1Api.GET("/user/:id", ginext.WrapHandler(userHandler.GetOneUserById))
+	v1Api.GET("/user/:id", user, ginext.WrapHandler(userHandler.GetOneUserById))
 	v1Api.POST("/user/create", ginext.WrapHandler(userHandler.CreateUser))
 	v1Api.POST("/user/check-phone", ginext.WrapHandler(userHandler.CheckDuplicatePhone))
-	v1Api.PUT("/user/update/:id", ginext.WrapHandler(userHandler.UpdateUser))
-	v1Api.DELETE("/user/:id", ginext.WrapHandler(userHandler.DeleteUser))
+	v1Api.PUT("/user/update/:id", user, ginext.WrapHandler(userHandler.UpdateUser))
+	v1Api.DELETE("/user/:id", user, ginext.WrapHandler(userHandler.DeleteUser))
 
 	// favorite
-	v1Api.POST("/favorite/create", ginext.WrapHandler(favoriteHandler.Create))
-	v1Api.GET("/favorite/get-all", ginext.WrapHandler(favoriteHandler.GetAllFavoriteParkingByUser))
-	v1Api.GET("/favorite/get-one", ginext.WrapHandler(favoriteHandler.GetOneFavoriteParking))
-	v1Api.DELETE("/favorite/delete/:id", ginext.WrapHandler(favoriteHandler.DeleteOne))
+	v1Api.POST("/favorite/create", driver, ginext.WrapHandler(favoriteHandler.Create))
+	v1Api.GET("/favorite/get-all", driver, ginext.WrapHandler(favoriteHandler.GetAllFavoriteParkingByUser))
+	v1Api.GET("/favorite/get-one", driver, ginext.WrapHandler(favoriteHandler.GetOneFavoriteParking))
+	v1Api.DELETE("/favorite/delete/:id", driver, ginext.WrapHandler(favoriteHandler.DeleteOne))
 
 	// time frame
-	v1Api.GET("/time-frame/get-all", ginext.WrapHandler(timeFrameHandler.GetAllTimeFrame))
-	v1Api.POST("/time-frame/create-multi", ginext.WrapHandler(timeFrameHandler.Create))
-	v1Api.PUT("/time-frame/update", ginext.WrapHandler(timeFrameHandler.Update))
+	v1Api.GET("/time-frame/get-all", anyone, ginext.WrapHandler(timeFrameHandler.GetAllTimeFrame))
+	v1Api.POST("/time-frame/create-multi", owner, ginext.WrapHandler(timeFrameHandler.Create))
+	v1Api.PUT("/time-frame/update", owner, ginext.WrapHandler(timeFrameHandler.Update))
 
-	v1Api.POST("/time-frame/create", ginext.WrapHandler(timeFrameHandler.CreateTimeFrame))
-	v1Api.GET("/time-frame/get-one/:id", ginext.WrapHandler(timeFrameHandler.GetOneTimeFrame))
-	v1Api.PUT("/time-frame/update/:id", ginext.WrapHandler(timeFrameHandler.UpdateTimeFrame))
-	v1Api.DELETE("/time-frame/delete/:id", ginext.WrapHandler(timeFrameHandler.DeleteTimeFrame))
+	v1Api.POST("/time-frame/create", owner, ginext.WrapHandler(timeFrameHandler.CreateTimeFrame))
+	v1Api.GET("/time-frame/get-one/:id", anyone, ginext.WrapHandler(timeFrameHandler.GetOneTimeFrame))
+	v1Api.PUT("/time-frame/update/:id", owner, ginext.WrapHandler(timeFrameHandler.UpdateTimeFrame))
+	v1Api.DELETE("/time-frame/delete/:id", owner, ginext.WrapHandler(timeFrameHandler.DeleteTimeFrame))
 
 	// parking lot
-	v1Api.POST("/parking-lot/create", ginext.WrapHandler(lotHandler.CreateParkingLot))
-	v1Api.GET("/parking-lot/get-one/:id", ginext.WrapHandler(lotHandler.GetOneParkingLot))
-	v1Api.GET("/parking-lot/get-list", ginext.WrapHandler(lotHandler.GetListParkingLot))
-	v1Api.PUT("/parking-lot/update/:id", ginext.WrapHandler(lotHandler.UpdateParkingLot))
-	v1Api.DELETE("/parking-lot/delete/:id", ginext.WrapHandler(lotHandler.DeleteParkingLot))
-	v1Api.GET("/parking-lot/info", ginext.WrapHandler(lotHandler.GetParkingLotsInfoByIds))
+	v1Api.POST("/parking-lot/create", owner, ginext.WrapHandler(lotHandler.CreateParkingLot))
+	v1Api.GET("/parking-lot/get-one/:id", anyone, ginext.WrapHandler(lotHandler.GetOneParkingLot))
+	v1Api.GET("/parking-lot/get-list", anyone, ginext.WrapHandler(lotHandler.GetListParkingLot))
+	v1Api.PUT("/parking-lot/update/:id", owner, ginext.WrapHandler(lotHandler.UpdateParkingLot))
+	v1Api.DELETE("/parking-lot/delete/:id", owner, ginext.WrapHandler(lotHandler.DeleteParkingLot))
+	v1Api.GET("/parking-lot/info", anyone, ginext.WrapHandler(lotHandler.GetParkingLotsInfoByIds))
 
-	v1Api.PUT("/parking-lot/:id/status", ginext.WrapHandler(lotHandler.ChangeParkingLotStatus))
-	v2Api.PUT("/parking-lot/update", ginext.WrapHandler(lotHandler.UpdateParkingLotV2))
+	v1Api.PUT("/parking-lot/:id/status", admin, ginext.WrapHandler(lotHandler.ChangeParkingLotStatus))
+	v2Api.PUT("/parking-lot/update", owner, ginext.WrapHandler(lotHandler.UpdateParkingLotV2))
 
 	// block
-	v1Api.POST("/block/create", ginext.WrapHandler(blockHandler.CreateBlock))
-	v1Api.GET("/block/get-one/:id", ginext.WrapHandler(blockHandler.GetOneBlock))
-	v1Api.GET("/block/get-list", ginext.WrapHandler(blockHandler.GetListBlock))
-	v1Api.PUT("/block/update/:id", ginext.WrapHandler(blockHandler.UpdateBlock))
-	v1Api.DELETE("/block/delete/:id", ginext.WrapHandler(blockHandler.DeleteBlock))
+	v1Api.POST("/block/create", owner, ginext.WrapHandler(blockHandler.CreateBlock))
+	v1Api.GET("/block/get-one/:id", anyone, ginext.WrapHandler(blockHandler.GetOneBlock))
+	v1Api.GET("/block/get-list", anyone, ginext.WrapHandler(blockHandler.GetListBlock))
+	v1Api.PUT("/block/update/:id", owner, ginext.WrapHandler(blockHandler.UpdateBlock))
+	v1Api.DELETE("/block/delete/:id", owner, ginext.WrapHandler(blockHandler.DeleteBlock))
 
 	// parking slot
-	v1Api.POST("/parking-slot/create", ginext.WrapHandler(slotHandler.CreateParkingSlot))
-	v1Api.GET("/parking-slot/get-one/:id", ginext.WrapHandler(slotHandler.GetOneParkingSlot))
-	v1Api.GET("/parking-slot/get-list", ginext.WrapHandler(slotHandler.GetListParkingSlot))
-	v1Api.GET("/parking-slot/available", ginext.WrapHandler(slotHandler.GetAvailableParkingSlot))
-	v1Api.PUT("/parking-slot/update/:id", ginext.WrapHandler(slotHandler.UpdateParkingSlot))
-	v1Api.DELETE("/parking-slot/delete/:id", ginext.WrapHandler(slotHandler.DeleteParkingSlot))
+	v1Api.POST("/parking-slot/create", owner, ginext.WrapHandler(slotHandler.CreateParkingSlot))
+	v1Api.GET("/parking-slot/get-one/:id", anyone, ginext.WrapHandler(slotHandler.GetOneParkingSlot))
+	v1Api.GET("/parking-slot/get-list", anyone, ginext.WrapHandler(slotHandler.GetListParkingSlot))
+	v1Api.GET("/parking-slot/available", anyone, ginext.WrapHandler(slotHandler.GetAvailableParkingSlot))
+	v1Api.PUT("/parking-slot/update/:id", owner, ginext.WrapHandler(slotHandler.UpdateParkingSlot))
+	v1Api.DELETE("/parking-slot/delete/:id", owner, ginext.WrapHandler(slotHandler.DeleteParkingSlot))
 	// v1Api.GET("/parking-slot/availability", ginext.WrapHandler(slotHandler.DeleteParkingSlot))
 
 	// vehicle
-	v1Api.POST("/vehicle/create", ginext.WrapHandler(vehicleHandler.CreateVehicle))
-	v1Api.GET("/vehicle/get-one/:id", ginext.WrapHandler(vehicleHandler.GetOneVehicle))
-	v1Api.GET("/vehicle/get-list", ginext.WrapHandler(vehicleHandler.GetListVehicle))
-	v1Api.PUT("/vehicle/update/:id", ginext.WrapHandler(vehicleHandler.UpdateVehicle))
-	v1Api.DELETE("/vehicle/delete/:id", ginext.WrapHandler(vehicleHandler.DeleteVehicle))
+	v1Api.POST("/vehicle/create", user, ginext.WrapHandler(vehicleHandler.CreateVehicle))
+	v1Api.GET("/vehicle/get-one/:id", user, ginext.WrapHandler(vehicleHandler.GetOneVehicle))
+	v1Api.GET("/vehicle/get-list", user, ginext.WrapHandler(vehicleHandler.GetListVehicle))
+	v1Api.PUT("/vehicle/update/:id", user, ginext.WrapHandler(vehicleHandler.UpdateVehicle))
+	v1Api.DELETE("/vehicle/delete/:id", user, ginext.WrapHandler(vehicleHandler.DeleteVehicle))
 
 	// ticket
-	v1Api.POST("/ticket/create", ginext.WrapHandler(ticketHandler.CreateTicket))
-	v1Api.GET("/ticket/get-all", ginext.WrapHandler(ticketHandler.GetAllTicket))
-	v1Api.GET("/ticket/get-one-with-extend/:id", ginext.WrapHandler(ticketHandler.GetOneTicketWithExtend))
-	v1Api.PUT("/ticket/cancel", ginext.WrapHandler(ticketHandler.CancelTicket))
-	v1Api.POST("/ticket/extend", ginext.WrapHandler(ticketHandler.ExtendTicket))
-	v1Api.POST("/ticket/procedure", ginext.WrapHandler(ticketHandler.ProcedureWithTicket))
-	v1Api.POST("/ticket/:id/review", ginext.WrapHandler(ticketHandler.ReviewTicket))
+	v1Api.POST("/ticket/quote", anyone, ginext.WrapHandler(ticketHandler.QuoteTicket))
+	v1Api.POST("/ticket/create", driver, ginext.WrapHandler(ticketHandler.CreateTicket))
+	v1Api.GET("/ticket/get-all", driver, ginext.WrapHandler(ticketHandler.GetAllTicket))
+	v1Api.GET("/ticket/get-one-with-extend/:id", anyone, ginext.WrapHandler(ticketHandler.GetOneTicketWithExtend))
+	v1Api.GET("/ticket/:id/timeline", anyone, ginext.WrapHandler(ticketHandler.GetTicketTimeline))
+	v1Api.PUT("/ticket/cancel", driver, ginext.WrapHandler(ticketHandler.CancelTicket))
+	v1Api.POST("/ticket/extend", driver, ginext.WrapHandler(ticketHandler.ExtendTicket))
+	v1Api.POST("/ticket/walk-in", staff, ginext.WrapHandler(ticketHandler.CreateWalkInTicket))
+	v1Api.POST("/ticket/gate/check-in", staff, ginext.WrapHandler(ticketHandler.GateCheckIn))
+	v1Api.POST("/ticket/gate/check-out", staff, ginext.WrapHandler(ticketHandler.GateCheckOut))
+	v1Api.POST("/ticket/procedure", staff, ginext.WrapHandler(ticketHandler.ProcedureWithTicket))
+	v1Api.GET("/ticket/:id/checkout-preview", staff, ginext.WrapHandler(ticketHandler.PreviewCheckout))
+	v1Api.POST("/ticket/:id/review", driver, ginext.WrapHandler(ticketHandler.ReviewTicket))
+	v1Api.GET("/ticket/long-term/get-list", driver, ginext.WrapHandler(ticketHandler.GetListLongTermTicket))
+	v1Api.GET("/ticket/long-term/:id", anyone, ginext.WrapHandler(ticketHandler.GetOneLongTermTicket))
+	v1Api.PUT("/ticket/long-term/:id/cancel", driver, ginext.WrapHandler(ticketHandler.CancelLongTermTicket))
+
+	// payment
+	v1Api.POST("/payment/create", driver, ginext.WrapHandler(paymentHandler.CreatePayment))
+	v1Api.GET("/payment/:id", anyone, ginext.WrapHandler(paymentHandler.GetOnePayment))
+	v1Api.POST("/payment/webhook/:provider", ginext.WrapHandler(paymentHandler.HandleWebhook))
+	if mockPayment {
+		v1Api.GET("/payment/mock/:ref", ginext.WrapHandler(paymentHandler.SimulateMockPayment))
+	}
+
+	// wallet
+	v1Api.GET("/wallet", driver, ginext.WrapHandler(walletHandler.GetWallet))
+	v1Api.POST("/wallet/top-up", driver, ginext.WrapHandler(walletHandler.TopUp))
+	v1Api.GET("/wallet/statement", driver, ginext.WrapHandler(walletHandler.GetStatement))
+
+	// notification
+	v1Api.GET("/notification/get-list", driver, ginext.WrapHandler(notificationHandler.GetListNotification))
+	v1Api.PUT("/notification/:id/read", driver, ginext.WrapHandler(notificationHandler.ReadNotification))
 
 	// company
 	merchantApi.POST("/company/create", cors.Default(), ginext.WrapHandler(companyHanler.CreateCompany))
-	merchantApi.PUT("/company/update/:id", cors.Default(), ginext.WrapHandler(companyHanler.UpdateCompany))
+	merchantApi.PUT("/company/update/:id", cors.Default(), owner, ginext.WrapHandler(companyHanler.UpdateCompany))
 	merchantApi.POST("/company/login", cors.Default(), ginext.WrapHandler(companyHanler.Login))
-	merchantApi.GET("/company/get-one/:id", cors.Default(), ginext.WrapHandler(companyHanler.GetOneCompany))
-	merchantApi.PUT("/company/update-password/:id", cors.Default(), ginext.WrapHandler(companyHanler.UpdateCompanyPassword))
-	merchantApi.PUT("/company/:id/status", cors.Default(), ginext.WrapHandler(companyHanler.ChangeCompanyStatus))
-	merchantApi.GET("/company", cors.Default(), ginext.WrapHandler(companyHanler.GetListCompany))
+	merchantApi.GET("/company/get-one/:id", cors.Default(), staff, ginext.WrapHandler(companyHanler.GetOneCompany))
+	merchantApi.PUT("/company/update-password/:id", cors.Default(), owner, ginext.WrapHandler(companyHanler.UpdateCompanyPassword))
+	merchantApi.PUT("/company/:id/status", cors.Default(), admin, ginext.WrapHandler(companyHanler.ChangeCompanyStatus))
+	merchantApi.GET("/company", cors.Default(), admin, ginext.WrapHandler(companyHanler.GetListCompany))
+	merchantApi.GET("/company/:id/setting/:key", staff, ginext.WrapHandler(settingHandler.GetCompanySetting))
+	merchantApi.PUT("/company/:id/setting/:key", admin, ginext.WrapHandler(settingHandler.UpdateCompanySetting))
+
+	merchantApi.GET("/parking-lot/get-list", staff, ginext.WrapHandler(lotHandler.GetListParkingLotCompany))
+	merchantApi.GET("/parking-lot/get-one/:id", staff, ginext.WrapHandler(lotHandler.GetOneParkingLot))
+	merchantApi.GET("/parking-lot/:id/setting/:key", staff, ginext.WrapHandler(settingHandler.GetParkingLotSetting))
+	merchantApi.PUT("/parking-lot/:id/setting/:key", owner, ginext.WrapHandler(settingHandler.UpdateParkingLotSetting))
+
+	merchantApi.GET("/block/get-list", staff, ginext.WrapHandler(blockHandler.GetListBlock))
+
+	merchantApi.GET("/time-frame/get-list", staff, ginext.WrapHandler(timeFrameHandler.GetAllTimeFrame))
+	merchantApi.GET("/ticket/get-all", staff, ginext.WrapHandler(ticketHandler.GetAllTicketCompany))
+	merchantApi.GET("/notification/get-list", staff, ginext.WrapHandler(notificationHandler.GetListNotification))
+	merchantApi.PUT("/notification/:id/read", staff, ginext.WrapHandler(notificationHandler.ReadNotification))
 
-	merchantApi.GET("/parking-lot/get-list", ginext.WrapHandler(lotHandler.GetListParkingLotCompany))
-	merchantApi.GET("/parking-lot/get-one/:id", ginext.WrapHandler(lotHandler.GetOneParkingLot))
+	// audit log
+	merchantApi.GET("/audit-log/get-list", owner, ginext.WrapHandler(auditHandler.GetListAuditLog))
 
-	merchantApi.GET("/block/get-list", ginext.WrapHandler(blockHandler.GetListBlock))
+	// voucher
+	merchantApi.POST("/voucher/create", owner, ginext.WrapHandler(voucherHandler.CreateVoucher))
+	merchantApi.GET("/voucher/get-list", staff, ginext.WrapHandler(voucherHandler.GetListVoucher))
+	merchantApi.GET("/voucher/get-one/:id", staff, ginext.WrapHandler(voucherHandler.GetOneVoucher))
+	merchantApi.PUT("/voucher/update/:id", owner, ginext.WrapHandler(voucherHandler.UpdateVoucher))
+	merchantApi.DELETE("/voucher/delete/:id", owner, ginext.WrapHandler(voucherHandler.DeleteVoucher))
+	merchantApi.GET("/voucher/redemption/get-list", staff, ginext.WrapHandler(voucherHandler.GetListRedemption))
+	merchantApi.GET("/voucher/report", staff, ginext.WrapHandler(voucherHandler.GetVoucherReport))
 
-	merchantApi.GET("/time-frame/get-list", ginext.WrapHandler(timeFrameHandler.GetAllTimeFrame))
-	merchantApi.GET("/ticket/get-all", ginext.WrapHandler(ticketHandler.GetAllTicketCompany))
+	// settlement
+	merchantApi.GET("/settlement/get-list", owner, ginext.WrapHandler(settlementHandler.GetListSettlement))
+	merchantApi.GET("/settlement/get-one/:id", owner, ginext.WrapHandler(settlementHandler.GetOneSettlement))
+	merchantApi.GET("/settlement/current", owner, ginext.WrapHandler(settlementHandler.GetCurrentSettlement))
+	merchantApi.GET("/settlement/export/:id", owner, ginext.WrapHandler(settlementHandler.ExportSettlement))
 
 	// employee
-	v1Api.POST("/employee/create", cors.Default(), ginext.WrapHandler(employeeHandler.CreateEmployee))
-	v1Api.PUT("/employee/update/:id", cors.Default(), ginext.WrapHandler(employeeHandler.UpdateEmployee))
-	v1Api.GET("/employee/get-list", cors.Default(), ginext.WrapHandler(employeeHandler.GetListEmployee))
-	v1Api.DELETE("/employee/delete/:id", ginext.WrapHandler(employeeHandler.DeleteEmployee))
+	v1Api.POST("/employee/create", cors.Default(), owner, ginext.WrapHandler(employeeHandler.CreateEmployee))
+	v1Api.PUT("/employee/update/:id", cors.Default(), owner, ginext.WrapHandler(employeeHandler.UpdateEmployee))
+	v1Api.GET("/employee/get-list", cors.Default(), staff, ginext.WrapHandler(employeeHandler.GetListEmployee))
+	v1Api.DELETE("/employee/delete/:id", owner, ginext.WrapHandler(employeeHandler.DeleteEmployee))
 	v1Api.POST("/employee/login", cors.Default(), ginext.WrapHandler(employeeHandler.Login))
-	v1Api.GET("/employee/get-one/:id", cors.Default(), ginext.WrapHandler(employeeHandler.GetOneEmployee))
+	v1Api.GET("/employee/get-one/:id", cors.Default(), staff, ginext.WrapHandler(employeeHandler.GetOneEmployee))
 
 	// admin
-	// adminApi.GET("/parking-lot")
-	// adminApi.PUT("/parking-lot")
-	//    adminApi.PUT("/parking-lot/status")
+	adminApi.POST("/login", ginext.WrapHandler(adminHandler.Login))
+	adminApi.POST("/create", admin, ginext.WrapHandler(adminHandler.CreateAdmin))
+	adminApi.GET("/review/company", admin, ginext.WrapHandler(adminHandler.GetReviewCompanies))
+	adminApi.PUT("/review/company/:id", admin, ginext.WrapHandler(adminHandler.ReviewCompany))
+	adminApi.GET("/review/parking-lot", admin, ginext.WrapHandler(adminHandler.GetReviewParkingLots))
+	adminApi.PUT("/review/parking-lot/:id", admin, ginext.WrapHandler(adminHandler.ReviewParkingLot))
+	adminApi.PUT("/company/:id/status", admin, ginext.WrapHandler(companyHanler.ChangeCompanyStatus))
+	adminApi.PUT("/parking-lot/:id/status", admin, ginext.WrapHandler(lotHandler.ChangeParkingLotStatus))
+	adminApi.GET("/status-history", admin, ginext.WrapHandler(adminHandler.GetStatusHistory))
 
-	// Migrate
-	migrateHandler := handlers.NewMigrationHandler(db)
-	s.Router.POST("/internal/migrate", migrateHandler.Migrate)
+	// Migrate, once an admin exists; the first admin is seeded by MIGRATE_ON_START
+	s.Router.POST("/internal/migrate", admin, migrateHandler.Migrate)
 	return s
 }
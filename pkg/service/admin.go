@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/limiter"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+	"strings"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	REVIEW_APPROVE = "approve"
+	REVIEW_REJECT  = "reject"
+)
+
+type AdminService struct {
+	repo  repo.PGInterface
+	guard *limiter.Guard
+}
+
+func NewAdminService(repo repo.PGInterface, guard *limiter.Guard) AdminInterface {
+	return &AdminService{repo: repo, guard: guard}
+}
+
+type AdminInterface interface {
+	LoginAdmin(ctx context.Context, email string, password string, client model.ClientInfo) (model.AdminLoginRes, error)
+	CreateAdmin(ctx context.Context, req model.AdminReq) (model.Admin, error)
+	SeedAdmin(ctx context.Context, email string, password string) error
+	GetReviewCompanies(ctx context.Context, req model.ListCompanyReq) (model.ListCompanyRes, error)
+	GetReviewParkingLots(ctx context.Context, req model.GetListParkingLotReq) (model.ListParkingLotRes, error)
+	ReviewCompany(ctx context.Context, id uuid.UUID, req model.ReviewReq) (model.Company, error)
+	ReviewParkingLot(ctx context.Context, id uuid.UUID, req model.ReviewReq) (model.ParkingLot, error)
+	GetStatusHistory(ctx context.Context, req model.ListStatusHistoryReq) (model.ListStatusHistoryRes, error)
+}
+
+func (s *AdminService) LoginAdmin(ctx context.Context, email string, password string, client model.ClientInfo) (res model.AdminLoginRes, err error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if err := s.guard.CheckLogin(ctx, email, client.IpAddress); err != nil {
+		return res, err
+	}
+	admin, err := s.repo.GetAdminByEmail(ctx, email, nil)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := s.guard.FailLogin(ctx, email, client.IpAddress); err != nil {
+				return res, err
+			}
+			return res, ginext.NewError(http.StatusUnauthorized, "Email not exists")
+		}
+		return res, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(password)); err != nil {
+		if err := s.guard.FailLogin(ctx, email, client.IpAddress); err != nil {
+			return res, err
+		}
+		return res, ginext.NewError(http.StatusUnauthorized, "Incorrect password")
+	}
+	if err := s.guard.ResetLogin(ctx, email); err != nil {
+		return res, err
+	}
+	if admin.Status != "active" {
+		return res, ginext.NewError(http.StatusUnauthorized, "Your account is currently inactive")
+	}
+
+	token, err := issueTokenPair(ctx, s.repo, adminPrincipal(admin, uuid.New()), client)
+	if err != nil {
+		return res, err
+	}
+	return model.AdminLoginRes{
+		Admin:        admin,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+	}, nil
+}
+
+func (s *AdminService) CreateAdmin(ctx context.Context, req model.AdminReq) (res model.Admin, err error) {
+	hashPassword, err := bcrypt.GenerateFromPassword([]byte(valid.String(req.Password)), 14)
+	if err != nil {
+		return res, err
+	}
+	admin := model.Admin{
+		Name:     valid.String(req.Name),
+		Email:    strings.ToLower(valid.String(req.Email)),
+		Password: string(hashPassword),
+	}
+	if principal, err := currentPrincipal(ctx); err == nil {
+		admin.CreatorID = &principal.ID
+	}
+	if err := s.repo.CreateAdmin(ctx, &admin, nil); err != nil {
+		return admin, err
+	}
+	return admin, nil
+}
+
+// SeedAdmin creates the first admin account, it does nothing once an admin exists
+func (s *AdminService) SeedAdmin(ctx context.Context, email string, password string) error {
+	if email == "" || password == "" {
+		return nil
+	}
+	total, err := s.repo.CountAdmin(ctx, nil)
+	if err != nil || total > 0 {
+		return err
+	}
+	logger.WithCtx(ctx, utils.GetCurrentCaller(s, 0)).Info("Create the first admin " + email)
+	_, err = s.CreateAdmin(ctx, model.AdminReq{
+		Name:     valid.StringPointer("admin"),
+		Email:    &email,
+		Password: &password,
+	})
+	return err
+}
+
+func (s *AdminService) GetReviewCompanies(ctx context.Context, req model.ListCompanyReq) (model.ListCompanyRes, error) {
+	if req.Status == nil {
+		req.Status = valid.StringPointer("pending")
+	}
+	if req.Sort == "" {
+		req.Sort = "created_at asc"
+	}
+	return s.repo.GetListCompany(ctx, req)
+}
+
+func (s *AdminService) GetReviewParkingLots(ctx context.Context, req model.GetListParkingLotReq) (model.ListParkingLotRes, error) {
+	if req.Status == nil {
+		req.Status = valid.StringPointer("pending")
+	}
+	if req.Sort == "" {
+		req.Sort = "created_at asc"
+	}
+	return s.repo.GetListParkingLotCompany(ctx, req)
+}
+
+func (s *AdminService) ReviewCompany(ctx context.Context, id uuid.UUID, req model.ReviewReq) (model.Company, error) {
+	company, err := s.repo.GetOneCompany(ctx, id)
+	if err != nil {
+		return company, err
+	}
+	status, err := reviewStatus(company.Status, req)
+	if err != nil {
+		return company, err
+	}
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		return changeCompanyStatus(ctx, rp, &company, status, valid.String(req.Reason))
+	})
+	return company, err
+}
+
+func (s *AdminService) ReviewParkingLot(ctx context.Context, id uuid.UUID, req model.ReviewReq) (model.ParkingLot, error) {
+	lot, err := s.repo.GetOneParkingLot(ctx, id)
+	if err != nil {
+		return lot, err
+	}
+	status, err := reviewStatus(lot.Status, req)
+	if err != nil {
+		return lot, err
+	}
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		return changeParkingLotStatus(ctx, rp, &lot, status, valid.String(req.Reason))
+	})
+	return lot, err
+}
+
+func (s *AdminService) GetStatusHistory(ctx context.Context, req model.ListStatusHistoryReq) (model.ListStatusHistoryRes, error) {
+	return s.repo.GetListStatusHistory(ctx, req)
+}
+
+// reviewStatus maps a decision on a pending record to its new status, a rejection needs a reason
+func reviewStatus(current string, req model.ReviewReq) (string, error) {
+	if current != "pending" {
+		return "", ginext.NewError(http.StatusConflict, "Only pending records can be reviewed")
+	}
+	switch valid.String(req.Decision) {
+	case REVIEW_APPROVE:
+		return "active", nil
+	case REVIEW_REJECT:
+		if strings.TrimSpace(valid.String(req.Reason)) == "" {
+			return "", ginext.NewError(http.StatusBadRequest, "A reason is required to reject")
+		}
+		return "rejected", nil
+	default:
+		return "", ginext.NewError(http.StatusBadRequest, "Decision must be approve or reject")
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/valid"
+
+	"github.com/google/uuid"
+)
+
+type AuditService struct {
+	repo repo.PGInterface
+}
+
+func NewAuditService(repo repo.PGInterface) AuditInterface {
+	return &AuditService{repo: repo}
+}
+
+type AuditInterface interface {
+	GetListAuditLog(ctx context.Context, req model.ListAuditLogReq) (model.ListAuditLogRes, error)
+}
+
+// GetListAuditLog lists the audit history of the company of the caller. Admins may ask for any company, or all of them.
+func (s *AuditService) GetListAuditLog(ctx context.Context, req model.ListAuditLogReq) (model.ListAuditLogRes, error) {
+	requested := uuid.Nil
+	if req.CompanyID != nil {
+		requested, _ = uuid.Parse(valid.String(req.CompanyID))
+	}
+	companyID, err := scopeCompanyID(ctx, requested)
+	if err != nil {
+		return model.ListAuditLogRes{}, err
+	}
+	req.CompanyID = nil
+	if companyID != uuid.Nil {
+		req.CompanyID = valid.StringPointer(companyID.String())
+	}
+	return s.repo.GetListAuditLog(ctx, req)
+}
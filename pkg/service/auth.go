@@ -2,11 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
+	"github.com/google/uuid"
 	"gitlab.com/goxp/cloud0/ginext"
 	"gitlab.com/goxp/cloud0/logger"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 	"net/http"
 	"parking-server/pkg/client"
+	"parking-server/pkg/limiter"
 	"parking-server/pkg/model"
 	"parking-server/pkg/repo"
 	"parking-server/pkg/utils"
@@ -15,56 +19,62 @@ import (
 )
 
 type AuthService struct {
-	repo         repo.PGInterface
-	twilioClient *client.TwilioClient
+	repo  repo.PGInterface
+	otp   client.OtpProvider
+	guard *limiter.Guard
 }
 
-func NewAuthService(repo repo.PGInterface) AuthServiceInterface {
-	twilioClient := client.GetTwiliioClient()
-	if twilioClient == nil {
-		panic("Twilio client was not set")
-	}
-
-	return &AuthService{repo: repo, twilioClient: twilioClient}
+func NewAuthService(repo repo.PGInterface, otp client.OtpProvider, guard *limiter.Guard) AuthServiceInterface {
+	return &AuthService{repo: repo, otp: otp, guard: guard}
 }
 
 type AuthServiceInterface interface {
-	Login(ctx context.Context, req model.Credential) (interface{}, error)
-	ResetPassword(ctx context.Context, req model.Credential) error
-	SendOtp(ctx context.Context, req model.SendOtpReq) error
-	VerifyOtp(ctx context.Context, req model.VeifryOtpReq) error
+	Login(ctx context.Context, req model.Credential, client model.ClientInfo) (interface{}, error)
+	RefreshToken(ctx context.Context, req model.RefreshTokenReq, client model.ClientInfo) (*model.TokenRes, error)
+	Logout(ctx context.Context, principal *utils.Principal) error
+	GetSessions(ctx context.Context, principal *utils.Principal) ([]model.SessionRes, error)
+	RevokeSession(ctx context.Context, principal *utils.Principal, sessionId uuid.UUID) error
+	ResetPassword(ctx context.Context, req model.ResetPasswordReq) error
+	SendOtp(ctx context.Context, req model.SendOtpReq, client model.ClientInfo) error
+	VerifyOtp(ctx context.Context, req model.VeifryOtpReq, client model.ClientInfo) (*model.VerifyOtpRes, error)
 }
 
-func (s *AuthService) Login(ctx context.Context, req model.Credential) (interface{}, error) {
+func (s *AuthService) Login(ctx context.Context, req model.Credential, client model.ClientInfo) (interface{}, error) {
 	log := logger.WithCtx(ctx, utils.GetCurrentCaller(s, 0))
+	if err := s.guard.CheckLogin(ctx, valid.String(req.UserName), client.IpAddress); err != nil {
+		return nil, err
+	}
 	user, err := s.repo.GetOneUserByPhone(ctx, valid.String(req.UserName), nil)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := s.guard.FailLogin(ctx, valid.String(req.UserName), client.IpAddress); err != nil {
+				return nil, err
+			}
+		}
 		return nil, err
 	}
 
 	// check password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(valid.String(req.Password))); err != nil {
+		if err := s.guard.FailLogin(ctx, valid.String(req.UserName), client.IpAddress); err != nil {
+			return nil, err
+		}
 		return nil, ginext.NewError(http.StatusBadRequest, "Mật khẩu không đúng!")
 	}
+	if err := s.guard.ResetLogin(ctx, valid.String(req.UserName)); err != nil {
+		return nil, err
+	}
 
-	token, err := utils.GenerateToken(user.ID.String())
+	// every login opens a new session
+	principal := &utils.Principal{ID: user.ID, SessionID: uuid.New(), Type: utils.PRINCIPAL_USER}
+	token, err := issueTokenPair(ctx, s.repo, principal, client)
 	if err != nil {
 		log.WithError(err).Error("Error when generate token - Login - AuthService")
 		return nil, err
 	}
-	// refresh token
-	exp := time.Now().Add(utils.EXPIRTE_TIME * time.Second)
-	rf_token, _ := utils.GenerateToken(user.ID.String())
-	refreshToken := &model.RefreshToken{
-		Token:       rf_token,
-		ExpiredDate: valid.DayTimePointer(exp),
-	}
-	if err := s.repo.CreateRefreshToken(ctx, refreshToken, nil); err != nil {
-		return nil, err
-	}
 	res := model.LoginResponse{
-		AccessToken:  token,
-		RefreshToken: refreshToken.Token,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
 		PhoneNumber:  user.PhoneNumber,
 		DisplayName:  user.DisplayName,
 		Id:           user.ID,
@@ -74,28 +84,132 @@ func (s *AuthService) Login(ctx context.Context, req model.Credential) (interfac
 	return res, nil
 }
 
-func (s *AuthService) ResetPassword(ctx context.Context, req model.Credential) error {
+// RefreshToken exchanges a refresh token for a new token pair. A refresh token can be used only once,
+// presenting an already used one means it was stolen, so the whole session is revoked.
+func (s *AuthService) RefreshToken(ctx context.Context, req model.RefreshTokenReq, client model.ClientInfo) (*model.TokenRes, error) {
 	log := logger.WithCtx(ctx, utils.GetCurrentCaller(s, 0))
-	user, err := s.repo.GetOneUserByPhone(ctx, valid.String(req.UserName), nil)
+
+	var (
+		res         *model.TokenRes
+		reusedToken *model.RefreshToken
+	)
+	err := s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		refreshToken, err := rp.GetRefreshTokenForUpdate(ctx, utils.HashToken(valid.String(req.RefreshToken)), nil)
+		if err != nil {
+			return err
+		}
+		if refreshToken.RevokedAt != nil || refreshToken.ExpiredDate == nil || refreshToken.ExpiredDate.Before(time.Now()) {
+			return ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+		}
+		if refreshToken.UsedAt != nil {
+			reusedToken = &refreshToken
+			return ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+		}
+
+		refreshToken.UsedAt = valid.DayTimePointer(time.Now())
+		if err := rp.UpdateRefreshToken(ctx, &refreshToken, nil); err != nil {
+			return err
+		}
+		// reload the principal, so that role changes and deactivated accounts apply on the next refresh
+		principal, err := loadPrincipal(ctx, rp, refreshToken)
+		if err != nil {
+			return err
+		}
+		res, err = issueTokenPair(ctx, rp, principal, client)
+		return err
+	})
+	if reusedToken != nil {
+		// revoke outside of the transaction above, it has been rolled back
+		log.WithField("session_id", reusedToken.SessionId).Warn("Refresh token reuse detected, revoke session")
+		if err := s.repo.RevokeSession(ctx, reusedToken.SessionId, nil); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *AuthService) Logout(ctx context.Context, principal *utils.Principal) error {
+	return s.repo.RevokeSession(ctx, principal.SessionID, nil)
+}
+
+func (s *AuthService) GetSessions(ctx context.Context, principal *utils.Principal) ([]model.SessionRes, error) {
+	tokens, err := s.repo.GetActiveSessionsByUser(ctx, principal.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.SessionRes, 0, len(tokens))
+	for _, token := range tokens {
+		res = append(res, model.SessionRes{
+			SessionId:   token.SessionId,
+			UserAgent:   token.UserAgent,
+			IpAddress:   token.IpAddress,
+			LastUsedAt:  token.CreatedAt,
+			ExpiredDate: token.ExpiredDate,
+			IsCurrent:   token.SessionId == principal.SessionID,
+		})
+	}
+	return res, nil
+}
+
+func (s *AuthService) RevokeSession(ctx context.Context, principal *utils.Principal, sessionId uuid.UUID) error {
+	tokens, err := s.repo.GetActiveSessionsByUser(ctx, principal.ID, nil)
 	if err != nil {
 		return err
 	}
+	for _, token := range tokens {
+		if token.SessionId == sessionId {
+			return s.repo.RevokeSession(ctx, sessionId, nil)
+		}
+	}
+	return ginext.NewError(http.StatusNotFound, utils.MessageError()[http.StatusNotFound])
+}
+
+// ResetPassword consumes a reset token issued by VerifyOtp and signs the user out of every session
+func (s *AuthService) ResetPassword(ctx context.Context, req model.ResetPasswordReq) error {
+	log := logger.WithCtx(ctx, utils.GetCurrentCaller(s, 0))
 	hashPass, err := utils.Hash(valid.String(req.Password))
 	if err != nil {
 		log.WithError(err).Error("Failed to hash password")
 		return ginext.NewError(http.StatusInternalServerError, "Failed to hash password")
 	}
-	user.Password = hashPass
 
-	if err := s.repo.UpdateUser(ctx, user, nil); err != nil {
-		return err
-	}
-	return nil
+	return s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		resetToken, err := rp.GetPasswordResetTokenForUpdate(ctx, utils.HashToken(valid.String(req.ResetToken)), nil)
+		if err != nil {
+			return err
+		}
+		if resetToken.UsedAt != nil || resetToken.ExpiredDate == nil || resetToken.ExpiredDate.Before(time.Now()) {
+			return ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+		}
+		resetToken.UsedAt = valid.DayTimePointer(time.Now())
+		if err := rp.UpdatePasswordResetToken(ctx, &resetToken, nil); err != nil {
+			return err
+		}
+
+		user, err := rp.GetOneUserByPhone(ctx, resetToken.PhoneNumber, nil)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ginext.NewError(http.StatusNotFound, "Số điện thoại chưa được đăng ký")
+			}
+			return err
+		}
+		user.Password = hashPass
+		if err := rp.UpdateUser(ctx, user, nil); err != nil {
+			return err
+		}
+		return rp.RevokeAllSessions(ctx, user.ID, nil)
+	})
 }
 
-func (s *AuthService) SendOtp(ctx context.Context, req model.SendOtpReq) error {
+func (s *AuthService) SendOtp(ctx context.Context, req model.SendOtpReq, client model.ClientInfo) error {
 	log := logger.WithCtx(ctx, utils.GetCurrentCaller(s, 0))
-	err := s.twilioClient.SendOtp(req.PhoneNumber)
+	if err := s.guard.HitOtpSend(ctx, req.PhoneNumber, client.IpAddress); err != nil {
+		return err
+	}
+	err := s.otp.SendOtp(ctx, req.PhoneNumber)
 	if err != nil {
 		log.WithError(err).Error("Failed to send otp")
 		return ginext.NewError(http.StatusInternalServerError, "Verification code failed. Check your phone number and try again. Still having trouble? Contact support.")
@@ -104,17 +218,39 @@ func (s *AuthService) SendOtp(ctx context.Context, req model.SendOtpReq) error {
 	return nil
 }
 
-func (s *AuthService) VerifyOtp(ctx context.Context, req model.VeifryOtpReq) error {
+// VerifyOtp checks the code and issues a single use reset token bound to the phone number
+func (s *AuthService) VerifyOtp(ctx context.Context, req model.VeifryOtpReq, client model.ClientInfo) (*model.VerifyOtpRes, error) {
 	log := logger.WithCtx(ctx, utils.GetCurrentCaller(s, 0))
-	ok, err := s.twilioClient.CheckOtp(req.PhoneNumber, req.Otp)
+	if err := s.guard.CheckOtpVerify(ctx, req.PhoneNumber, client.IpAddress); err != nil {
+		return nil, err
+	}
+	ok, err := s.otp.CheckOtp(ctx, req.PhoneNumber, req.Otp)
 	if err != nil {
 		log.WithError(err).Error("Failed check otp")
-		return ginext.NewError(http.StatusInternalServerError, "Verification code failed. Check your phone number and try again. Still having trouble? Contact support.")
+		return nil, ginext.NewError(http.StatusInternalServerError, "Verification code failed. Check your phone number and try again. Still having trouble? Contact support.")
 	}
 
 	if !ok {
-		return ginext.NewError(http.StatusBadRequest, "Incorrect OTP.")
+		if err := s.guard.FailOtpVerify(ctx, req.PhoneNumber, client.IpAddress); err != nil {
+			return nil, err
+		}
+		return nil, ginext.NewError(http.StatusBadRequest, "Incorrect OTP.")
+	}
+	if err := s.guard.ResetOtpVerify(ctx, req.PhoneNumber); err != nil {
+		return nil, err
 	}
 
-	return nil
+	token, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, ginext.NewError(http.StatusInternalServerError, "Error when generate reset token: "+err.Error())
+	}
+	resetToken := &model.PasswordResetToken{
+		Token:       utils.HashToken(token),
+		PhoneNumber: req.PhoneNumber,
+		ExpiredDate: valid.DayTimePointer(time.Now().Add(utils.RESET_TOKEN_EXPIRTE_TIME * time.Second)),
+	}
+	if err := s.repo.CreatePasswordResetToken(ctx, resetToken, nil); err != nil {
+		return nil, err
+	}
+	return &model.VerifyOtpRes{ResetToken: token}, nil
 }
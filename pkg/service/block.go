@@ -26,6 +26,9 @@ type BlockInterface interface {
 }
 
 func (s *BlockService) CreateBlock(ctx context.Context, req model.BlockReq) (*model.Block, error) {
+	if err := authorizeParkingLot(ctx, s.repo, valid.UUID(req.ParkingLotID)); err != nil {
+		return nil, err
+	}
 	block := &model.Block{
 		Code:         valid.String(req.Code),
 		Description:  valid.String(req.Description),
@@ -52,6 +55,14 @@ func (s *BlockService) UpdateBlock(ctx context.Context, req model.BlockReq) (mod
 	if err != nil {
 		return block, err
 	}
+	if err := authorizeParkingLot(ctx, s.repo, block.ParkingLotID); err != nil {
+		return block, err
+	}
+	if req.ParkingLotID != nil && *req.ParkingLotID != block.ParkingLotID {
+		if err := authorizeParkingLot(ctx, s.repo, *req.ParkingLotID); err != nil {
+			return block, err
+		}
+	}
 
 	utils.Sync(req, &block)
 	if err := s.repo.UpdateBlock(ctx, &block); err != nil {
@@ -62,5 +73,8 @@ func (s *BlockService) UpdateBlock(ctx context.Context, req model.BlockReq) (mod
 }
 
 func (s *BlockService) DeleteBlock(ctx context.Context, id uuid.UUID) error {
+	if err := authorizeBlock(ctx, s.repo, id); err != nil {
+		return err
+	}
 	return s.repo.DeleteBlock(ctx, id)
 }
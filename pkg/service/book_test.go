@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/utils"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// slotRepo keeps the tickets in memory. A slot locked in a transaction stays locked until the transaction
+// ends, like the row lock Postgres takes.
+type slotRepo struct {
+	repo.PGInterface
+	mu      sync.Mutex
+	tickets []model.Ticket
+	locks   sync.Map // slot id -> *sync.Mutex
+}
+
+type slotTx struct {
+	*slotRepo
+	held []*sync.Mutex
+}
+
+func (r *slotRepo) Transaction(ctx context.Context, f func(rp repo.PGInterface) error) error {
+	tx := &slotTx{slotRepo: r}
+	defer func() {
+		for _, lock := range tx.held {
+			lock.Unlock()
+		}
+	}()
+	return f(tx)
+}
+
+func (tx *slotTx) LockParkingSlot(ctx context.Context, id uuid.UUID, _ *gorm.DB) error {
+	lock, _ := tx.locks.LoadOrStore(id, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	tx.held = append(tx.held, lock.(*sync.Mutex))
+	return nil
+}
+
+func (tx *slotTx) HasOverlapTicket(ctx context.Context, slotID uuid.UUID, start, end time.Time, _ *gorm.DB) (bool, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	for _, ticket := range tx.tickets {
+		if *ticket.ParkingSlotId == slotID && ticket.StartTime.Before(end) && start.Before(stayEnd(ticket)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (tx *slotTx) CreateTicket(ctx context.Context, ticket *model.Ticket, _ *gorm.DB) error {
+	// leaves room for another booking to run its check in between, which only the slot lock prevents
+	runtime.Gosched()
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	ticket.ID = uuid.New()
+	tx.tickets = append(tx.tickets, *ticket)
+	return nil
+}
+
+func (tx *slotTx) CreateTicketStateHistory(context.Context, *model.TicketStateHistory, *gorm.DB) error {
+	return nil
+}
+
+// TestBookParkingSlotConcurrent books one slot for overlapping windows from many goroutines, each in a
+// transaction of its own: exactly one booking wins, the others get a conflict. TestCreateTicketConcurrent
+// checks the same against Postgres.
+func TestBookParkingSlotConcurrent(t *testing.T) {
+	utils.LoadMessageError()
+	rp := &slotRepo{}
+	slotID := uuid.New()
+	start := time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC)
+
+	const bookings = 20
+	errs := make([]error, bookings)
+	var wg sync.WaitGroup
+	for i := 0; i < bookings; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			from := start.Add(time.Duration(i%3) * 30 * time.Minute)
+			to := from.Add(2 * time.Hour)
+			errs[i] = rp.Transaction(context.Background(), func(tx repo.PGInterface) error {
+				return bookParkingSlot(context.Background(), tx, &model.Ticket{
+					ParkingSlotId: &slotID,
+					StartTime:     &from,
+					EndTime:       &to,
+					State:         model.TicketStateNew,
+				})
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	booked := 0
+	for _, err := range errs {
+		if err == nil {
+			booked++
+		} else if !hasCode(err, http.StatusConflict) {
+			t.Errorf("booking failed with %v, want a conflict", err)
+		}
+	}
+	if booked != 1 || len(rp.tickets) != 1 {
+		t.Errorf("%d bookings succeeded with %d tickets, want exactly 1", booked, len(rp.tickets))
+	}
+}
+
+func TestBookParkingSlotAfterPreviousStay(t *testing.T) {
+	utils.LoadMessageError()
+	rp := &slotRepo{}
+	slotID := uuid.New()
+	start := time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		from := start.Add(time.Duration(i) * time.Hour)
+		to := from.Add(time.Hour)
+		err := rp.Transaction(context.Background(), func(tx repo.PGInterface) error {
+			return bookParkingSlot(context.Background(), tx, &model.Ticket{
+				ParkingSlotId: &slotID,
+				StartTime:     &from,
+				EndTime:       &to,
+				State:         model.TicketStateNew,
+			})
+		})
+		if err != nil {
+			t.Fatalf("booking %d: %v, want the back-to-back stays to fit", i, err)
+		}
+	}
+}
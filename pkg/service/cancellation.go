@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/valid"
+	"time"
+
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// refundPercent applies the cancellation policy of the lot to a ticket cancelled at now
+func refundPercent(ctx context.Context, rp repo.PGInterface, ticket model.Ticket, now time.Time) (float64, error) {
+	policy := model.CancellationPolicy{}
+	if _, err := loadSetting(ctx, rp, valid.UUID(ticket.ParkingLotId), model.SETTING_CANCELLATION_POLICY, &policy); err != nil {
+		return 0, err
+	}
+	lead := valid.DayTime(ticket.StartTime).Sub(now)
+	if lead <= 0 {
+		if !policy.AllowAfterStart {
+			return 0, ginext.NewError(http.StatusConflict, "Ticket cannot be cancelled after its start time")
+		}
+		return 0, nil
+	}
+	if lead >= time.Duration(policy.FreeBefore)*time.Minute {
+		return 100, nil
+	}
+	percent, best := 0.0, -1
+	for _, tier := range policy.Tiers {
+		if lead >= time.Duration(tier.Before)*time.Minute && tier.Before > best {
+			percent, best = tier.Percent, tier.Before
+		}
+	}
+	return percent, nil
+}
+
+// cancelTicket cancels the ticket and its extensions, refunding percent of each that was paid. It must run
+// inside a transaction and returns the tickets cancelled, for their refund to be paid out after commit.
+func cancelTicket(ctx context.Context, rp repo.PGInterface, ticket *model.Ticket, percent float64) ([]model.Ticket, error) {
+	extensions, err := rp.GetListExtendTicketByOrigin(ctx, ticket.ID.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var cancelled []model.Ticket
+	cancel := func(t *model.Ticket) error {
+		t.Refund = 0
+		if t.PaymentStatus == model.TICKET_PAID {
+			t.Refund = math.Round(t.Total*percent) / 100
+		}
+		if err := transitionTicket(ctx, rp, t, model.TicketStateCancel, nil); err != nil {
+			return err
+		}
+		if err := releaseVoucher(ctx, rp, *t); err != nil {
+			return err
+		}
+		cancelled = append(cancelled, *t)
+		return nil
+	}
+	if err := cancel(ticket); err != nil {
+		return nil, err
+	}
+	for i := range extensions {
+		if extensions[i].State != model.TicketStateExtend && extensions[i].State != model.TicketStatePending {
+			continue
+		}
+		if err := cancel(&extensions[i]); err != nil {
+			return nil, err
+		}
+	}
+	return cancelled, nil
+}
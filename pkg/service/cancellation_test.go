@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gorm.io/gorm"
+)
+
+// settingRepo serves the settings of lots from memory
+type settingRepo struct {
+	repo.PGInterface
+	settings map[string]interface{}
+}
+
+func (r settingRepo) GetSetting(ctx context.Context, parkingLotID uuid.UUID, key string, _ *gorm.DB) (model.Setting, error) {
+	value, ok := r.settings[key]
+	if !ok {
+		return model.Setting{}, ginext.NewError(http.StatusNotFound, "setting not found")
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return model.Setting{}, err
+	}
+	return model.Setting{ParkingLotId: parkingLotID, Key: key, Value: pgtype.JSONB{Bytes: data, Status: pgtype.Present}}, nil
+}
+
+func TestRefundPercent(t *testing.T) {
+	policy := model.CancellationPolicy{
+		FreeBefore: 24 * 60,
+		Tiers:      []model.RefundTier{{Before: 120, Percent: 50}, {Before: 360, Percent: 80}},
+	}
+	lenient := policy
+	lenient.AllowAfterStart = true
+	withPolicy := func(p model.CancellationPolicy) settingRepo {
+		return settingRepo{settings: map[string]interface{}{model.SETTING_CANCELLATION_POLICY: p}}
+	}
+
+	tests := []struct {
+		name    string
+		rp      settingRepo
+		lead    time.Duration // from the cancellation to the start of the ticket
+		percent float64
+		code    int
+	}{
+		{name: "before the free period", rp: withPolicy(policy), lead: 48 * time.Hour, percent: 100},
+		{name: "at the start of the free period", rp: withPolicy(policy), lead: 24 * time.Hour, percent: 100},
+		{name: "best tier reached", rp: withPolicy(policy), lead: 10 * time.Hour, percent: 80},
+		{name: "lower tier", rp: withPolicy(policy), lead: 3 * time.Hour, percent: 50},
+		{name: "no tier reached", rp: withPolicy(policy), lead: time.Hour, percent: 0},
+		{name: "after the start", rp: withPolicy(policy), lead: -time.Hour, code: http.StatusConflict},
+		{name: "after the start when allowed", rp: withPolicy(lenient), lead: -time.Hour, percent: 0},
+		{name: "no policy", rp: settingRepo{}, lead: time.Minute, percent: 100},
+		{name: "no policy after the start", rp: settingRepo{}, lead: -time.Minute, code: http.StatusConflict},
+	}
+	lotID := uuid.New()
+	now := time.Date(2026, 10, 21, 8, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := now.Add(tt.lead)
+			percent, err := refundPercent(context.Background(), tt.rp, model.Ticket{ParkingLotId: &lotID, StartTime: &start}, now)
+			if tt.code != 0 {
+				if !hasCode(err, tt.code) {
+					t.Fatalf("got error %v, want a %d", err, tt.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if percent != tt.percent {
+				t.Errorf("got %v%%, want %v%%", percent, tt.percent)
+			}
+		})
+	}
+}
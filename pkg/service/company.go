@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"net/http"
+	"parking-server/pkg/limiter"
 	"parking-server/pkg/model"
 	"parking-server/pkg/repo"
 	"parking-server/pkg/utils"
@@ -15,17 +16,18 @@ import (
 )
 
 type CompanyService struct {
-	repo repo.PGInterface
+	repo  repo.PGInterface
+	guard *limiter.Guard
 }
 
-func NewCompanyService(repo repo.PGInterface) CompanyInterface {
-	return &CompanyService{repo: repo}
+func NewCompanyService(repo repo.PGInterface, guard *limiter.Guard) CompanyInterface {
+	return &CompanyService{repo: repo, guard: guard}
 }
 
 type CompanyInterface interface {
 	CreateCompany(ctx context.Context, req model.CompanyReq) (model.Company, error)
 	GetListCompany(ctx context.Context, req model.ListCompanyReq) (model.ListCompanyRes, error)
-	LoginCompany(ctx context.Context, email string, password string) (model.Company, error)
+	LoginCompany(ctx context.Context, email string, password string, client model.ClientInfo) (model.CompanyLoginRes, error)
 	GetOneCompany(ctx context.Context, id uuid.UUID) (model.Company, error)
 	UpdateCompany(ctx context.Context, id uuid.UUID, req model.CompanyReq) (model.Company, error)
 	UpdateCompanyPassword(ctx context.Context, id uuid.UUID, req model.PasswordChangeReq) (model.Company, error)
@@ -51,35 +53,69 @@ func (s *CompanyService) CreateCompany(ctx context.Context, req model.CompanyReq
 	return company, nil
 }
 
-func (s *CompanyService) LoginCompany(ctx context.Context, email string, password string) (model.Company, error) {
+func (s *CompanyService) LoginCompany(ctx context.Context, email string, password string, client model.ClientInfo) (res model.CompanyLoginRes, err error) {
+	if err := s.guard.CheckLogin(ctx, email, client.IpAddress); err != nil {
+		return res, err
+	}
 	company, err := s.repo.GetCompanyByEmail(ctx, email)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return company, ginext.NewError(http.StatusUnauthorized, "Email not exists")
+			if err := s.guard.FailLogin(ctx, email, client.IpAddress); err != nil {
+				return res, err
+			}
+			return res, ginext.NewError(http.StatusUnauthorized, "Email not exists")
 		}
-		return company, err
+		return res, err
 	}
 	err = bcrypt.CompareHashAndPassword([]byte(company.Password), []byte(password))
 	if err != nil {
-		return company, ginext.NewError(http.StatusUnauthorized, "Incorrect password")
+		if err := s.guard.FailLogin(ctx, email, client.IpAddress); err != nil {
+			return res, err
+		}
+		return res, ginext.NewError(http.StatusUnauthorized, "Incorrect password")
+	}
+	if err := s.guard.ResetLogin(ctx, email); err != nil {
+		return res, err
 	}
 
 	if company.Status == "inactive" {
-		return company, ginext.NewError(http.StatusUnauthorized, "Your account is currently inactive")
+		return res, ginext.NewError(http.StatusUnauthorized, "Your account is currently inactive")
 	}
 
 	if company.Status == "pending" {
-		return company, ginext.NewError(http.StatusUnauthorized, "Your account is currently under review. We'll be in touch as soon as it's finalized.")
+		return res, ginext.NewError(http.StatusUnauthorized, "Your account is currently under review. We'll be in touch as soon as it's finalized.")
 	}
 
-	return company, nil
+	if company.Status != "active" {
+		return res, ginext.NewError(http.StatusUnauthorized, "Your account was not approved")
+	}
+
+	token, err := issueTokenPair(ctx, s.repo, companyPrincipal(company, uuid.New()), client)
+	if err != nil {
+		return res, err
+	}
+
+	return model.CompanyLoginRes{
+		Company:      company,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+	}, nil
 }
 
 func (s *CompanyService) GetOneCompany(ctx context.Context, id uuid.UUID) (model.Company, error) {
+	if err := authorizeCompany(ctx, id); err != nil {
+		return model.Company{}, err
+	}
 	return s.repo.GetOneCompany(ctx, id)
 }
 
 func (s *CompanyService) UpdateCompany(ctx context.Context, id uuid.UUID, req model.CompanyReq) (model.Company, error) {
+	if err := authorizeCompany(ctx, id); err != nil {
+		return model.Company{}, err
+	}
+	// status is reviewed by admins, see ChangecompanyStatus
+	req.Status = nil
+
 	company, err := s.repo.GetOneCompany(ctx, id)
 	if err != nil {
 		return company, err
@@ -95,6 +131,9 @@ func (s *CompanyService) UpdateCompany(ctx context.Context, id uuid.UUID, req mo
 }
 
 func (s *CompanyService) UpdateCompanyPassword(ctx context.Context, id uuid.UUID, req model.PasswordChangeReq) (model.Company, error) {
+	if err := authorizeCompany(ctx, id); err != nil {
+		return model.Company{}, err
+	}
 	company, err := s.repo.GetOneCompany(ctx, id)
 	if err != nil {
 		return company, err
@@ -124,16 +163,10 @@ func (s *CompanyService) ChangecompanyStatus(ctx context.Context, req model.Chan
 		return company, err
 	}
 
-	if req.Status != nil && *req.Status == company.Status {
-		return company, nil
-	}
-
-	utils.Sync(req, &company)
-	if err := s.repo.UpdateCompany(ctx, &company); err != nil {
-		return company, err
-	}
-
-	return company, nil
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		return changeCompanyStatus(ctx, rp, &company, valid.String(req.Status), valid.String(req.Reason))
+	})
+	return company, err
 }
 
 func (s *CompanyService) GetListCompany(ctx context.Context, req model.ListCompanyReq) (model.ListCompanyRes, error) {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"parking-server/pkg/limiter"
 	"parking-server/pkg/model"
 	"parking-server/pkg/repo"
 	"parking-server/pkg/utils"
@@ -16,17 +17,18 @@ import (
 )
 
 type EmployeeService struct {
-	repo repo.PGInterface
+	repo  repo.PGInterface
+	guard *limiter.Guard
 }
 
-func NewEmployeeService(repo repo.PGInterface) EmployeeInterface {
-	return &EmployeeService{repo: repo}
+func NewEmployeeService(repo repo.PGInterface, guard *limiter.Guard) EmployeeInterface {
+	return &EmployeeService{repo: repo, guard: guard}
 }
 
 type EmployeeInterface interface {
 	CreateEmployee(ctx context.Context, req model.EmployeeReq) (model.Employee, error)
 	GetListEmployee(ctx context.Context, req model.ListEmployeeReq) (model.ListEmployeeRes, error)
-	LoginEmployee(ctx context.Context, email string, password string) (model.Employee, error)
+	LoginEmployee(ctx context.Context, email string, password string, client model.ClientInfo) (model.EmployeeLoginRes, error)
 	GetOneEmployee(ctx context.Context, id uuid.UUID) (model.Employee, error)
 	UpdateEmployee(ctx context.Context, id uuid.UUID, req model.EmployeeReq) (model.Employee, error)
 	UpdateEmployeePassword(ctx context.Context, id uuid.UUID, req model.PasswordChangeReq) (model.Employee, error)
@@ -34,6 +36,12 @@ type EmployeeInterface interface {
 }
 
 func (s *EmployeeService) CreateEmployee(ctx context.Context, req model.EmployeeReq) (res model.Employee, err error) {
+	companyID, err := scopeCompanyID(ctx, valid.UUID(req.CompanyID))
+	if err != nil {
+		return res, err
+	}
+	req.CompanyID = &companyID
+
 	hashPassword, err := bcrypt.GenerateFromPassword([]byte(valid.String(req.Password)), 14)
 	if err != nil {
 		return res, err
@@ -58,32 +66,68 @@ func (s *EmployeeService) CreateEmployee(ctx context.Context, req model.Employee
 }
 
 func (s *EmployeeService) GetListEmployee(ctx context.Context, req model.ListEmployeeReq) (model.ListEmployeeRes, error) {
+	requested, _ := uuid.Parse(valid.String(req.CompanyID))
+	companyID, err := scopeCompanyID(ctx, requested)
+	if err != nil {
+		return model.ListEmployeeRes{}, err
+	}
+	if companyID != uuid.Nil {
+		req.CompanyID = valid.StringPointer(companyID.String())
+	}
 	return s.repo.GetListEmployee(ctx, req)
 }
 
-func (s *EmployeeService) LoginEmployee(ctx context.Context, email string, password string) (model.Employee, error) {
+func (s *EmployeeService) LoginEmployee(ctx context.Context, email string, password string, client model.ClientInfo) (res model.EmployeeLoginRes, err error) {
+	if err := s.guard.CheckLogin(ctx, email, client.IpAddress); err != nil {
+		return res, err
+	}
 	employee, err := s.repo.GetEmployeeByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return employee, ginext.NewError(http.StatusUnauthorized, "Email not exists")
+			if err := s.guard.FailLogin(ctx, email, client.IpAddress); err != nil {
+				return res, err
+			}
+			return res, ginext.NewError(http.StatusUnauthorized, "Email not exists")
 		}
-		return employee, err
+		return res, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(employee.Password), []byte(password))
+	if err != nil {
+		if err := s.guard.FailLogin(ctx, email, client.IpAddress); err != nil {
+			return res, err
+		}
+		return res, ginext.NewError(http.StatusUnauthorized, "Incorrect password")
+	}
+	if err := s.guard.ResetLogin(ctx, email); err != nil {
+		return res, err
 	}
 
 	if employee.Status != "active" {
-		return employee, ginext.NewError(http.StatusUnauthorized, "Your account is currently inactive")
+		return res, ginext.NewError(http.StatusUnauthorized, "Your account is currently inactive")
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(employee.Password), []byte(password))
+	token, err := issueTokenPair(ctx, s.repo, employeePrincipal(employee, uuid.New()), client)
 	if err != nil {
-		return employee, ginext.NewError(http.StatusUnauthorized, "Incorrect password")
+		return res, err
 	}
 
-	return employee, nil
+	return model.EmployeeLoginRes{
+		Employee:     employee,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+	}, nil
 }
 
 func (s *EmployeeService) GetOneEmployee(ctx context.Context, id uuid.UUID) (model.Employee, error) {
-	return s.repo.GetOneEmployee(ctx, id)
+	employee, err := s.repo.GetOneEmployee(ctx, id)
+	if err != nil {
+		return employee, err
+	}
+	if err := authorizeCompany(ctx, employee.CompanyID); err != nil {
+		return model.Employee{}, err
+	}
+	return employee, nil
 }
 
 func (s *EmployeeService) UpdateEmployee(ctx context.Context, id uuid.UUID, req model.EmployeeReq) (model.Employee, error) {
@@ -91,6 +135,11 @@ func (s *EmployeeService) UpdateEmployee(ctx context.Context, id uuid.UUID, req
 	if err != nil {
 		return employee, err
 	}
+	if err := authorizeCompany(ctx, employee.CompanyID); err != nil {
+		return employee, err
+	}
+	// an employee can not be moved to another company
+	req.CompanyID = nil
 
 	utils.Sync(req, &employee)
 
@@ -134,5 +183,12 @@ func (s *EmployeeService) UpdateEmployeePassword(ctx context.Context, id uuid.UU
 }
 
 func (s *EmployeeService) DeleteEmployee(ctx context.Context, id uuid.UUID) error {
+	employee, err := s.repo.GetOneEmployee(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := authorizeCompany(ctx, employee.CompanyID); err != nil {
+		return err
+	}
 	return s.repo.DeleteEmployee(ctx, id)
 }
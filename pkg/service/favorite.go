@@ -46,7 +46,11 @@ func (s *FavoriteService) GetAllFavoriteParkingByUser(ctx context.Context, userI
 }
 
 func (s *FavoriteService) DeleteOne(ctx context.Context, id uuid.UUID) error {
-	if err := s.repo.DeleteOneFavorite(ctx, id, nil); err != nil {
+	principal, err := currentPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteOneFavorite(ctx, id, principal.ID, nil); err != nil {
 		return err
 	}
 	return nil
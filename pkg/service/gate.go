@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// checkInEarly is how long before its start a booking may check in
+const checkInEarly = 30 * time.Minute
+
+// GateProcedure checks in or out the car with the plate at the gate of the lot. Several matching tickets
+// come back as candidates with a 409, to retry with the chosen TicketId; no match is a 404 telling why.
+func (s *TicketService) GateProcedure(ctx context.Context, req model.GateProcedureReq, procedure string) (*model.GateProcedureRes, error) {
+	lotID := valid.UUID(req.ParkingLotId)
+	if err := authorizeParkingLot(ctx, s.repo, lotID); err != nil {
+		return nil, err
+	}
+	number := utils.NormalizePlate(valid.String(req.Number))
+	if number == "" {
+		return nil, ginext.NewError(http.StatusBadRequest, "Plate number is required")
+	}
+	tickets, err := s.repo.GetTicketsByPlate(ctx, number, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var matches []model.Ticket
+	for _, ticket := range tickets {
+		if !gateMatch(ticket, lotID, procedure, now) {
+			continue
+		}
+		if req.TicketId != nil && *req.TicketId != ticket.ID {
+			continue
+		}
+		matches = append(matches, ticket)
+	}
+
+	switch len(matches) {
+	case 0:
+		if req.TicketId != nil {
+			return nil, ginext.NewError(http.StatusNotFound, "Ticket does not match plate "+number+" at this gate")
+		}
+		return nil, ginext.NewError(http.StatusNotFound, explainNoMatch(tickets, lotID, procedure, number, now))
+	case 1:
+		ticket := matches[0]
+		checkout, err := s.runProcedure(ctx, &ticket, procedure)
+		if err != nil {
+			return nil, err
+		}
+		return &model.GateProcedureRes{Ticket: &ticket, Checkout: checkout}, nil
+	}
+	res := &model.GateProcedureRes{
+		Candidates: matches,
+		Message:    fmt.Sprintf("%d tickets match plate %s, pick one", len(matches), number),
+	}
+	return res, ginext.NewError(http.StatusConflict, "Several tickets match plate "+number)
+}
+
+// gateMatch reports whether the ticket can go through the procedure at the lot now
+func gateMatch(ticket model.Ticket, lotID uuid.UUID, procedure string, now time.Time) bool {
+	if valid.UUID(ticket.ParkingLotId) != lotID {
+		return false
+	}
+	switch procedure {
+	case model.PROCEDURE_CHECK_IN:
+		return ticket.State == model.TicketStateNew &&
+			!now.Before(valid.DayTime(ticket.StartTime).Add(-checkInEarly)) && now.Before(stayEnd(ticket))
+	case model.PROCEDURE_CHECK_OUT:
+		return ticket.State == model.TicketStateOngoing
+	}
+	return false
+}
+
+// explainNoMatch tells the gate why no ticket of the plate can go through the procedure at the lot
+func explainNoMatch(tickets []model.Ticket, lotID uuid.UUID, procedure, number string, now time.Time) string {
+	var here, elsewhere []model.Ticket
+	for _, ticket := range tickets {
+		if ticket.State == model.TicketStateCancel || ticket.State == model.TicketStateCompleted {
+			continue
+		}
+		if valid.UUID(ticket.ParkingLotId) == lotID {
+			here = append(here, ticket)
+		} else {
+			elsewhere = append(elsewhere, ticket)
+		}
+	}
+	if len(here) == 0 {
+		if len(elsewhere) > 0 && elsewhere[0].ParkingLot != nil {
+			return fmt.Sprintf("Booking of %s is at another parking lot: %s", number, elsewhere[0].ParkingLot.Name)
+		}
+		return "No booking found for plate " + number
+	}
+	for _, ticket := range here {
+		switch {
+		case procedure == model.PROCEDURE_CHECK_IN && ticket.State == model.TicketStateOngoing:
+			return fmt.Sprintf("Vehicle %s has already checked in", number)
+		case procedure == model.PROCEDURE_CHECK_OUT && ticket.State == model.TicketStateNew:
+			return fmt.Sprintf("Vehicle %s has not checked in yet", number)
+		case procedure == model.PROCEDURE_CHECK_IN && ticket.State == model.TicketStatePending:
+			return fmt.Sprintf("Booking of %s is not paid yet", number)
+		}
+	}
+	for _, ticket := range here {
+		if start := valid.DayTime(ticket.StartTime); ticket.State == model.TicketStateNew && now.Before(start.Add(-checkInEarly)) {
+			return fmt.Sprintf("Booking of %s starts at %s", number, start.Format(time.RFC3339))
+		}
+	}
+	if ticket := here[len(here)-1]; ticket.State == model.TicketStateExpired || !now.Before(stayEnd(ticket)) {
+		return fmt.Sprintf("Booking of %s expired at %s", number, stayEnd(ticket).Format(time.RFC3339))
+	}
+	return "No booking found for plate " + number
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// a series never books more occurrences than this
+const maxLongTermOccurrences = 366
+
+type occurrence struct {
+	start time.Time
+	end   time.Time
+}
+
+// createLongTermTicket books every occurrence of the series, or none of them
+func (s *TicketService) createLongTermTicket(ctx context.Context, req *model.TicketReq) (*model.Ticket, error) {
+	if req.VoucherCode != nil {
+		return nil, ginext.NewError(http.StatusBadRequest, "Vouchers only apply to single bookings")
+	}
+	occurrences, err := expandOccurrences(req)
+	if err != nil {
+		return nil, err
+	}
+	pricing, err := loadPricing(ctx, s.repo, valid.UUID(req.ParkingLotId))
+	if err != nil {
+		return nil, err
+	}
+
+	series := &model.LongTermTicket{
+		BaseModel: model.BaseModel{
+			CreatorID: req.UserId,
+			UpdaterID: req.UserId,
+		},
+		Type:          req.Type,
+		StartTime:     valid.DayTimePointer(occurrences[0].start),
+		EndTime:       valid.DayTimePointer(occurrences[len(occurrences)-1].end),
+		RepeatUntil:   req.RepeatUntil,
+		Interval:      req.Interval,
+		Weekdays:      joinWeekdays(req.Weekdays),
+		UserId:        req.UserId,
+		VehicleId:     req.VehicleId,
+		ParkingLotId:  req.ParkingLotId,
+		ParkingSlotId: req.ParkingSlotId,
+		TimeFrameId:   req.TimeFrameId,
+		State:         model.LONG_TERM_ACTIVE,
+	}
+	var tickets []*model.Ticket
+	for _, o := range occurrences {
+		quote, err := pricing.quote(o.start, o.end)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, &model.Ticket{
+			BaseModel: model.BaseModel{
+				CreatorID: req.UserId,
+				UpdaterID: req.UserId,
+			},
+			UserId:        req.UserId,
+			StartTime:     valid.DayTimePointer(o.start),
+			EndTime:       valid.DayTimePointer(o.end),
+			VehicleId:     req.VehicleId,
+			ParkingLotId:  req.ParkingLotId,
+			ParkingSlotId: req.ParkingSlotId,
+			TimeFrameId:   req.TimeFrameId,
+			Total:         quote.Total,
+			Price:         &quote,
+		})
+		series.Total += quote.Total
+	}
+	// the series is paid at once, its occurrences wait for the payment together
+	for _, ticket := range tickets {
+		awaitPayment(ticket, walletCost(series.Total, req.PayWithWallet), model.TicketStateNew)
+	}
+
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		if err := rp.CreateLongTermTicket(ctx, series, nil); err != nil {
+			return err
+		}
+		for _, ticket := range tickets {
+			ticket.LongTermTicketId = &series.ID
+			if err := bookParkingSlot(ctx, rp, ticket); err != nil {
+				if hasCode(err, http.StatusConflict) {
+					return ginext.NewError(http.StatusConflict, utils.MessageError()[http.StatusConflict]+
+						": "+valid.DayTime(ticket.StartTime).Format(time.RFC3339))
+				}
+				return err
+			}
+		}
+		if !req.PayWithWallet {
+			return nil
+		}
+		return spendWallet(ctx, rp, valid.UUID(series.UserId), series.Total, model.WalletTransaction{
+			LongTermTicketID: &series.ID,
+			Description:      "Thanh toán vé đỗ xe dài hạn",
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tickets[0].State == model.TicketStatePending {
+		tickets[0].Payment = s.payments.payFor(ctx, model.Payment{
+			UserID:           series.UserId,
+			LongTermTicketID: &series.ID,
+			Purpose:          model.PAYMENT_FOR_LONG_TERM,
+			Amount:           series.Total,
+		})
+	}
+	return tickets[0], nil
+}
+
+// expandOccurrences repeats the window of the request by its recurrence rule, up to RepeatUntil
+func expandOccurrences(req *model.TicketReq) ([]occurrence, error) {
+	start, end := valid.DayTime(req.StartTime), valid.DayTime(req.EndTime)
+	if !start.Before(end) {
+		return nil, ginext.NewError(http.StatusBadRequest, "Start time must be before end time")
+	}
+	if req.RepeatUntil == nil || req.RepeatUntil.Before(start) {
+		return nil, ginext.NewError(http.StatusBadRequest, "Repeat until must be after start time")
+	}
+
+	// match tells whether the series has an occurrence the given number of days after the first one
+	var match func(days int, day time.Time) bool
+	switch req.Type {
+	case model.LONG_TERM_DAILY:
+		match = func(int, time.Time) bool { return true }
+	case model.LONG_TERM_CYCLE:
+		if req.Interval <= 0 {
+			return nil, ginext.NewError(http.StatusBadRequest, "Interval must be greater than 0")
+		}
+		match = func(days int, _ time.Time) bool { return days%req.Interval == 0 }
+	case model.LONG_TERM_CUSTOM:
+		weekdays := map[time.Weekday]bool{}
+		for _, d := range req.Weekdays {
+			if d < 0 || d > 6 {
+				return nil, ginext.NewError(http.StatusBadRequest, "Weekdays must be between 0 (sunday) and 6")
+			}
+			weekdays[time.Weekday(d)] = true
+		}
+		if len(weekdays) == 0 {
+			return nil, ginext.NewError(http.StatusBadRequest, "Weekdays are required")
+		}
+		match = func(_ int, day time.Time) bool { return weekdays[day.Weekday()] }
+	default:
+		return nil, ginext.NewError(http.StatusBadRequest, "Wrong long term type")
+	}
+
+	var res []occurrence
+	for days := 0; ; days++ {
+		s := start.AddDate(0, 0, days)
+		if s.After(valid.DayTime(req.RepeatUntil)) {
+			break
+		}
+		if !match(days, s) {
+			continue
+		}
+		o := occurrence{start: s, end: end.AddDate(0, 0, days)}
+		if len(res) > 0 && o.start.Before(res[len(res)-1].end) {
+			return nil, ginext.NewError(http.StatusBadRequest, "Occurrences of the series overlap each other")
+		}
+		if len(res) == maxLongTermOccurrences {
+			return nil, ginext.NewError(http.StatusBadRequest, "Too many occurrences, max is "+strconv.Itoa(maxLongTermOccurrences))
+		}
+		res = append(res, o)
+	}
+	if len(res) == 0 {
+		return nil, ginext.NewError(http.StatusBadRequest, "Series has no occurrence")
+	}
+	return res, nil
+}
+
+func joinWeekdays(weekdays []int) string {
+	var res []string
+	for _, d := range weekdays {
+		res = append(res, strconv.Itoa(d))
+	}
+	return strings.Join(res, ",")
+}
+
+func (s *TicketService) GetListLongTermTicket(ctx context.Context, req model.ListLongTermTicketReq) ([]model.LongTermTicket, error) {
+	principal, err := currentPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.Is(utils.PRINCIPAL_ADMIN) {
+		req.UserId = valid.StringPointer(principal.ID.String())
+	}
+	return s.repo.GetListLongTermTicket(ctx, req, nil)
+}
+
+func (s *TicketService) GetOneLongTermTicket(ctx context.Context, id uuid.UUID) (model.LongTermTicket, error) {
+	series, err := s.repo.GetOneLongTermTicket(ctx, id, nil)
+	if err != nil {
+		return series, err
+	}
+	if err := authorizeTicket(ctx, s.repo, model.Ticket{UserId: series.UserId, ParkingLotId: series.ParkingLotId}); err != nil {
+		return series, err
+	}
+	return series, nil
+}
+
+// CancelLongTermTicket cancels every occurrence of the series that has not started yet, refunding each by the
+// cancellation policy of the lot. A single occurrence is cancelled like any other ticket.
+func (s *TicketService) CancelLongTermTicket(ctx context.Context, id uuid.UUID) (model.LongTermTicket, error) {
+	series, err := s.repo.GetOneLongTermTicket(ctx, id, nil)
+	if err != nil {
+		return series, err
+	}
+	if err := authorizeUser(ctx, valid.UUID(series.UserId)); err != nil {
+		return series, err
+	}
+	if series.State == model.LONG_TERM_CANCEL {
+		return series, ginext.NewError(http.StatusConflict, "Series is already cancelled")
+	}
+	now := time.Now()
+	var cancelled []model.Ticket
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		for i := range series.Tickets {
+			occurrence := &series.Tickets[i]
+			if occurrence.State != model.TicketStateNew && occurrence.State != model.TicketStatePending ||
+				!valid.DayTime(occurrence.StartTime).After(now) {
+				continue
+			}
+			percent, err := refundPercent(ctx, rp, *occurrence, now)
+			if err != nil {
+				return err
+			}
+			tickets, err := cancelTicket(ctx, rp, occurrence, percent)
+			if err != nil {
+				return err
+			}
+			cancelled = append(cancelled, tickets...)
+		}
+		series.State = model.LONG_TERM_CANCEL
+		return rp.UpdateLongTermTicket(ctx, &series, nil)
+	})
+	if err != nil {
+		return series, err
+	}
+	s.payments.refundTickets(ctx, cancelled)
+	return series, nil
+}
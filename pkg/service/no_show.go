@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"time"
+
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+const (
+	// key of the advisory lock held by the replica running the no-show worker
+	noShowLockKey   int64 = 7310001
+	noShowBatchSize       = 200
+
+	defaultNoShowInterval = time.Minute
+	defaultNoShowGrace    = 15 * time.Minute
+)
+
+// NoShowWorker expires new tickets that were not checked in within the grace period of their lot,
+// so they stop holding the slot
+type NoShowWorker struct {
+	repo     repo.PGInterface
+	interval time.Duration
+	grace    time.Duration
+}
+
+func NewNoShowWorker(repo repo.PGInterface, interval time.Duration, grace time.Duration) *NoShowWorker {
+	if interval <= 0 {
+		interval = defaultNoShowInterval
+	}
+	if grace <= 0 {
+		grace = defaultNoShowGrace
+	}
+	return &NoShowWorker{repo: repo, interval: interval, grace: grace}
+}
+
+// Run expires no-shows every interval until ctx is done. Replicas may all run it: a run is skipped while
+// another replica holds the lock.
+func (w *NoShowWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.expire(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *NoShowWorker) expire(ctx context.Context) {
+	log := logger.WithCtx(ctx, "NoShowWorker")
+	for {
+		expired := 0
+		err := w.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+			locked, err := rp.TryAdvisoryXactLock(ctx, noShowLockKey, nil)
+			if err != nil || !locked {
+				return err
+			}
+			tickets, err := rp.GetNoShowTickets(ctx, w.grace, noShowBatchSize, nil)
+			if err != nil {
+				return err
+			}
+			// a ticket that fails is skipped, so that it does not hold back the rest of the batch
+			for i := range tickets {
+				if err := rp.Savepoint(ctx, "no_show", func() error {
+					return expireTicket(ctx, rp, &tickets[i])
+				}); err != nil {
+					log.WithError(err).Errorf("Failed to expire no-show ticket %s", tickets[i].ID)
+					continue
+				}
+				expired++
+			}
+			return nil
+		})
+		if err != nil {
+			log.WithError(err).Error("Failed to expire no-show tickets")
+			return
+		}
+		if expired > 0 {
+			log.Infof("Expired %d no-show tickets", expired)
+		}
+		if expired < noShowBatchSize {
+			return
+		}
+	}
+}
+
+func expireTicket(ctx context.Context, rp repo.PGInterface, ticket *model.Ticket) error {
+	reason := "not checked in within the grace period"
+	if err := transitionTicket(ctx, rp, ticket, model.TicketStateExpired, &reason); err != nil {
+		return err
+	}
+	if err := transitionExtensions(ctx, rp, *ticket, model.TicketStateExpired, &reason); err != nil {
+		return err
+	}
+	if ticket.UserId == nil {
+		return nil
+	}
+	title := "Vé đỗ xe đã hết hạn"
+	return rp.CreateNotification(ctx, &model.Notification{
+		UserID:  ticket.UserId,
+		Title:   title,
+		Content: fmt.Sprintf("%s vì xe chưa vào bãi sau giờ bắt đầu %s", title, ticket.StartTime.Format("15:04 02/01/2006")),
+	}, nil)
+}
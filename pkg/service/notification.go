@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+
+	"github.com/google/uuid"
+)
+
+type NotificationService struct {
+	repo repo.PGInterface
+}
+
+func NewNotificationService(repo repo.PGInterface) NotificationInterface {
+	return &NotificationService{repo: repo}
+}
+
+type NotificationInterface interface {
+	GetListNotification(ctx context.Context, req model.ListNotificationReq) (model.ListNotificationRes, error)
+	ReadNotification(ctx context.Context, id uuid.UUID) error
+}
+
+func (s *NotificationService) GetListNotification(ctx context.Context, req model.ListNotificationReq) (model.ListNotificationRes, error) {
+	if err := scopeNotification(ctx, &req); err != nil {
+		return model.ListNotificationRes{}, err
+	}
+	return s.repo.GetListNotification(ctx, req)
+}
+
+func (s *NotificationService) ReadNotification(ctx context.Context, id uuid.UUID) error {
+	var req model.ListNotificationReq
+	if err := scopeNotification(ctx, &req); err != nil {
+		return err
+	}
+	return s.repo.MarkNotificationRead(ctx, id, req, nil)
+}
+
+// scopeNotification limits req to the notifications of the caller: their own for a user, their company's otherwise
+func scopeNotification(ctx context.Context, req *model.ListNotificationReq) error {
+	principal, err := currentPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	if principal.Is(utils.PRINCIPAL_USER) {
+		req.UserID = valid.StringPointer(principal.ID.String())
+		return nil
+	}
+	companyID, err := scopeCompanyID(ctx, uuid.Nil)
+	if err != nil {
+		return err
+	}
+	req.CompanyID = valid.StringPointer(companyID.String())
+	return nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/valid"
+	"time"
+)
+
+// extensionHeld reports whether the extension still counts, i.e. was paid and neither cancelled nor expired
+func extensionHeld(extension model.Ticket) bool {
+	return extension.State != model.TicketStateCancel && extension.State != model.TicketStateExpired &&
+		extension.State != model.TicketStatePending
+}
+
+// bookedEnd is the end of the ticket or of its last extension that still counts
+func bookedEnd(ticket model.Ticket, extensions []model.Ticket) time.Time {
+	end := valid.DayTime(ticket.EndTime)
+	for _, extension := range extensions {
+		if extensionHeld(extension) && valid.DayTime(extension.EndTime).After(end) {
+			end = valid.DayTime(extension.EndTime)
+		}
+	}
+	return end
+}
+
+// computeCheckout works out what the car of the ticket owes when it leaves at exit. The overstay after the
+// booked end, extensions included, is priced with the penalty of the lot if any, else with its time frames.
+// A walk-in ticket has no booked end and owes its whole stay.
+func computeCheckout(ctx context.Context, rp repo.PGInterface, ticket model.Ticket, exit time.Time) (model.CheckoutRes, error) {
+	res := model.CheckoutRes{
+		TicketID:  ticket.ID,
+		BookedEnd: valid.DayTime(ticket.EndTime),
+		ExitTime:  exit,
+		Paid:      ticket.Total,
+		Charges:   []model.TicketCharge{},
+	}
+	if ticket.EndTime == nil {
+		// walk-in: nothing was paid up front, the whole stay is due
+		charge, err := walkInCharge(ctx, rp, ticket, exit)
+		if err != nil {
+			return res, err
+		}
+		res.BookedEnd = exit
+		res.Charges = append(res.Charges, charge)
+		res.AmountDue = charge.Amount
+		return res, nil
+	}
+	extensions, err := rp.GetListExtendTicketByOrigin(ctx, ticket.ID.String(), nil)
+	if err != nil {
+		return res, err
+	}
+	for _, extension := range extensions {
+		if extensionHeld(extension) {
+			res.Paid += extension.Total
+		}
+	}
+	res.BookedEnd = bookedEnd(ticket, extensions)
+	if !exit.After(res.BookedEnd) {
+		return res, nil
+	}
+	overstay := exit.Sub(res.BookedEnd)
+	res.Overstay = int(math.Ceil(overstay.Minutes()))
+
+	var penalty model.OverstayPenalty
+	if _, err := loadSetting(ctx, rp, valid.UUID(ticket.ParkingLotId), model.SETTING_OVERSTAY_PENALTY, &penalty); err != nil {
+		return res, err
+	}
+	if overstay <= time.Duration(penalty.Grace)*time.Minute {
+		return res, nil
+	}
+
+	charge := model.TicketCharge{
+		TicketID:  ticket.ID,
+		Type:      model.CHARGE_OVERSTAY,
+		StartTime: valid.DayTimePointer(res.BookedEnd),
+		EndTime:   valid.DayTimePointer(exit),
+	}
+	if penalty.RatePerHour > 0 {
+		hours := math.Ceil(overstay.Hours())
+		charge.Amount = hours * penalty.RatePerHour
+		charge.Description = fmt.Sprintf("Overstay %d minutes, %.0f hours x %.0f", res.Overstay, hours, penalty.RatePerHour)
+	} else {
+		quote, err := quoteStay(ctx, rp, valid.UUID(ticket.ParkingLotId), res.BookedEnd, exit)
+		if err != nil {
+			return res, err
+		}
+		charge.Amount = quote.Total
+		charge.Description = fmt.Sprintf("Overstay %d minutes, priced with the time frames of the lot", res.Overstay)
+	}
+	res.Charges = append(res.Charges, charge)
+	res.AmountDue += charge.Amount
+	return res, nil
+}
@@ -31,10 +31,24 @@ type ParkingLotInterface interface {
 }
 
 func (s *ParkingLotService) GetListParkingLotCompany(ctx context.Context, req model.GetListParkingLotReq) (res model.ListParkingLotRes, err error) {
+	requested, _ := uuid.Parse(valid.String(req.CompanyID))
+	companyID, err := scopeCompanyID(ctx, requested)
+	if err != nil {
+		return res, err
+	}
+	if companyID != uuid.Nil {
+		req.CompanyID = valid.StringPointer(companyID.String())
+	}
 	return s.repo.GetListParkingLotCompany(ctx, req)
 }
 
 func (s *ParkingLotService) CreateParkingLot(ctx context.Context, req model.ParkingLotReq) (*model.ParkingLot, error) {
+	companyID, err := scopeCompanyID(ctx, valid.UUID(req.CompanyID))
+	if err != nil {
+		return nil, err
+	}
+	req.CompanyID = &companyID
+
 	ParkingLot := &model.ParkingLot{
 		Name:        valid.String(req.Name),
 		Description: valid.String(req.Description),
@@ -65,6 +79,11 @@ func (s *ParkingLotService) UpdateParkingLot(ctx context.Context, req model.Park
 	if err != nil {
 		return ParkingLot, err
 	}
+	if err := authorizeCompany(ctx, ParkingLot.CompanyID); err != nil {
+		return ParkingLot, err
+	}
+	// a lot can not be handed over to another company
+	req.CompanyID = nil
 
 	utils.Sync(req, &ParkingLot)
 	if err := s.repo.UpdateParkingLot(ctx, &ParkingLot); err != nil {
@@ -80,23 +99,20 @@ func (s *ParkingLotService) ChangeParkingLotStatus(ctx context.Context, req mode
 		return ParkingLot, err
 	}
 
-	if req.Status != nil && *req.Status == ParkingLot.Status {
-		return ParkingLot, nil
-	}
-
-	utils.Sync(req, &ParkingLot)
-	if err := s.repo.UpdateParkingLot(ctx, &ParkingLot); err != nil {
-		return ParkingLot, err
-	}
-
-	return ParkingLot, nil
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		return changeParkingLotStatus(ctx, rp, &ParkingLot, valid.String(req.Status), valid.String(req.Reason))
+	})
+	return ParkingLot, err
 }
 
 func (s *ParkingLotService) UpdateParkingLotV2(ctx context.Context, req model.UpdateParkingLotReq) (model.ParkingLot, error) {
-	ParkingLot, err := s.repo.GetOneParkingLot(ctx, *req.ID)
+	ParkingLot, err := s.repo.GetOneParkingLot(ctx, valid.UUID(req.ID))
 	if err != nil {
 		return ParkingLot, err
 	}
+	if err := authorizeCompany(ctx, ParkingLot.CompanyID); err != nil {
+		return ParkingLot, err
+	}
 
 	parkingLot := model.ParkingLot{
 		BaseModel:   model.BaseModel{ID: *req.ID},
@@ -113,24 +129,53 @@ func (s *ParkingLotService) UpdateParkingLotV2(ctx context.Context, req model.Up
 	var newBlocks []model.Block
 	var newTimeFrames []model.TimeFrame
 
+	// blocks and time frames with an id must already be of this lot, else they would be moved into it
+	ownBlocks := map[uuid.UUID]bool{}
+	for _, block := range ParkingLot.Blocks {
+		ownBlocks[block.ID] = true
+	}
+	ownTimeFrames := map[uuid.UUID]bool{}
+	for _, timeFrame := range ParkingLot.TimeFrames {
+		ownTimeFrames[timeFrame.ID] = true
+	}
+
 	for _, block := range req.Blocks {
 		block.ParkingLotID = *req.ID
 
 		if block.ID == uuid.Nil {
 			newBlocks = append(newBlocks, block)
+		} else if !ownBlocks[block.ID] {
+			return ParkingLot, errForbidden()
 		}
 		parkingLot.Blocks = append(parkingLot.Blocks, block)
 	}
 
 	for _, timeFrame := range req.TimeFrames {
+		if err := validateTimeFrame(timeFrame.Duration); err != nil {
+			return ParkingLot, err
+		}
 		timeFrame.ParkingLotId = *req.ID
 
 		if timeFrame.ID == uuid.Nil {
 			newTimeFrames = append(newTimeFrames, timeFrame)
+		} else if !ownTimeFrames[timeFrame.ID] {
+			return ParkingLot, errForbidden()
 		}
 		parkingLot.TimeFrames = append(parkingLot.TimeFrames, timeFrame)
 	}
 
+	if req.PricingRules != nil {
+		if err := validatePricingRules(req.PricingRules); err != nil {
+			return ParkingLot, err
+		}
+		parkingLot.PricingRules = []model.PricingRule{}
+		for _, rule := range req.PricingRules {
+			rule.ID = uuid.Nil
+			rule.ParkingLotId = *req.ID
+			parkingLot.PricingRules = append(parkingLot.PricingRules, rule)
+		}
+	}
+
 	if err := s.repo.UpdateParkingLotV2(ctx, parkingLot, newTimeFrames, newBlocks); err != nil {
 		return ParkingLot, err
 	}
@@ -144,6 +189,9 @@ func (s *ParkingLotService) UpdateParkingLotV2(ctx context.Context, req model.Up
 }
 
 func (s *ParkingLotService) DeleteParkingLot(ctx context.Context, id uuid.UUID) error {
+	if err := authorizeParkingLot(ctx, s.repo, id); err != nil {
+		return err
+	}
 	return s.repo.DeleteParkingLot(ctx, id)
 }
 
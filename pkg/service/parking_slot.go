@@ -27,6 +27,9 @@ type ParkingSlotInterface interface {
 }
 
 func (s *ParkingSlotService) CreateParkingSlot(ctx context.Context, req model.ParkingSlotReq) (*model.ParkingSlot, error) {
+	if err := authorizeBlock(ctx, s.repo, valid.UUID(req.BlockID)); err != nil {
+		return nil, err
+	}
 	ParkingSlot := &model.ParkingSlot{
 		Name:        valid.String(req.Name),
 		Description: valid.String(req.Description),
@@ -76,6 +79,14 @@ func (s *ParkingSlotService) UpdateParkingSlot(ctx context.Context, req model.Pa
 	if err != nil {
 		return ParkingSlot, err
 	}
+	if err := authorizeBlock(ctx, s.repo, ParkingSlot.BlockID); err != nil {
+		return ParkingSlot, err
+	}
+	if req.BlockID != nil && *req.BlockID != ParkingSlot.BlockID {
+		if err := authorizeBlock(ctx, s.repo, *req.BlockID); err != nil {
+			return ParkingSlot, err
+		}
+	}
 
 	utils.Sync(req, &ParkingSlot)
 	if err := s.repo.UpdateParkingSlot(ctx, &ParkingSlot); err != nil {
@@ -86,5 +97,8 @@ func (s *ParkingSlotService) UpdateParkingSlot(ctx context.Context, req model.Pa
 }
 
 func (s *ParkingSlotService) DeleteParkingSlot(ctx context.Context, id uuid.UUID) error {
+	if err := authorizeParkingSlot(ctx, s.repo, id); err != nil {
+		return err
+	}
 	return s.repo.DeleteParkingSlot(ctx, id)
 }
@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"parking-server/pkg/client"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/valid"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+const (
+	defaultPaymentHold     = 15 * time.Minute
+	defaultPaymentCurrency = "VND"
+	maxMockPaymentDelay    = 5 * time.Minute
+)
+
+type PaymentService struct {
+	repo     repo.PGInterface
+	provider client.PaymentProvider
+	currency string
+	hold     time.Duration
+}
+
+func NewPaymentService(repo repo.PGInterface, provider client.PaymentProvider, currency string, hold time.Duration) *PaymentService {
+	if currency == "" {
+		currency = defaultPaymentCurrency
+	}
+	if hold <= 0 {
+		hold = defaultPaymentHold
+	}
+	return &PaymentService{repo: repo, provider: provider, currency: currency, hold: hold}
+}
+
+type PaymentServiceInterface interface {
+	CreatePayment(ctx context.Context, req model.CreatePaymentReq) (model.Payment, error)
+	GetOnePayment(ctx context.Context, id uuid.UUID) (model.Payment, error)
+	HandleWebhook(ctx context.Context, provider string, header http.Header, body []byte) error
+	SimulateMockPayment(ctx context.Context, ref string, outcome string, delay time.Duration) (model.Payment, error)
+}
+
+// awaitPayment puts a ticket that costs something on hold until it is paid, a free one goes straight to paidState
+func awaitPayment(ticket *model.Ticket, cost float64, paidState model.TicketState) {
+	if cost > 0 {
+		ticket.State = model.TicketStatePending
+		ticket.PaymentStatus = model.TICKET_UNPAID
+		return
+	}
+	ticket.State = paidState
+	ticket.PaymentStatus = model.TICKET_PAID
+}
+
+// walletCost is what is left to pay through the provider, nothing when the wallet pays with the booking
+func walletCost(cost float64, payWithWallet bool) float64 {
+	if payWithWallet {
+		return 0
+	}
+	return cost
+}
+
+// payFor asks the provider for the payment of tickets just booked. On failure the tickets stay pending and
+// the payment can be asked again with CreatePayment until the hold runs out.
+func (s *PaymentService) payFor(ctx context.Context, payment model.Payment) *model.Payment {
+	if payment.Amount <= 0 {
+		return nil
+	}
+	if err := s.requestPayment(ctx, &payment); err != nil {
+		logger.WithCtx(ctx, "PaymentService").WithError(err).Error("Failed to create payment")
+		return nil
+	}
+	return &payment
+}
+
+func (s *PaymentService) requestPayment(ctx context.Context, payment *model.Payment) error {
+	payment.ID = uuid.New()
+	payment.Provider = s.provider.Name()
+	payment.Currency = s.currency
+	payment.Status = model.PAYMENT_PENDING
+	payment.ExpiredAt = valid.DayTimePointer(time.Now().Add(s.hold))
+
+	intent, err := s.provider.CreateIntent(ctx, client.PaymentIntentReq{
+		PaymentID:   payment.ID,
+		Amount:      payment.Amount,
+		Currency:    payment.Currency,
+		Description: "Parkar " + payment.Purpose,
+		ExpiredAt:   *payment.ExpiredAt,
+	})
+	if err != nil {
+		return ginext.NewError(http.StatusBadGateway, "Payment provider error: "+err.Error())
+	}
+	payment.ProviderRef = intent.ProviderRef
+	payment.PayURL = intent.PayURL
+	payment.QRCode = intent.QRCode
+	return s.repo.CreatePayment(ctx, payment, nil)
+}
+
+// CreatePayment asks again for the payment of a ticket or a series still pending, e.g. after a failed one
+func (s *PaymentService) CreatePayment(ctx context.Context, req model.CreatePaymentReq) (model.Payment, error) {
+	payment := model.Payment{}
+	switch {
+	case req.TicketId != nil && req.LongTermTicketId == nil:
+		ticket, err := s.repo.GetOneTicket(ctx, req.TicketId.String(), nil)
+		if err != nil {
+			return payment, err
+		}
+		if err := authorizeUser(ctx, valid.UUID(ticket.UserId)); err != nil {
+			return payment, err
+		}
+		if ticket.State != model.TicketStatePending {
+			return payment, ginext.NewError(http.StatusConflict, "Ticket is not waiting for payment")
+		}
+		extension, err := s.repo.IsTicketExtension(ctx, ticket.ID, nil)
+		if err != nil {
+			return payment, err
+		}
+		payment.Purpose = model.PAYMENT_FOR_BOOKING
+		if extension {
+			payment.Purpose = model.PAYMENT_FOR_EXTENSION
+		}
+		payment.UserID = ticket.UserId
+		payment.TicketID = &ticket.ID
+		payment.Amount = ticket.Total
+	case req.LongTermTicketId != nil && req.TicketId == nil:
+		series, err := s.repo.GetOneLongTermTicket(ctx, *req.LongTermTicketId, nil)
+		if err != nil {
+			return payment, err
+		}
+		if err := authorizeUser(ctx, valid.UUID(series.UserId)); err != nil {
+			return payment, err
+		}
+		for _, occurrence := range series.Tickets {
+			if occurrence.State == model.TicketStatePending {
+				payment.Amount += occurrence.Total
+			}
+		}
+		if payment.Amount <= 0 {
+			return payment, ginext.NewError(http.StatusConflict, "Series is not waiting for payment")
+		}
+		payment.Purpose = model.PAYMENT_FOR_LONG_TERM
+		payment.UserID = series.UserId
+		payment.LongTermTicketID = &series.ID
+	default:
+		return payment, ginext.NewError(http.StatusBadRequest, "Either ticketId or longTermTicketId is required")
+	}
+	if err := s.requestPayment(ctx, &payment); err != nil {
+		return payment, err
+	}
+	return payment, nil
+}
+
+func (s *PaymentService) GetOnePayment(ctx context.Context, id uuid.UUID) (model.Payment, error) {
+	payment, err := s.repo.GetOnePayment(ctx, id, nil)
+	if err != nil {
+		return payment, err
+	}
+	if err := authorizeUser(ctx, valid.UUID(payment.UserID)); err != nil {
+		return model.Payment{}, err
+	}
+	return payment, nil
+}
+
+// HandleWebhook applies an event of the provider. Events already applied are acknowledged and skipped, so the
+// provider may deliver them again. Money that comes in after the hold of its tickets ran out is refunded, or
+// left due for the payment hold worker to refund when the provider fails.
+func (s *PaymentService) HandleWebhook(ctx context.Context, provider string, header http.Header, body []byte) error {
+	log := logger.WithCtx(ctx, "PaymentService")
+	if provider != s.provider.Name() {
+		return ginext.NewError(http.StatusNotFound, "Unknown payment provider "+provider)
+	}
+	event, err := s.provider.ParseWebhook(ctx, header, body)
+	if err != nil {
+		log.WithError(err).Error("error_401: rejected payment webhook")
+		return ginext.NewError(http.StatusUnauthorized, "Invalid webhook: "+err.Error())
+	}
+
+	var late *model.Payment
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		payment, err := rp.GetPaymentByRef(ctx, provider, event.ProviderRef, nil)
+		if err != nil {
+			return err
+		}
+		applied, err := rp.HasPaymentTransaction(ctx, event.EventID, nil)
+		if err != nil || applied {
+			return err
+		}
+		payload := string(body)
+		if err := rp.CreatePaymentTransaction(ctx, &model.PaymentTransaction{
+			PaymentID: payment.ID,
+			EventID:   event.EventID,
+			Type:      event.Type,
+			Status:    event.Status,
+			Amount:    event.Amount,
+			Payload:   &payload,
+		}, nil); err != nil {
+			return err
+		}
+		if event.Type != model.PAYMENT_TX_CHARGE ||
+			(payment.Status != model.PAYMENT_PENDING && payment.Status != model.PAYMENT_FAILED) {
+			return nil
+		}
+		if event.Status != model.PAYMENT_SUCCEEDED || event.Amount < payment.Amount {
+			payment.Status = model.PAYMENT_FAILED
+			return rp.UpdatePayment(ctx, &payment, nil)
+		}
+		payment.Status = model.PAYMENT_SUCCEEDED
+		if err := rp.UpdatePayment(ctx, &payment, nil); err != nil {
+			return err
+		}
+		if payment.Purpose == model.PAYMENT_FOR_TOP_UP {
+			return creditTopUp(ctx, rp, payment)
+		}
+		confirmed, err := confirmTickets(ctx, rp, payment)
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			return nil
+		}
+		// recorded with the payment, so that the refund is retried by the payment hold worker if it fails now
+		payment.RefundDue = payment.Amount
+		late = &payment
+		return rp.UpdatePayment(ctx, &payment, nil)
+	})
+	if err != nil {
+		return err
+	}
+	if late != nil {
+		log.Warnf("Payment %s came in after the hold of its tickets, refunding it", late.ID)
+		if err := s.refundPayment(ctx, *late, late.RefundDue); err != nil {
+			log.WithError(err).Errorf("Failed to refund payment %s, it will be retried", late.ID)
+		}
+	}
+	return nil
+}
+
+// confirmTickets moves the pending tickets of a paid payment on. It reports false when none was still pending.
+func confirmTickets(ctx context.Context, rp repo.PGInterface, payment model.Payment) (bool, error) {
+	var tickets []model.Ticket
+	switch {
+	case payment.TicketID != nil:
+		ticket, err := rp.GetOneTicket(ctx, payment.TicketID.String(), nil)
+		if err != nil {
+			return false, err
+		}
+		tickets = append(tickets, ticket)
+	case payment.LongTermTicketID != nil:
+		series, err := rp.GetOneLongTermTicket(ctx, *payment.LongTermTicketID, nil)
+		if err != nil {
+			return false, err
+		}
+		tickets = series.Tickets
+	}
+	to := model.TicketStateNew
+	if payment.Purpose == model.PAYMENT_FOR_EXTENSION {
+		to = model.TicketStateExtend
+	}
+	reason := "paid"
+	confirmed := false
+	for i := range tickets {
+		if tickets[i].State != model.TicketStatePending {
+			continue
+		}
+		tickets[i].PaymentStatus = model.TICKET_PAID
+		err := transitionTicket(ctx, rp, &tickets[i], to, &reason)
+		if hasCode(err, http.StatusConflict) {
+			// expired by the hold worker meanwhile
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		confirmed = true
+	}
+	return confirmed, nil
+}
+
+// refundTickets gives back the refund of cancelled tickets that were paid, to the wallet when they were paid
+// from it. The cancellation is already committed, failures are logged for the staff to settle by hand.
+func (s *PaymentService) refundTickets(ctx context.Context, tickets []model.Ticket) {
+	log := logger.WithCtx(ctx, "PaymentService")
+	for _, ticket := range tickets {
+		if ticket.Refund <= 0 || ticket.PaymentStatus != model.TICKET_PAID {
+			continue
+		}
+		spend, err := s.repo.GetWalletSpend(ctx, ticket.ID, ticket.LongTermTicketId, nil)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to look up the wallet spend of ticket %s", ticket.ID)
+			continue
+		}
+		if spend != nil {
+			if err := s.refundToWallet(ctx, *spend, ticket); err != nil {
+				log.WithError(err).Errorf("Failed to refund ticket %s to the wallet", ticket.ID)
+			}
+			continue
+		}
+		payment, err := s.repo.GetPaidPayment(ctx, ticket.ID, ticket.LongTermTicketId, nil)
+		if err != nil || payment == nil {
+			log.WithError(err).Errorf("No payment to refund ticket %s from", ticket.ID)
+			continue
+		}
+		if err := s.refundPayment(ctx, *payment, ticket.Refund); err != nil {
+			log.WithError(err).Errorf("Failed to refund ticket %s", ticket.ID)
+			continue
+		}
+		if err := s.repo.UpdateTicketPaymentStatus(ctx, []uuid.UUID{ticket.ID}, model.TICKET_REFUNDED, nil); err != nil {
+			log.WithError(err).Errorf("Failed to mark ticket %s refunded", ticket.ID)
+		}
+	}
+}
+
+// refundToWallet credits the refund of a ticket paid from the wallet back to it
+func (s *PaymentService) refundToWallet(ctx context.Context, spend model.WalletTransaction, ticket model.Ticket) error {
+	return s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		if _, err := moveWallet(ctx, rp, spend.WalletID, ticket.Refund, model.LEDGER_ACCOUNT_PARKING, model.WalletTransaction{
+			Type:             model.WALLET_TX_REFUND,
+			TicketID:         &ticket.ID,
+			LongTermTicketID: ticket.LongTermTicketId,
+			Description:      "Hoàn tiền vé đỗ xe đã huỷ",
+		}); err != nil {
+			return err
+		}
+		return rp.UpdateTicketPaymentStatus(ctx, []uuid.UUID{ticket.ID}, model.TICKET_REFUNDED, nil)
+	})
+}
+
+// refundPayment gives back amount of the payment, never more than what is left of it
+func (s *PaymentService) refundPayment(ctx context.Context, payment model.Payment, amount float64) error {
+	amount = math.Min(amount, payment.Amount-payment.Refunded)
+	if amount <= 0 {
+		return nil
+	}
+	event, err := s.provider.Refund(ctx, payment.ProviderRef, amount)
+	if err != nil {
+		return ginext.NewError(http.StatusBadGateway, "Payment provider error: "+err.Error())
+	}
+	return s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		payment, err := rp.GetPaymentByRef(ctx, payment.Provider, payment.ProviderRef, nil)
+		if err != nil {
+			return err
+		}
+		applied, err := rp.HasPaymentTransaction(ctx, event.EventID, nil)
+		if err != nil || applied {
+			return err
+		}
+		if err := rp.CreatePaymentTransaction(ctx, &model.PaymentTransaction{
+			PaymentID: payment.ID,
+			EventID:   event.EventID,
+			Type:      model.PAYMENT_TX_REFUND,
+			Status:    event.Status,
+			Amount:    event.Amount,
+		}, nil); err != nil {
+			return err
+		}
+		if event.Status != model.PAYMENT_SUCCEEDED {
+			return nil
+		}
+		payment.Refunded += event.Amount
+		payment.RefundDue = math.Max(0, payment.RefundDue-event.Amount)
+		if payment.Refunded >= payment.Amount {
+			payment.Status = model.PAYMENT_REFUNDED
+		}
+		return rp.UpdatePayment(ctx, &payment, nil)
+	})
+}
+
+// SimulateMockPayment is the pay page of the mock provider: the payment succeeds or fails after delay
+func (s *PaymentService) SimulateMockPayment(ctx context.Context, ref string, outcome string, delay time.Duration) (model.Payment, error) {
+	mock, ok := s.provider.(*client.MockPaymentProvider)
+	if !ok {
+		return model.Payment{}, ginext.NewError(http.StatusNotFound, "Mock payments are disabled")
+	}
+	if outcome == "" {
+		outcome = client.MOCK_OUTCOME_SUCCESS
+	}
+	if outcome != client.MOCK_OUTCOME_SUCCESS && outcome != client.MOCK_OUTCOME_FAIL {
+		return model.Payment{}, ginext.NewError(http.StatusBadRequest, "Outcome must be success or fail")
+	}
+	if delay < 0 || delay > maxMockPaymentDelay {
+		return model.Payment{}, ginext.NewError(http.StatusBadRequest, "Delay must be between 0 and 300 seconds")
+	}
+	payment, err := s.repo.GetPaymentByRef(ctx, mock.Name(), ref, nil)
+	if err != nil {
+		return payment, err
+	}
+	if err := mock.Simulate(ctx, ref, outcome, delay, payment.Amount); err != nil {
+		return payment, err
+	}
+	return payment, nil
+}
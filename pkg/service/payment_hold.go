@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"time"
+
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+const (
+	// keys of the advisory locks held by the replica expiring unpaid bookings and by the one retrying refunds
+	paymentHoldLockKey   int64 = 7310002
+	refundLockKey        int64 = 7310004
+	paymentHoldBatchSize       = 200
+)
+
+// PaymentHoldWorker expires the bookings that were not paid within the hold, so they stop holding the slot.
+// It also retries the refunds of late payments the provider failed to give back.
+type PaymentHoldWorker struct {
+	repo     repo.PGInterface
+	payments *PaymentService
+	interval time.Duration
+	hold     time.Duration
+}
+
+func NewPaymentHoldWorker(repo repo.PGInterface, payments *PaymentService, interval time.Duration, hold time.Duration) *PaymentHoldWorker {
+	if interval <= 0 {
+		interval = defaultNoShowInterval
+	}
+	if hold <= 0 {
+		hold = defaultPaymentHold
+	}
+	return &PaymentHoldWorker{repo: repo, payments: payments, interval: interval, hold: hold}
+}
+
+// Run expires unpaid bookings and retries due refunds every interval until ctx is done, on one replica at a time
+func (w *PaymentHoldWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.expire(ctx)
+		w.refund(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *PaymentHoldWorker) expire(ctx context.Context) {
+	log := logger.WithCtx(ctx, "PaymentHoldWorker")
+	for {
+		expired := 0
+		err := w.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+			locked, err := rp.TryAdvisoryXactLock(ctx, paymentHoldLockKey, nil)
+			if err != nil || !locked {
+				return err
+			}
+			tickets, err := rp.GetUnpaidTickets(ctx, w.hold, paymentHoldBatchSize, nil)
+			if err != nil {
+				return err
+			}
+			// a ticket that fails is skipped, so that it does not hold back the rest of the batch
+			for i := range tickets {
+				if err := rp.Savepoint(ctx, "payment_hold", func() error {
+					return expireUnpaidTicket(ctx, rp, &tickets[i])
+				}); err != nil {
+					log.WithError(err).Errorf("Failed to expire unpaid ticket %s", tickets[i].ID)
+					continue
+				}
+				expired++
+			}
+			return nil
+		})
+		if err != nil {
+			log.WithError(err).Error("Failed to expire unpaid tickets")
+			return
+		}
+		if expired > 0 {
+			log.Infof("Expired %d unpaid tickets", expired)
+		}
+		if expired < paymentHoldBatchSize {
+			return
+		}
+	}
+}
+
+// refund retries the refunds due for at least an interval, which leaves the first attempt to the webhook.
+// The lock is held over the calls to the provider, so two replicas never refund the same payment.
+func (w *PaymentHoldWorker) refund(ctx context.Context) {
+	log := logger.WithCtx(ctx, "PaymentHoldWorker")
+	err := w.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		locked, err := rp.TryAdvisoryXactLock(ctx, refundLockKey, nil)
+		if err != nil || !locked {
+			return err
+		}
+		payments, err := rp.GetRefundDuePayments(ctx, time.Now().Add(-w.interval), paymentHoldBatchSize, nil)
+		if err != nil {
+			return err
+		}
+		for _, payment := range payments {
+			if err := w.payments.refundPayment(ctx, payment, payment.RefundDue); err != nil {
+				log.WithError(err).Errorf("Failed to refund payment %s", payment.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to retry due refunds")
+	}
+}
+
+func expireUnpaidTicket(ctx context.Context, rp repo.PGInterface, ticket *model.Ticket) error {
+	reason := "not paid within the hold"
+	if err := transitionTicket(ctx, rp, ticket, model.TicketStateExpired, &reason); err != nil {
+		return err
+	}
+	if err := releaseVoucher(ctx, rp, *ticket); err != nil {
+		return err
+	}
+	if ticket.UserId == nil || ticket.LongTermTicketId != nil {
+		// the occurrences of a series would flood the user
+		return nil
+	}
+	return rp.CreateNotification(ctx, &model.Notification{
+		UserID:  ticket.UserId,
+		Title:   "Vé đỗ xe đã bị huỷ",
+		Content: "Vé đỗ xe đã bị huỷ vì chưa được thanh toán kịp thời hạn",
+	}, nil)
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/utils"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+func errForbidden() error {
+	return ginext.NewError(http.StatusForbidden, utils.MessageError()[http.StatusForbidden])
+}
+
+// hasCode reports whether err is an api error with the given http code
+func hasCode(err error, code int) bool {
+	var apiErr ginext.ApiError
+	return errors.As(err, &apiErr) && apiErr.Code() == code
+}
+
+func currentPrincipal(ctx context.Context) (*utils.Principal, error) {
+	principal, ok := utils.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+	}
+	return principal, nil
+}
+
+// authorizeUser allows admins and the user owning the record
+func authorizeUser(ctx context.Context, userID uuid.UUID) error {
+	principal, err := currentPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	if principal.Is(utils.PRINCIPAL_ADMIN) {
+		return nil
+	}
+	if principal.Is(utils.PRINCIPAL_USER) && principal.ID == userID {
+		return nil
+	}
+	return errForbidden()
+}
+
+// authorizeCompany allows admins, the company itself and its employees
+func authorizeCompany(ctx context.Context, companyID uuid.UUID) error {
+	principal, err := currentPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	if principal.Is(utils.PRINCIPAL_ADMIN) {
+		return nil
+	}
+	if principal.Is(utils.PRINCIPAL_COMPANY, utils.PRINCIPAL_EMPLOYEE) && principal.CompanyID == companyID {
+		return nil
+	}
+	return errForbidden()
+}
+
+// scopeCompanyID returns the company a merchant principal is bound to, requested is only honored for admins
+func scopeCompanyID(ctx context.Context, requested uuid.UUID) (uuid.UUID, error) {
+	principal, err := currentPrincipal(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if principal.Is(utils.PRINCIPAL_COMPANY, utils.PRINCIPAL_EMPLOYEE) {
+		return principal.CompanyID, nil
+	}
+	if principal.Is(utils.PRINCIPAL_ADMIN) {
+		return requested, nil
+	}
+	return uuid.Nil, errForbidden()
+}
+
+func authorizeParkingLot(ctx context.Context, rp repo.PGInterface, parkingLotID uuid.UUID) error {
+	lot, err := rp.GetOneParkingLot(ctx, parkingLotID)
+	if err != nil {
+		return err
+	}
+	return authorizeCompany(ctx, lot.CompanyID)
+}
+
+func authorizeBlock(ctx context.Context, rp repo.PGInterface, blockID uuid.UUID) error {
+	block, err := rp.GetOneBlock(ctx, blockID)
+	if err != nil {
+		return err
+	}
+	return authorizeParkingLot(ctx, rp, block.ParkingLotID)
+}
+
+func authorizeParkingSlot(ctx context.Context, rp repo.PGInterface, slotID uuid.UUID) error {
+	slot, err := rp.GetOneParkingSlot(ctx, slotID)
+	if err != nil {
+		return err
+	}
+	return authorizeBlock(ctx, rp, slot.BlockID)
+}
+
+// authorizeTicket allows the driver owning the ticket, and the staff of the lot it belongs to
+func authorizeTicket(ctx context.Context, rp repo.PGInterface, ticket model.Ticket) error {
+	principal, err := currentPrincipal(ctx)
+	if err != nil {
+		return err
+	}
+	if principal.Is(utils.PRINCIPAL_USER) {
+		if ticket.UserId != nil && *ticket.UserId == principal.ID {
+			return nil
+		}
+		return errForbidden()
+	}
+	if ticket.ParkingLotId == nil {
+		return authorizeCompany(ctx, uuid.Nil)
+	}
+	return authorizeParkingLot(ctx, rp, *ticket.ParkingLotId)
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/valid"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+const (
+	// unit of TimeFrame.Duration
+	timeFrameUnit = time.Hour
+	// no stay is priced longer than this, the price table grows with the stay
+	maxStay = 366 * 24 * time.Hour
+	// longest time frame, in timeFrameUnit, a lot may price with
+	maxTimeFrameDuration = int(maxStay / timeFrameUnit)
+
+	dateLayout  = "2006-01-02"
+	clockLayout = "15:04"
+)
+
+// quoteStay prices a stay at a parking lot with the time frames and pricing rules of the lot
+func quoteStay(ctx context.Context, rp repo.PGInterface, parkingLotID uuid.UUID, start, end time.Time) (model.PriceQuote, error) {
+	pricing, err := loadPricing(ctx, rp, parkingLotID)
+	if err != nil {
+		return model.PriceQuote{}, err
+	}
+	return pricing.quote(start, end)
+}
+
+// lotPricing is what a lot prices its stays with
+type lotPricing struct {
+	parkingLotID uuid.UUID
+	frames       []model.TimeFrame
+	rules        []model.PricingRule // highest priority first
+	holidays     map[string]bool
+	dailyCap     float64
+}
+
+func loadPricing(ctx context.Context, rp repo.PGInterface, parkingLotID uuid.UUID) (lotPricing, error) {
+	pricing := lotPricing{parkingLotID: parkingLotID, holidays: map[string]bool{}}
+	frames, err := rp.GetAllTimeFrame(ctx, model.GetListTimeFrameParam{ParkingLotId: valid.StringPointer(parkingLotID.String())}, nil)
+	if err != nil {
+		return pricing, err
+	}
+	pricing.frames = frames.Data
+	if pricing.rules, err = rp.GetPricingRules(ctx, parkingLotID, nil); err != nil {
+		return pricing, err
+	}
+	holidays := model.Holidays{}
+	if _, err := loadSetting(ctx, rp, parkingLotID, model.SETTING_HOLIDAYS, &holidays); err != nil {
+		return pricing, err
+	}
+	for _, date := range holidays.Dates {
+		pricing.holidays[date] = true
+	}
+	dailyCap := model.DailyCap{}
+	if _, err := loadSetting(ctx, rp, parkingLotID, model.SETTING_DAILY_CAP, &dailyCap); err != nil {
+		return pricing, err
+	}
+	pricing.dailyCap = dailyCap.Amount
+	return pricing, nil
+}
+
+// quote prices the stay with the time frames, then splits it at midnights and at the edges of the rules: each
+// segment takes its share of the time frame price by duration, scaled by the rule covering it. The daily cap
+// bounds what the segments of one day add up to.
+func (p lotPricing) quote(start, end time.Time) (model.PriceQuote, error) {
+	quote, err := priceStay(p.frames, start, end)
+	if err != nil {
+		return quote, err
+	}
+	quote.ParkingLotId = p.parkingLotID
+	if len(p.rules) == 0 && p.dailyCap <= 0 {
+		return quote, nil
+	}
+
+	base, stay := quote.Total, float64(end.Sub(start))
+	quote.Total = 0
+	var day string
+	dayTotal := 0.0
+	closeDay := func() {
+		if p.dailyCap > 0 && dayTotal > p.dailyCap {
+			quote.Capped += dayTotal - p.dailyCap
+			dayTotal = p.dailyCap
+		}
+		quote.Total += dayTotal
+		dayTotal = 0
+	}
+	for _, segment := range p.split(start, end) {
+		if d := segment.StartTime.Format(dateLayout); d != day {
+			closeDay()
+			day = d
+		}
+		share := base * float64(segment.EndTime.Sub(segment.StartTime)) / stay
+		segment.Amount = roundAmount(share * segment.Multiplier)
+		dayTotal += segment.Amount
+		quote.Segments = append(quote.Segments, segment)
+	}
+	closeDay()
+	quote.Capped = roundAmount(quote.Capped)
+	quote.Total = roundAmount(quote.Total)
+	return quote, nil
+}
+
+// split cuts [start, end) in the local time at every midnight and every edge of a rule window, then merges the
+// neighbouring pieces of a day left under the same rule
+func (p lotPricing) split(start, end time.Time) []model.PriceSegment {
+	start, end = start.In(time.Local), end.In(time.Local)
+	cuts := []time.Time{start, end}
+	for day := dayStart(start); day.Before(end); day = day.AddDate(0, 0, 1) {
+		cuts = append(cuts, day)
+		for _, rule := range p.rules {
+			from, to, ok := ruleWindow(rule)
+			if !ok {
+				continue
+			}
+			cuts = append(cuts, day.Add(time.Duration(from)*time.Minute), day.Add(time.Duration(to)*time.Minute))
+		}
+	}
+	sort.Slice(cuts, func(a, b int) bool { return cuts[a].Before(cuts[b]) })
+
+	var segments []model.PriceSegment
+	for i := 1; i < len(cuts); i++ {
+		from, to := cuts[i-1], cuts[i]
+		if !from.Before(to) || from.Before(start) || to.After(end) {
+			continue
+		}
+		rule := p.ruleAt(from)
+		segment := model.PriceSegment{StartTime: from, EndTime: to, Multiplier: 1}
+		if rule != nil {
+			segment.PricingRuleId = &rule.ID
+			segment.Multiplier = rule.Multiplier
+		}
+		if n := len(segments); n > 0 {
+			last := &segments[n-1]
+			if last.EndTime.Equal(from) && sameRule(last.PricingRuleId, segment.PricingRuleId) &&
+				last.StartTime.Format(dateLayout) == from.Format(dateLayout) {
+				last.EndTime = to
+				continue
+			}
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// ruleAt is the rule of highest priority covering t, nil if none does
+func (p lotPricing) ruleAt(t time.Time) *model.PricingRule {
+	holiday := p.holidays[t.Format(dateLayout)]
+	minute := t.Hour()*60 + t.Minute()
+	for i, rule := range p.rules {
+		if rule.Holiday != holiday {
+			continue
+		}
+		if !rule.Holiday && rule.Weekdays != "" && !containsWeekday(rule.Weekdays, int(t.Weekday())) {
+			continue
+		}
+		if from, to, ok := ruleWindow(rule); ok {
+			inside := minute >= from && minute < to
+			if to < from {
+				inside = minute >= from || minute < to
+			}
+			if !inside {
+				continue
+			}
+		}
+		return &p.rules[i]
+	}
+	return nil
+}
+
+// ruleWindow returns the window of the rule in minutes of the day, ok is false for a rule of the whole day
+func ruleWindow(rule model.PricingRule) (from, to int, ok bool) {
+	if rule.StartTime == "" && rule.EndTime == "" {
+		return 0, 0, false
+	}
+	start, err := time.Parse(clockLayout, rule.StartTime)
+	if err != nil {
+		return 0, 0, false
+	}
+	end, err := time.Parse(clockLayout, rule.EndTime)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), true
+}
+
+// validateTimeFrame checks the duration of a time frame a merchant saves on a lot
+func validateTimeFrame(duration int) error {
+	if duration <= 0 || duration > maxTimeFrameDuration {
+		return ginext.NewError(http.StatusBadRequest, "Duration of time frame must be between 1 and "+strconv.Itoa(maxTimeFrameDuration)+" hours")
+	}
+	return nil
+}
+
+// validatePricingRules checks the rules a merchant saves on a lot
+func validatePricingRules(rules []model.PricingRule) error {
+	for _, rule := range rules {
+		if (rule.StartTime == "") != (rule.EndTime == "") {
+			return ginext.NewError(http.StatusBadRequest, "Pricing rule needs both startTime and endTime, or neither")
+		}
+		if rule.StartTime != "" {
+			if _, err := time.Parse(clockLayout, rule.StartTime); err != nil {
+				return ginext.NewError(http.StatusBadRequest, "Invalid startTime of pricing rule: "+rule.StartTime)
+			}
+			if _, err := time.Parse(clockLayout, rule.EndTime); err != nil {
+				return ginext.NewError(http.StatusBadRequest, "Invalid endTime of pricing rule: "+rule.EndTime)
+			}
+			if rule.StartTime == rule.EndTime {
+				return ginext.NewError(http.StatusBadRequest, "Pricing rule window must not be empty")
+			}
+		}
+		if rule.Weekdays != "" {
+			for _, d := range strings.Split(rule.Weekdays, ",") {
+				if n, err := strconv.Atoi(strings.TrimSpace(d)); err != nil || n < 0 || n > 6 {
+					return ginext.NewError(http.StatusBadRequest, "Weekdays of pricing rule must be between 0 and 6")
+				}
+			}
+		}
+		if rule.Multiplier <= 0 {
+			return ginext.NewError(http.StatusBadRequest, "Multiplier of pricing rule must be positive")
+		}
+	}
+	return nil
+}
+
+func containsWeekday(weekdays string, day int) bool {
+	for _, d := range strings.Split(weekdays, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(d)); err == nil && n == day {
+			return true
+		}
+	}
+	return false
+}
+
+func sameRule(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// priceStay picks the cheapest combination of time frames whose durations add up to at least the stay.
+// Any started unit is billed in full.
+func priceStay(frames []model.TimeFrame, start, end time.Time) (model.PriceQuote, error) {
+	if !start.Before(end) {
+		return model.PriceQuote{}, ginext.NewError(http.StatusBadRequest, "Start time must be before end time")
+	}
+	if end.Sub(start) > maxStay {
+		return model.PriceQuote{}, ginext.NewError(http.StatusBadRequest, "Stay must not be longer than a year")
+	}
+	var usable []model.TimeFrame
+	longest := 0
+	for _, frame := range frames {
+		// frames saved before their duration was checked may be longer than any stay
+		if frame.Duration <= 0 || frame.Duration > maxTimeFrameDuration || frame.Cost < 0 {
+			continue
+		}
+		usable = append(usable, frame)
+		if frame.Duration > longest {
+			longest = frame.Duration
+		}
+	}
+	if len(usable) == 0 {
+		return model.PriceQuote{}, ginext.NewError(http.StatusBadRequest, "Parking lot has no time frame")
+	}
+
+	units := int(math.Ceil(float64(end.Sub(start)) / float64(timeFrameUnit)))
+	// cost[i] is the cheapest price of covering exactly i units, last[i] the frame used last to get there.
+	// Covering more than the stay may be cheaper, but never by more than the longest frame.
+	size := units + longest
+	cost := make([]float64, size+1)
+	last := make([]int, size+1)
+	for i := 1; i <= size; i++ {
+		cost[i] = math.Inf(1)
+		last[i] = -1
+		for j, frame := range usable {
+			if frame.Duration <= i && cost[i-frame.Duration]+frame.Cost < cost[i] {
+				cost[i] = cost[i-frame.Duration] + frame.Cost
+				last[i] = j
+			}
+		}
+	}
+	best := -1
+	for i := units; i <= size; i++ {
+		if last[i] >= 0 && (best < 0 || cost[i] < cost[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return model.PriceQuote{}, ginext.NewError(http.StatusBadRequest, "Time frames of the parking lot cannot cover the stay")
+	}
+
+	quantity := map[int]int{}
+	for i := best; i > 0; i -= usable[last[i]].Duration {
+		quantity[last[i]]++
+	}
+	quote := model.PriceQuote{StartTime: start, EndTime: end, Duration: best}
+	for j, n := range quantity {
+		frame := usable[j]
+		quote.Items = append(quote.Items, model.PriceItem{
+			TimeFrameId: frame.ID,
+			Duration:    frame.Duration,
+			Cost:        frame.Cost,
+			Quantity:    n,
+			Amount:      frame.Cost * float64(n),
+		})
+		quote.Total += frame.Cost * float64(n)
+	}
+	sort.Slice(quote.Items, func(a, b int) bool {
+		return quote.Items[a].Duration > quote.Items[b].Duration
+	})
+	return quote, nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"math"
+	"net/http"
+	"parking-server/pkg/model"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPriceStay(t *testing.T) {
+	hourly := model.TimeFrame{BaseModel: model.BaseModel{ID: uuid.New()}, Duration: 1, Cost: 10000}
+	threeHours := model.TimeFrame{BaseModel: model.BaseModel{ID: uuid.New()}, Duration: 3, Cost: 25000}
+	daily := model.TimeFrame{BaseModel: model.BaseModel{ID: uuid.New()}, Duration: 24, Cost: 100000}
+	frames := []model.TimeFrame{hourly, threeHours, daily, {Duration: 0, Cost: 0}}
+	start := time.Date(2026, 10, 21, 8, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		frames   []model.TimeFrame
+		stay     time.Duration
+		total    float64
+		duration int
+		code     int
+	}{
+		{name: "started hour billed in full", frames: frames, stay: 90 * time.Minute, total: 20000, duration: 2},
+		{name: "longer frame cheaper", frames: frames, stay: 3 * time.Hour, total: 25000, duration: 3},
+		{name: "covering more than the stay is cheaper", frames: frames, stay: 20 * time.Hour, total: 100000, duration: 24},
+		{name: "frames combined", frames: frames, stay: 26 * time.Hour, total: 120000, duration: 26},
+		{name: "only the frames of the lot", frames: []model.TimeFrame{threeHours}, stay: 4 * time.Hour, total: 50000, duration: 6},
+		{name: "frame longer than a year left out", frames: []model.TimeFrame{hourly, {Duration: math.MaxInt32, Cost: 1}}, stay: 2 * time.Hour, total: 20000, duration: 2},
+		{name: "no time frame", frames: []model.TimeFrame{{Duration: 0, Cost: 10}}, stay: time.Hour, code: http.StatusBadRequest},
+		{name: "empty stay", frames: frames, stay: 0, code: http.StatusBadRequest},
+		{name: "stay longer than a year", frames: frames, stay: 400 * 24 * time.Hour, code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := priceStay(tt.frames, start, start.Add(tt.stay))
+			if tt.code != 0 {
+				if !hasCode(err, tt.code) {
+					t.Fatalf("got error %v, want a %d", err, tt.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if quote.Total != tt.total || quote.Duration != tt.duration {
+				t.Errorf("got total %v for %d units, want %v for %d", quote.Total, quote.Duration, tt.total, tt.duration)
+			}
+			sum := 0.0
+			for _, item := range quote.Items {
+				sum += item.Amount
+			}
+			if sum != quote.Total {
+				t.Errorf("items add up to %v, want the total %v", sum, quote.Total)
+			}
+		})
+	}
+}
+
+func TestValidateTimeFrame(t *testing.T) {
+	tests := []struct {
+		duration int
+		valid    bool
+	}{
+		{1, true},
+		{24, true},
+		{maxTimeFrameDuration, true},
+		{0, false},
+		{-1, false},
+		{maxTimeFrameDuration + 1, false},
+		{math.MaxInt32, false},
+	}
+	for _, tt := range tests {
+		err := validateTimeFrame(tt.duration)
+		if tt.valid && err != nil {
+			t.Errorf("duration %d: got %v, want it accepted", tt.duration, err)
+		}
+		if !tt.valid && !hasCode(err, http.StatusBadRequest) {
+			t.Errorf("duration %d: got %v, want a 400", tt.duration, err)
+		}
+	}
+}
+
+func TestLotPricingQuote(t *testing.T) {
+	hourly := []model.TimeFrame{{Duration: 1, Cost: 10000}}
+	rule := func(weekdays string, holiday bool, from, to string, multiplier float64) model.PricingRule {
+		return model.PricingRule{
+			BaseModel:  model.BaseModel{ID: uuid.New()},
+			Weekdays:   weekdays,
+			Holiday:    holiday,
+			StartTime:  from,
+			EndTime:    to,
+			Multiplier: multiplier,
+		}
+	}
+	// 2026-10-21 is a wednesday
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, 10, day, hour, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name     string
+		pricing  lotPricing
+		start    time.Time
+		end      time.Time
+		total    float64
+		capped   float64
+		segments int
+	}{
+		{
+			name:    "no rule",
+			pricing: lotPricing{frames: hourly},
+			start:   at(21, 8), end: at(21, 10),
+			total: 20000,
+		},
+		{
+			name:    "rush hour",
+			pricing: lotPricing{frames: hourly, rules: []model.PricingRule{rule("", false, "07:00", "09:00", 1.5)}},
+			start:   at(21, 6), end: at(21, 10),
+			total: 50000, segments: 3,
+		},
+		{
+			name:    "weekend rule from midnight",
+			pricing: lotPricing{frames: hourly, rules: []model.PricingRule{rule("0,6", false, "", "", 2)}},
+			start:   at(23, 22), end: at(24, 2),
+			total: 60000, segments: 2,
+		},
+		{
+			name: "holiday rule instead of the weekday one",
+			pricing: lotPricing{frames: hourly, holidays: map[string]bool{"2026-10-21": true}, rules: []model.PricingRule{
+				rule("", true, "", "", 2),
+				rule("", false, "", "", 3),
+			}},
+			start: at(21, 10), end: at(21, 12),
+			total: 40000, segments: 1,
+		},
+		{
+			name:    "window past midnight",
+			pricing: lotPricing{frames: hourly, rules: []model.PricingRule{rule("", false, "22:00", "06:00", 0.5)}},
+			start:   at(21, 20), end: at(22, 8),
+			total: 80000, segments: 4,
+		},
+		{
+			name: "highest priority first",
+			pricing: lotPricing{frames: hourly, rules: []model.PricingRule{
+				rule("", false, "07:00", "09:00", 2),
+				rule("", false, "", "", 1.5),
+			}},
+			start: at(21, 8), end: at(21, 10),
+			total: 35000, segments: 2,
+		},
+		{
+			name:    "daily cap",
+			pricing: lotPricing{frames: hourly, dailyCap: 50000},
+			start:   at(21, 8), end: at(22, 2),
+			total: 70000, capped: 110000, segments: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := tt.pricing.quote(tt.start, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if quote.Total != tt.total || quote.Capped != tt.capped {
+				t.Errorf("got total %v capped %v, want %v capped %v", quote.Total, quote.Capped, tt.total, tt.capped)
+			}
+			if len(quote.Segments) != tt.segments {
+				t.Errorf("got %d segments, want %d", len(quote.Segments), tt.segments)
+			}
+			for i, segment := range quote.Segments {
+				if i > 0 && !segment.StartTime.Equal(quote.Segments[i-1].EndTime) {
+					t.Errorf("segment %d starts at %v, want the end of the previous one", i, segment.StartTime)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePricingRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  model.PricingRule
+		valid bool
+	}{
+		{name: "whole day", rule: model.PricingRule{Multiplier: 1.2}, valid: true},
+		{name: "window", rule: model.PricingRule{StartTime: "22:00", EndTime: "06:00", Weekdays: "1, 2", Multiplier: 0.5}, valid: true},
+		{name: "start without end", rule: model.PricingRule{StartTime: "07:00", Multiplier: 1}},
+		{name: "invalid clock", rule: model.PricingRule{StartTime: "7h", EndTime: "09:00", Multiplier: 1}},
+		{name: "empty window", rule: model.PricingRule{StartTime: "07:00", EndTime: "07:00", Multiplier: 1}},
+		{name: "weekday out of range", rule: model.PricingRule{Weekdays: "7", Multiplier: 1}},
+		{name: "no multiplier", rule: model.PricingRule{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePricingRules([]model.PricingRule{tt.rule})
+			if tt.valid && err != nil {
+				t.Errorf("got %v, want the rule accepted", err)
+			}
+			if !tt.valid && !hasCode(err, http.StatusBadRequest) {
+				t.Errorf("got %v, want a 400", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// settingValidators lists the settings a company may set on its lots, with the check of their value
+var settingValidators = map[string]func(raw []byte) error{
+	model.SETTING_NO_SHOW_GRACE_PERIOD: func(raw []byte) error {
+		var v model.NoShowGracePeriod
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if v.Minutes < 0 {
+			return errors.New("minutes must not be negative")
+		}
+		return nil
+	},
+	model.SETTING_OVERSTAY_PENALTY: func(raw []byte) error {
+		var v model.OverstayPenalty
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if v.RatePerHour < 0 || v.Grace < 0 {
+			return errors.New("rate and grace must not be negative")
+		}
+		return nil
+	},
+	model.SETTING_CANCELLATION_POLICY: func(raw []byte) error {
+		var v model.CancellationPolicy
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if v.FreeBefore < 0 {
+			return errors.New("freeBefore must not be negative")
+		}
+		for _, tier := range v.Tiers {
+			if tier.Before < 0 || tier.Before > v.FreeBefore || tier.Percent < 0 || tier.Percent > 100 {
+				return errors.New("tiers must start before freeBefore and refund between 0 and 100 percent")
+			}
+		}
+		return nil
+	},
+	model.SETTING_HOLIDAYS: func(raw []byte) error {
+		var v model.Holidays
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		for _, date := range v.Dates {
+			if _, err := time.Parse(dateLayout, date); err != nil {
+				return errors.New("dates must be formatted as " + dateLayout)
+			}
+		}
+		return nil
+	},
+	model.SETTING_DAILY_CAP: func(raw []byte) error {
+		var v model.DailyCap
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if v.Amount < 0 {
+			return errors.New("amount must not be negative")
+		}
+		return nil
+	},
+}
+
+// companySettingValidators lists the company wide settings, only set by the platform
+var companySettingValidators = map[string]func(raw []byte) error{
+	model.SETTING_PLATFORM_COMMISSION: func(raw []byte) error {
+		var v model.PlatformCommission
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if v.Percent < 0 || v.Percent > 100 {
+			return errors.New("percent must be between 0 and 100")
+		}
+		return nil
+	},
+}
+
+type SettingService struct {
+	repo repo.PGInterface
+}
+
+func NewSettingService(repo repo.PGInterface) SettingInterface {
+	return &SettingService{repo: repo}
+}
+
+type SettingInterface interface {
+	GetParkingLotSetting(ctx context.Context, parkingLotID uuid.UUID, key string) (model.Setting, error)
+	UpdateParkingLotSetting(ctx context.Context, parkingLotID uuid.UUID, key string, value json.RawMessage) (model.Setting, error)
+	GetCompanySetting(ctx context.Context, companyID uuid.UUID, key string) (model.Setting, error)
+	UpdateCompanySetting(ctx context.Context, companyID uuid.UUID, key string, value json.RawMessage) (model.Setting, error)
+}
+
+func (s *SettingService) GetParkingLotSetting(ctx context.Context, parkingLotID uuid.UUID, key string) (model.Setting, error) {
+	if _, ok := settingValidators[key]; !ok {
+		return model.Setting{}, ginext.NewError(http.StatusBadRequest, "Unknown setting "+key)
+	}
+	if err := authorizeParkingLot(ctx, s.repo, parkingLotID); err != nil {
+		return model.Setting{}, err
+	}
+	return s.repo.GetSetting(ctx, parkingLotID, key, nil)
+}
+
+func (s *SettingService) UpdateParkingLotSetting(ctx context.Context, parkingLotID uuid.UUID, key string, value json.RawMessage) (model.Setting, error) {
+	validate, ok := settingValidators[key]
+	if !ok {
+		return model.Setting{}, ginext.NewError(http.StatusBadRequest, "Unknown setting "+key)
+	}
+	if err := validate(value); err != nil {
+		return model.Setting{}, ginext.NewError(http.StatusBadRequest, "Invalid setting: "+err.Error())
+	}
+	lot, err := s.repo.GetOneParkingLot(ctx, parkingLotID)
+	if err != nil {
+		return model.Setting{}, err
+	}
+	if err := authorizeCompany(ctx, lot.CompanyID); err != nil {
+		return model.Setting{}, err
+	}
+	setting := model.Setting{
+		CompanyId:    lot.CompanyID,
+		ParkingLotId: parkingLotID,
+		Key:          key,
+		Value:        pgtype.JSONB{Bytes: value, Status: pgtype.Present},
+	}
+	if err := s.repo.SaveSetting(ctx, &setting, nil); err != nil {
+		return model.Setting{}, err
+	}
+	return setting, nil
+}
+
+func (s *SettingService) GetCompanySetting(ctx context.Context, companyID uuid.UUID, key string) (model.Setting, error) {
+	if _, ok := companySettingValidators[key]; !ok {
+		return model.Setting{}, ginext.NewError(http.StatusBadRequest, "Unknown setting "+key)
+	}
+	if err := authorizeCompany(ctx, companyID); err != nil {
+		return model.Setting{}, err
+	}
+	return s.repo.GetCompanySetting(ctx, companyID, key, nil)
+}
+
+// UpdateCompanySetting saves a company wide setting; the route lets only admins reach it
+func (s *SettingService) UpdateCompanySetting(ctx context.Context, companyID uuid.UUID, key string, value json.RawMessage) (model.Setting, error) {
+	validate, ok := companySettingValidators[key]
+	if !ok {
+		return model.Setting{}, ginext.NewError(http.StatusBadRequest, "Unknown setting "+key)
+	}
+	if err := validate(value); err != nil {
+		return model.Setting{}, ginext.NewError(http.StatusBadRequest, "Invalid setting: "+err.Error())
+	}
+	if _, err := s.repo.GetOneCompany(ctx, companyID); err != nil {
+		return model.Setting{}, err
+	}
+	setting := model.Setting{
+		CompanyId: companyID,
+		Key:       key,
+		Value:     pgtype.JSONB{Bytes: value, Status: pgtype.Present},
+	}
+	if err := s.repo.SaveSetting(ctx, &setting, nil); err != nil {
+		return model.Setting{}, err
+	}
+	return setting, nil
+}
+
+// loadSetting decodes the setting of the lot into out. It reports false when the lot has no such setting.
+func loadSetting(ctx context.Context, rp repo.PGInterface, parkingLotID uuid.UUID, key string, out interface{}) (bool, error) {
+	setting, err := rp.GetSetting(ctx, parkingLotID, key, nil)
+	if err != nil {
+		if hasCode(err, http.StatusNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := json.Unmarshal(setting.Value.Bytes, out); err != nil {
+		return false, ginext.NewError(http.StatusInternalServerError, "Invalid setting "+key+": "+err.Error())
+	}
+	return true, nil
+}
+
+// loadCompanySetting decodes the company wide setting into out. It reports false when the company has none.
+func loadCompanySetting(ctx context.Context, rp repo.PGInterface, companyID uuid.UUID, key string, out interface{}) (bool, error) {
+	setting, err := rp.GetCompanySetting(ctx, companyID, key, nil)
+	if err != nil {
+		if hasCode(err, http.StatusNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := json.Unmarshal(setting.Value.Bytes, out); err != nil {
+		return false, ginext.NewError(http.StatusInternalServerError, "Invalid setting "+key+": "+err.Error())
+	}
+	return true, nil
+}
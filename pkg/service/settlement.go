@@ -0,0 +1,238 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/valid"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+const (
+	// key of the advisory lock held by the replica closing the statements
+	settlementLockKey  int64 = 7310003
+	settlementInterval       = time.Hour
+)
+
+type SettlementService struct {
+	repo     repo.PGInterface
+	currency string
+}
+
+func NewSettlementService(repo repo.PGInterface, currency string) SettlementInterface {
+	if currency == "" {
+		currency = defaultPaymentCurrency
+	}
+	return &SettlementService{repo: repo, currency: currency}
+}
+
+type SettlementInterface interface {
+	GetListSettlement(ctx context.Context, req model.ListSettlementReq) (model.ListSettlementRes, error)
+	GetOneSettlement(ctx context.Context, id uuid.UUID) (model.Settlement, error)
+	GetCurrentSettlement(ctx context.Context, companyID uuid.UUID) (model.Settlement, error)
+	ExportSettlement(ctx context.Context, id uuid.UUID) ([]byte, string, error)
+}
+
+func (s *SettlementService) GetListSettlement(ctx context.Context, req model.ListSettlementReq) (model.ListSettlementRes, error) {
+	requested, _ := uuid.Parse(valid.String(req.CompanyID))
+	companyID, err := scopeCompanyID(ctx, requested)
+	if err != nil {
+		return model.ListSettlementRes{}, err
+	}
+	if companyID != uuid.Nil {
+		req.CompanyID = valid.StringPointer(companyID.String())
+	}
+	return s.repo.GetListSettlement(ctx, req, nil)
+}
+
+func (s *SettlementService) GetOneSettlement(ctx context.Context, id uuid.UUID) (model.Settlement, error) {
+	settlement, err := s.repo.GetOneSettlement(ctx, id, nil)
+	if err != nil {
+		return settlement, err
+	}
+	if err := authorizeCompany(ctx, settlement.CompanyID); err != nil {
+		return model.Settlement{}, err
+	}
+	return settlement, nil
+}
+
+// GetCurrentSettlement computes the statement of the running month so far, it is not saved
+func (s *SettlementService) GetCurrentSettlement(ctx context.Context, companyID uuid.UUID) (model.Settlement, error) {
+	companyID, err := scopeCompanyID(ctx, companyID)
+	if err != nil {
+		return model.Settlement{}, err
+	}
+	if companyID == uuid.Nil {
+		return model.Settlement{}, ginext.NewError(http.StatusBadRequest, "companyId is required")
+	}
+	start := monthStart(time.Now())
+	return buildSettlement(ctx, s.repo, companyID, start, start.AddDate(0, 1, 0), s.currency)
+}
+
+// ExportSettlement renders a statement and its lines as CSV, with the file name to download it as
+func (s *SettlementService) ExportSettlement(ctx context.Context, id uuid.UUID) ([]byte, string, error) {
+	settlement, err := s.GetOneSettlement(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	amount := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	rows := [][]string{
+		{"occurred_at", "type", "ticket_id", "parking_lot_id", "description", "amount"},
+	}
+	for _, line := range settlement.Lines {
+		ticketID, lotID := "", ""
+		if line.TicketID != nil {
+			ticketID = line.TicketID.String()
+		}
+		if line.ParkingLotID != nil {
+			lotID = line.ParkingLotID.String()
+		}
+		rows = append(rows, []string{line.OccurredAt.Format(time.RFC3339), line.Type, ticketID, lotID, line.Description, amount(line.Amount)})
+	}
+	rows = append(rows,
+		[]string{},
+		[]string{"bookings", amount(settlement.Bookings)},
+		[]string{"extensions", amount(settlement.Extensions)},
+		[]string{"charges", amount(settlement.Charges)},
+		[]string{"refunds", amount(-settlement.Refunds)},
+		[]string{"gross", amount(settlement.Gross)},
+		[]string{"commission", amount(-settlement.Commission)},
+		[]string{"payout", amount(settlement.Payout)},
+		[]string{"currency", settlement.Currency},
+	)
+	if err := w.WriteAll(rows); err != nil {
+		return nil, "", ginext.NewError(http.StatusInternalServerError, err.Error())
+	}
+	name := fmt.Sprintf("settlement-%s-%s.csv", settlement.CompanyID, settlement.PeriodStart.Format("2006-01"))
+	return buf.Bytes(), name, nil
+}
+
+// buildSettlement sums what the company earned in [start, end) and takes the commission of the platform off it
+func buildSettlement(ctx context.Context, rp repo.PGInterface, companyID uuid.UUID, start, end time.Time, currency string) (model.Settlement, error) {
+	settlement := model.Settlement{
+		CompanyID:   companyID,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Status:      model.SETTLEMENT_OPEN,
+		Currency:    currency,
+	}
+	lines, err := rp.GetSettlementLines(ctx, companyID, start, end, nil)
+	if err != nil {
+		return settlement, err
+	}
+	for _, line := range lines {
+		switch line.Type {
+		case model.SETTLEMENT_LINE_BOOKING:
+			settlement.Bookings += line.Amount
+		case model.SETTLEMENT_LINE_EXTENSION:
+			settlement.Extensions += line.Amount
+		case model.SETTLEMENT_LINE_CHARGE:
+			settlement.Charges += line.Amount
+		case model.SETTLEMENT_LINE_REFUND:
+			settlement.Refunds -= line.Amount
+		}
+	}
+	settlement.Gross = settlement.Bookings + settlement.Extensions + settlement.Charges - settlement.Refunds
+
+	commission := model.PlatformCommission{}
+	if _, err := loadCompanySetting(ctx, rp, companyID, model.SETTING_PLATFORM_COMMISSION, &commission); err != nil {
+		return settlement, err
+	}
+	settlement.CommissionPercent = commission.Percent
+	if settlement.Gross > 0 && commission.Percent > 0 {
+		settlement.Commission = math.Round(settlement.Gross*commission.Percent) / 100
+		lines = append(lines, model.SettlementLine{
+			Type:        model.SETTLEMENT_LINE_COMMISSION,
+			OccurredAt:  end,
+			Amount:      -settlement.Commission,
+			Description: fmt.Sprintf("Platform commission %g%%", commission.Percent),
+		})
+	}
+	settlement.Payout = settlement.Gross - settlement.Commission
+	settlement.Lines = lines
+	return settlement, nil
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// SettlementWorker closes the statement of every active company once its month is over. A closed statement
+// is saved with its lines and never recomputed, whatever happens to its tickets later.
+type SettlementWorker struct {
+	repo     repo.PGInterface
+	currency string
+}
+
+func NewSettlementWorker(repo repo.PGInterface, currency string) *SettlementWorker {
+	if currency == "" {
+		currency = defaultPaymentCurrency
+	}
+	return &SettlementWorker{repo: repo, currency: currency}
+}
+
+// Run closes the months that are over every hour until ctx is done, on one replica at a time
+func (w *SettlementWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(settlementInterval)
+	defer ticker.Stop()
+	for {
+		w.close(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *SettlementWorker) close(ctx context.Context, now time.Time) {
+	log := logger.WithCtx(ctx, "SettlementWorker")
+	closed := 0
+	err := w.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		locked, err := rp.TryAdvisoryXactLock(ctx, settlementLockKey, nil)
+		if err != nil || !locked {
+			return err
+		}
+		dues, err := rp.GetSettlementsDue(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, due := range dues {
+			// a company is settled from the month it was created, each month once it is over
+			for start := monthStart(due.Since.In(now.Location())); !start.AddDate(0, 1, 0).After(now); start = start.AddDate(0, 1, 0) {
+				settlement, err := buildSettlement(ctx, rp, due.CompanyID, start, start.AddDate(0, 1, 0), w.currency)
+				if err != nil {
+					return err
+				}
+				settlement.Status = model.SETTLEMENT_CLOSED
+				settlement.ClosedAt = valid.DayTimePointer(now)
+				if err := rp.CreateSettlement(ctx, &settlement, nil); err != nil {
+					return err
+				}
+				closed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to close settlements")
+		return
+	}
+	if closed > 0 {
+		log.Infof("Closed %d settlements", closed)
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+const (
+	STATUS_ENTITY_COMPANY     = "company"
+	STATUS_ENTITY_PARKING_LOT = "parking_lot"
+)
+
+var statuses = map[string]bool{"active": true, "inactive": true, "pending": true, "rejected": true}
+
+var statusLabels = map[string]string{
+	"active":   "đã được duyệt",
+	"inactive": "đã bị tạm ngưng",
+	"pending":  "đang chờ duyệt",
+	"rejected": "đã bị từ chối",
+}
+
+// changeCompanyStatus updates the status, records the change and notifies the company, rp should be a transaction
+func changeCompanyStatus(ctx context.Context, rp repo.PGInterface, company *model.Company, status string, reason string) error {
+	if !statuses[status] {
+		return ginext.NewError(http.StatusBadRequest, "Invalid status")
+	}
+	if company.Status == status {
+		return nil
+	}
+	from := company.Status
+	company.Status = status
+	if err := rp.UpdateCompany(ctx, company); err != nil {
+		return err
+	}
+	title := fmt.Sprintf("Tài khoản %s %s", company.Name, statusLabels[status])
+	return recordStatusChange(ctx, rp, STATUS_ENTITY_COMPANY, company.ID, company.ID, from, status, reason, title)
+}
+
+func changeParkingLotStatus(ctx context.Context, rp repo.PGInterface, lot *model.ParkingLot, status string, reason string) error {
+	if !statuses[status] {
+		return ginext.NewError(http.StatusBadRequest, "Invalid status")
+	}
+	if lot.Status == status {
+		return nil
+	}
+	from := lot.Status
+	lot.Status = status
+	if err := rp.UpdateParkingLot(ctx, lot); err != nil {
+		return err
+	}
+	title := fmt.Sprintf("Bãi đỗ xe %s %s", lot.Name, statusLabels[status])
+	return recordStatusChange(ctx, rp, STATUS_ENTITY_PARKING_LOT, lot.ID, lot.CompanyID, from, status, reason, title)
+}
+
+func recordStatusChange(ctx context.Context, rp repo.PGInterface, entityType string, entityID uuid.UUID, companyID uuid.UUID,
+	from string, to string, reason string, title string) error {
+	history := &model.StatusHistory{
+		EntityType: entityType,
+		EntityID:   entityID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+	}
+	if principal, err := currentPrincipal(ctx); err == nil {
+		history.ActorID = &principal.ID
+		history.CreatorID = &principal.ID
+	}
+	if err := rp.CreateStatusHistory(ctx, history, nil); err != nil {
+		return err
+	}
+
+	content := title
+	if reason != "" {
+		content = fmt.Sprintf("%s. Lý do: %s", title, reason)
+	}
+	return rp.CreateNotification(ctx, &model.Notification{
+		CompanyID: &companyID,
+		Title:     title,
+		Content:   content,
+	}, nil)
+}
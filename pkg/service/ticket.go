@@ -6,37 +6,104 @@ import (
 	"net/http"
 	"parking-server/pkg/model"
 	"parking-server/pkg/repo"
+	"parking-server/pkg/utils"
 	"parking-server/pkg/valid"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"gitlab.com/goxp/cloud0/ginext"
 	"gorm.io/gorm"
 )
 
+// bookingClockSkew is how long before now a booking may start, for clients whose clock runs late
+const bookingClockSkew = time.Minute
+
 type TicketService struct {
-	repo repo.PGInterface
+	repo     repo.PGInterface
+	payments *PaymentService
 }
 
-func NewTicketService(repo repo.PGInterface) TicketServiceInterface {
-	return &TicketService{repo: repo}
+func NewTicketService(repo repo.PGInterface, payments *PaymentService) TicketServiceInterface {
+	return &TicketService{repo: repo, payments: payments}
 }
 
 type TicketServiceInterface interface {
 	CreateTicket(ctx context.Context, req *model.TicketReq) (*model.Ticket, error)
 	ProcedureWithTicket(ctx context.Context, req *model.ProcedureReq) (bool, error)
-	ExtendTicket(ctx context.Context, req *model.ExtendTicketReq) (*model.TicketExtend, error)
-	GetAllTicket(ctx context.Context, req model.GetListTicketParam) ([]model.Ticket, error)
+	ExtendTicket(ctx context.Context, req *model.ExtendTicketReq) (*model.ExtendTicketRes, error)
+	GetAllTicket(ctx context.Context, req model.GetListTicketParam) (model.ListTicketRes, error)
 	GetOneTicketWithExtend(ctx context.Context, id string) (model.TicketResponse, error)
-	CancelTicket(ctx context.Context, id string) error
-	GetAllTicketCompany(ctx context.Context, req model.GetListTicketReq) ([]model.GetListTicketRes, error)
+	CancelTicket(ctx context.Context, id string) (model.CancelTicketRes, error)
+	GetAllTicketCompany(ctx context.Context, req model.GetListTicketReq) (model.ListTicketCompanyRes, error)
 	ReviewTicktet(ctx context.Context, req *model.ReviewTicketReq) error
+	QuoteTicket(ctx context.Context, req model.QuoteReq) (model.PriceQuote, error)
+	GetTicketTimeline(ctx context.Context, id string) (model.TicketTimelineRes, error)
+	GetListLongTermTicket(ctx context.Context, req model.ListLongTermTicketReq) ([]model.LongTermTicket, error)
+	GetOneLongTermTicket(ctx context.Context, id uuid.UUID) (model.LongTermTicket, error)
+	CancelLongTermTicket(ctx context.Context, id uuid.UUID) (model.LongTermTicket, error)
+	PreviewCheckout(ctx context.Context, id string) (model.CheckoutRes, error)
+	GateProcedure(ctx context.Context, req model.GateProcedureReq, procedure string) (*model.GateProcedureRes, error)
+	CreateWalkInTicket(ctx context.Context, req model.WalkInTicketReq) (*model.Ticket, error)
+}
+
+func (s *TicketService) QuoteTicket(ctx context.Context, req model.QuoteReq) (model.PriceQuote, error) {
+	return quoteStay(ctx, s.repo, valid.UUID(req.ParkingLotId), valid.DayTime(req.StartTime), valid.DayTime(req.EndTime))
 }
 
-func (s *TicketService) GetAllTicketCompany(ctx context.Context, req model.GetListTicketReq) ([]model.GetListTicketRes, error) {
+func (s *TicketService) GetAllTicketCompany(ctx context.Context, req model.GetListTicketReq) (model.ListTicketCompanyRes, error) {
+	ids := req.ParkingLotIDs
+	if req.ParkingLotID != nil {
+		ids = append(ids, valid.String(req.ParkingLotID))
+	}
+	req.ParkingLotIDs = nil
+	for _, raw := range ids {
+		for _, id := range strings.Split(raw, ",") {
+			if id = strings.TrimSpace(id); id == "" {
+				continue
+			}
+			parkingLotID, err := uuid.Parse(id)
+			if err != nil {
+				return model.ListTicketCompanyRes{}, ginext.NewError(http.StatusBadRequest, "Wrong parking lot id")
+			}
+			if err := authorizeParkingLot(ctx, s.repo, parkingLotID); err != nil {
+				return model.ListTicketCompanyRes{}, err
+			}
+			req.ParkingLotIDs = append(req.ParkingLotIDs, parkingLotID.String())
+		}
+	}
+	if len(req.ParkingLotIDs) == 0 {
+		// no lot picked: every lot of the caller's company
+		companyID, err := scopeCompanyID(ctx, uuid.Nil)
+		if err != nil {
+			return model.ListTicketCompanyRes{}, err
+		}
+		if companyID != uuid.Nil {
+			req.CompanyID = valid.StringPointer(companyID.String())
+		}
+	}
 	return s.repo.GetAllTicketCompany(ctx, req)
 }
 
 func (s *TicketService) CreateTicket(ctx context.Context, req *model.TicketReq) (*model.Ticket, error) {
+	vehicle, err := s.repo.GetOneVehicle(ctx, valid.UUID(req.VehicleId))
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeUser(ctx, valid.UUID(vehicle.UserID)); err != nil {
+		return nil, err
+	}
+	if err := checkBooking(ctx, s.repo, req); err != nil {
+		return nil, err
+	}
+	if req.IsLongTerm {
+		return s.createLongTermTicket(ctx, req)
+	}
+	quote, err := quoteStay(ctx, s.repo, valid.UUID(req.ParkingLotId), valid.DayTime(req.StartTime), valid.DayTime(req.EndTime))
+	if err != nil {
+		return nil, err
+	}
+
 	ticket := &model.Ticket{
 		BaseModel: model.BaseModel{
 			CreatorID: req.UserId,
@@ -49,44 +116,96 @@ func (s *TicketService) CreateTicket(ctx context.Context, req *model.TicketReq)
 		ParkingLotId:  req.ParkingLotId,
 		ParkingSlotId: req.ParkingSlotId,
 		TimeFrameId:   req.TimeFrameId,
-		State:         "new",
-		Total:         valid.Float64(req.Total),
+		Total:         quote.Total,
+		Price:         &quote,
 	}
 
-	if req.IsLongTerm {
-		longTermTicket := &model.LongTermTicket{
-			BaseModel: model.BaseModel{
-				CreatorID: req.UserId,
-				UpdaterID: req.UserId,
-			},
-			StartTime:     req.StartTime,
-			EndTime:       req.EndTime,
-			VehicleId:     req.VehicleId,
-			ParkingLotId:  req.ParkingLotId,
-			ParkingSlotId: req.ParkingSlotId,
-			TimeFrameId:   req.TimeFrameId,
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		voucher, err := applyVoucher(ctx, rp, ticket, req.VoucherCode)
+		if err != nil {
+			return err
 		}
-		s.repo.CreateLongTermTicket(ctx, longTermTicket, nil)
-
-		// create ticket normal
-		switch req.Type {
-		case "DAILY":
-		case "CYCLE":
-		case "CUSTOM":
+		awaitPayment(ticket, walletCost(ticket.Total, req.PayWithWallet), model.TicketStateNew)
+		if err := bookParkingSlot(ctx, rp, ticket); err != nil {
+			return err
 		}
-	}
-	err := s.repo.CreateTicket(ctx, ticket, nil)
+		if err := redeemVoucher(ctx, rp, voucher, *ticket); err != nil || !req.PayWithWallet {
+			return err
+		}
+		return spendWallet(ctx, rp, valid.UUID(ticket.UserId), ticket.Total, model.WalletTransaction{
+			TicketID:    &ticket.ID,
+			Description: "Thanh toán vé đỗ xe",
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
+	if ticket.State == model.TicketStatePending {
+		ticket.Payment = s.payments.payFor(ctx, model.Payment{
+			UserID:   ticket.UserId,
+			TicketID: &ticket.ID,
+			Purpose:  model.PAYMENT_FOR_BOOKING,
+			Amount:   ticket.Total,
+		})
+	}
 	return ticket, nil
 }
 
-func (s *TicketService) ExtendTicket(ctx context.Context, req *model.ExtendTicketReq) (*model.TicketExtend, error) {
+// checkBooking makes sure the slot is one of the lot the stay is priced at, and the stay has not started yet
+func checkBooking(ctx context.Context, rp repo.PGInterface, req *model.TicketReq) error {
+	if valid.DayTime(req.StartTime).Before(time.Now().Add(-bookingClockSkew)) {
+		return ginext.NewError(http.StatusBadRequest, "Start time must not be in the past")
+	}
+	return checkSlotInLot(ctx, rp, valid.UUID(req.ParkingSlotId), valid.UUID(req.ParkingLotId))
+}
+
+// bookParkingSlot creates the ticket if its slot is free for the whole stay. It must run inside a transaction:
+// the slot row stays locked until commit, so concurrent bookings of the same slot are checked one by one.
+func bookParkingSlot(ctx context.Context, rp repo.PGInterface, ticket *model.Ticket) error {
+	slotID := valid.UUID(ticket.ParkingSlotId)
+	if err := rp.LockParkingSlot(ctx, slotID, nil); err != nil {
+		return err
+	}
+	taken, err := rp.HasOverlapTicket(ctx, slotID, valid.DayTime(ticket.StartTime), stayEnd(*ticket), nil)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ginext.NewError(http.StatusConflict, utils.MessageError()[http.StatusConflict])
+	}
+	if err := rp.CreateTicket(ctx, ticket, nil); err != nil {
+		return err
+	}
+	return recordTicketState(ctx, rp, ticket.ID, "", ticket.State, nil)
+}
+
+// ExtendTicket books [StartTime, EndTime) right after the current end of the ticket, on the same slot or on
+// req.ParkingSlotId. When that slot is taken, the conflict comes with a free slot of the lot to retry with.
+func (s *TicketService) ExtendTicket(ctx context.Context, req *model.ExtendTicketReq) (*model.ExtendTicketRes, error) {
 	ticket, err := s.repo.GetOneTicket(ctx, valid.UUID(req.TicketOriginId).String(), nil)
 	if err != nil {
 		return nil, err
 	}
+	if err := authorizeUser(ctx, valid.UUID(ticket.UserId)); err != nil {
+		return nil, err
+	}
+	start, end := valid.DayTime(req.StartTime), valid.DayTime(req.EndTime)
+	if err := checkExtendable(ctx, s.repo, ticket, start); err != nil {
+		return nil, err
+	}
+	lotID := valid.UUID(ticket.ParkingLotId)
+	quote, err := quoteStay(ctx, s.repo, lotID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	slotID := valid.UUID(ticket.ParkingSlotId)
+	if req.ParkingSlotId != nil && *req.ParkingSlotId != slotID {
+		if err := checkSlotInLot(ctx, s.repo, *req.ParkingSlotId, lotID); err != nil {
+			return nil, err
+		}
+		slotID = *req.ParkingSlotId
+	}
+
 	extendTicket := &model.Ticket{
 		BaseModel: model.BaseModel{
 			CreatorID: ticket.CreatorID,
@@ -97,35 +216,111 @@ func (s *TicketService) ExtendTicket(ctx context.Context, req *model.ExtendTicke
 		EndTime:       req.EndTime,
 		VehicleId:     ticket.VehicleId,
 		ParkingLotId:  ticket.ParkingLotId,
-		ParkingSlotId: ticket.ParkingSlotId,
+		ParkingSlotId: &slotID,
 		TimeFrameId:   req.TimeFrameId,
-		State:         "extend",
-		Total:         valid.Float64(req.Total),
+		Total:         quote.Total,
+		Price:         &quote,
 	}
-	ticket.IsExtend = true
-	if err := s.repo.UpdateTicket(ctx, &ticket, nil); err != nil {
-		return nil, err
+	awaitPayment(extendTicket, walletCost(extendTicket.Total, req.PayWithWallet), model.TicketStateExtend)
+	ticketEx := &model.TicketExtend{TicketId: ticket.ID}
+	slotTaken := false
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		// checked again on the locked origin, which may have been checked out or extended meanwhile
+		origin, err := rp.GetOneTicketForUpdate(ctx, ticket.ID, nil)
+		if err != nil {
+			return err
+		}
+		if err := checkExtendable(ctx, rp, origin, start); err != nil {
+			return err
+		}
+		if err := bookParkingSlot(ctx, rp, extendTicket); err != nil {
+			slotTaken = hasCode(err, http.StatusConflict)
+			return err
+		}
+		if err := rp.MarkTicketExtended(ctx, origin.ID, nil); err != nil {
+			return err
+		}
+		// create extend ticket table
+		ticketEx.TicketExtendId = extendTicket.ID
+		if err := rp.CreateTicketExtend(ctx, ticketEx, nil); err != nil || !req.PayWithWallet {
+			return err
+		}
+		return spendWallet(ctx, rp, valid.UUID(extendTicket.UserId), extendTicket.Total, model.WalletTransaction{
+			TicketID:    &extendTicket.ID,
+			Description: "Thanh toán gia hạn vé đỗ xe",
+		})
+	})
+	if slotTaken {
+		return s.offerAlternativeSlot(ctx, lotID, slotID, start, end, err)
 	}
-	if err := s.repo.CreateTicket(ctx, extendTicket, nil); err != nil {
+	if err != nil {
 		return nil, err
 	}
-	// create extend ticket table
-	ticketEx := &model.TicketExtend{
-		TicketExtendId: extendTicket.ID,
-		TicketId:       ticket.ID,
+	if extendTicket.State == model.TicketStatePending {
+		extendTicket.Payment = s.payments.payFor(ctx, model.Payment{
+			UserID:   extendTicket.UserId,
+			TicketID: &extendTicket.ID,
+			Purpose:  model.PAYMENT_FOR_EXTENSION,
+			Amount:   extendTicket.Total,
+		})
 	}
-	if err := s.repo.CreateTicketExtend(ctx, ticketEx, nil); err != nil {
-		return nil, err
+	return &model.ExtendTicketRes{TicketExtend: ticketEx, Ticket: extendTicket}, nil
+}
+
+// checkExtendable makes sure the ticket can still be extended from start, its current end
+func checkExtendable(ctx context.Context, rp repo.PGInterface, ticket model.Ticket, start time.Time) error {
+	if ticket.State != model.TicketStateNew && ticket.State != model.TicketStateOngoing {
+		return ginext.NewError(http.StatusConflict, "Only new or ongoing tickets can be extended")
 	}
-	return ticketEx, nil
+	extensions, err := rp.GetListExtendTicketByOrigin(ctx, ticket.ID.String(), nil)
+	if err != nil {
+		return err
+	}
+	currentEnd := bookedEnd(ticket, extensions)
+	if !start.Truncate(time.Second).Equal(currentEnd.Truncate(time.Second)) {
+		return ginext.NewError(http.StatusBadRequest, "Extension must start at the current end of the ticket: "+currentEnd.Format(time.RFC3339))
+	}
+	return nil
 }
 
-func (s *TicketService) GetAllTicket(ctx context.Context, req model.GetListTicketParam) ([]model.Ticket, error) {
-	res, err := s.repo.GetAllTicket(ctx, req, nil)
+// offerAlternativeSlot looks for a free slot for the window, in the block of the taken slot first.
+// It still returns the conflict, with the slot found if any.
+func (s *TicketService) offerAlternativeSlot(ctx context.Context, lotID, takenSlotID uuid.UUID, start, end time.Time, conflict error) (*model.ExtendTicketRes, error) {
+	taken, err := s.repo.GetOneParkingSlot(ctx, takenSlotID)
 	if err != nil {
 		return nil, err
 	}
-	return res, nil
+	slot, err := s.repo.GetFreeParkingSlot(ctx, lotID, taken.BlockID, start, end, nil)
+	if err != nil {
+		return nil, err
+	}
+	if slot == nil {
+		return nil, conflict
+	}
+	return &model.ExtendTicketRes{
+		Message:     conflict.Error(),
+		Alternative: slot,
+	}, conflict
+}
+
+// checkSlotInLot makes sure the slot belongs to the lot
+func checkSlotInLot(ctx context.Context, rp repo.PGInterface, slotID, lotID uuid.UUID) error {
+	slot, err := rp.GetOneParkingSlot(ctx, slotID)
+	if err != nil {
+		return err
+	}
+	block, err := rp.GetOneBlock(ctx, slot.BlockID)
+	if err != nil {
+		return err
+	}
+	if block.ParkingLotID != lotID {
+		return ginext.NewError(http.StatusBadRequest, "Parking slot does not belong to the parking lot")
+	}
+	return nil
+}
+
+func (s *TicketService) GetAllTicket(ctx context.Context, req model.GetListTicketParam) (model.ListTicketRes, error) {
+	return s.repo.GetAllTicket(ctx, req, nil)
 }
 
 func (s *TicketService) GetOneTicketWithExtend(ctx context.Context, id string) (model.TicketResponse, error) {
@@ -133,6 +328,9 @@ func (s *TicketService) GetOneTicketWithExtend(ctx context.Context, id string) (
 	if err != nil {
 		return model.TicketResponse{}, err
 	}
+	if err := authorizeTicket(ctx, s.repo, ticket); err != nil {
+		return model.TicketResponse{}, err
+	}
 	ticketExtend, err := s.repo.GetListExtendTicketByOrigin(ctx, ticket.ID.String(), nil)
 	if err != nil {
 		return model.TicketResponse{}, err
@@ -144,16 +342,36 @@ func (s *TicketService) GetOneTicketWithExtend(ctx context.Context, id string) (
 	return ticketRes, nil
 }
 
-func (s *TicketService) CancelTicket(ctx context.Context, id string) error {
+// CancelTicket cancels the ticket and its extensions, refunding them by the cancellation policy of the lot
+func (s *TicketService) CancelTicket(ctx context.Context, id string) (model.CancelTicketRes, error) {
 	ticket, err := s.repo.GetOneTicket(ctx, id, nil)
 	if err != nil {
-		return err
+		return model.CancelTicketRes{}, err
 	}
-	ticket.State = "cancel"
-	if err := s.repo.UpdateTicket(ctx, &ticket, nil); err != nil {
+	if err := authorizeUser(ctx, valid.UUID(ticket.UserId)); err != nil {
+		return model.CancelTicketRes{}, err
+	}
+	if !ticket.State.CanTransitionTo(model.TicketStateCancel) {
+		return model.CancelTicketRes{}, errIllegalTransition(ticket.State, model.TicketStateCancel)
+	}
+	percent, err := refundPercent(ctx, s.repo, ticket, time.Now())
+	if err != nil {
+		return model.CancelTicketRes{}, err
+	}
+	res := model.CancelTicketRes{TicketId: ticket.ID.String(), Percent: percent}
+	var cancelled []model.Ticket
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		cancelled, err = cancelTicket(ctx, rp, &ticket, percent)
 		return err
+	})
+	if err != nil {
+		return model.CancelTicketRes{}, err
 	}
-	return nil
+	for _, t := range cancelled {
+		res.Refund += t.Refund
+	}
+	s.payments.refundTickets(ctx, cancelled)
+	return res, nil
 }
 
 func (s *TicketService) ProcedureWithTicket(ctx context.Context, req *model.ProcedureReq) (bool, error) {
@@ -161,20 +379,103 @@ func (s *TicketService) ProcedureWithTicket(ctx context.Context, req *model.Proc
 	if err != nil {
 		return false, err
 	}
-	switch req.Type {
-	case "check_in":
-		ticket.State = "ongoing"
-		ticket.EntryTime = valid.DayTimePointer(time.Now())
-	case "check_out":
-		ticket.State = "completed"
-		ticket.ExitTime = valid.DayTimePointer(time.Now())
+	if ticket.ParkingLotId == nil {
+		return false, errForbidden()
 	}
-	if err := s.repo.UpdateTicket(ctx, &ticket, nil); err != nil {
+	if err := authorizeParkingLot(ctx, s.repo, *ticket.ParkingLotId); err != nil {
+		return false, err
+	}
+	if _, err := s.runProcedure(ctx, &ticket, req.Type); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
+// runProcedure checks the car of the ticket in or out. Check-out records the charges due and returns them.
+func (s *TicketService) runProcedure(ctx context.Context, ticket *model.Ticket, procedure string) (*model.CheckoutRes, error) {
+	var checkout *model.CheckoutRes
+	err := s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		switch procedure {
+		case model.PROCEDURE_CHECK_IN:
+			ticket.EntryTime = valid.DayTimePointer(time.Now())
+			return transitionTicket(ctx, rp, ticket, model.TicketStateOngoing, nil)
+		case model.PROCEDURE_CHECK_OUT:
+			ticket.ExitTime = valid.DayTimePointer(time.Now())
+			res, err := computeCheckout(ctx, rp, *ticket, *ticket.ExitTime)
+			if err != nil {
+				return err
+			}
+			if ticket.EndTime == nil {
+				ticket.EndTime = ticket.ExitTime
+			}
+			for i := range res.Charges {
+				if err := rp.CreateTicketCharge(ctx, &res.Charges[i], nil); err != nil {
+					return err
+				}
+			}
+			if ticket.UserId != nil {
+				paid, err := chargeWallet(ctx, rp, *ticket.UserId, res.AmountDue, model.WalletTransaction{
+					TicketID:    &ticket.ID,
+					Description: "Thanh toán phí phát sinh khi ra bãi",
+				})
+				if err != nil {
+					return err
+				}
+				res.PaidFromWallet = paid
+				res.AmountDue -= paid
+			}
+			if err := transitionTicket(ctx, rp, ticket, model.TicketStateCompleted, nil); err != nil {
+				return err
+			}
+			checkout = &res
+			return transitionExtensions(ctx, rp, *ticket, model.TicketStateCompleted, nil)
+		}
+		return ginext.NewError(http.StatusBadRequest, "Wrong procedure type")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checkout, nil
+}
+
+// PreviewCheckout shows the gate what the car owes if it leaves now, before the barrier opens
+func (s *TicketService) PreviewCheckout(ctx context.Context, id string) (model.CheckoutRes, error) {
+	ticket, err := s.repo.GetOneTicket(ctx, id, nil)
+	if err != nil {
+		return model.CheckoutRes{}, err
+	}
+	if ticket.ParkingLotId == nil {
+		return model.CheckoutRes{}, errForbidden()
+	}
+	if err := authorizeParkingLot(ctx, s.repo, *ticket.ParkingLotId); err != nil {
+		return model.CheckoutRes{}, err
+	}
+	if !ticket.State.CanTransitionTo(model.TicketStateCompleted) {
+		return model.CheckoutRes{}, errIllegalTransition(ticket.State, model.TicketStateCompleted)
+	}
+	return computeCheckout(ctx, s.repo, ticket, time.Now())
+}
+
+func (s *TicketService) GetTicketTimeline(ctx context.Context, id string) (model.TicketTimelineRes, error) {
+	ticket, err := s.repo.GetOneTicket(ctx, id, nil)
+	if err != nil {
+		return model.TicketTimelineRes{}, err
+	}
+	if err := authorizeTicket(ctx, s.repo, ticket); err != nil {
+		return model.TicketTimelineRes{}, err
+	}
+	history, err := s.repo.GetListTicketStateHistory(ctx, ticket.ID, nil)
+	if err != nil {
+		return model.TicketTimelineRes{}, err
+	}
+	return model.TicketTimelineRes{
+		TicketID:  ticket.ID,
+		State:     ticket.State,
+		UpdatedAt: ticket.UpdatedAt,
+		History:   history,
+	}, nil
+}
+
 func (s *TicketService) ReviewTicktet(ctx context.Context, req *model.ReviewTicketReq) error {
 	ticket, err := s.repo.GetOneTicket(ctx, req.TicketId.String(), nil)
 	if err != nil {
@@ -183,8 +484,11 @@ func (s *TicketService) ReviewTicktet(ctx context.Context, req *model.ReviewTick
 		}
 		return err
 	}
+	if err := authorizeUser(ctx, valid.UUID(ticket.UserId)); err != nil {
+		return err
+	}
 
-	if ticket.State != "completed" {
+	if ticket.State != model.TicketStateCompleted {
 		return ginext.NewError(http.StatusBadRequest, "ticket is not yet completed")
 	}
 
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/utils"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// transitionTicket moves the ticket to state to and records the transition. It must run inside a transaction.
+func transitionTicket(ctx context.Context, rp repo.PGInterface, ticket *model.Ticket, to model.TicketState, reason *string) error {
+	from := ticket.State
+	if !from.CanTransitionTo(to) {
+		return errIllegalTransition(from, to)
+	}
+	ticket.State = to
+	if err := rp.UpdateTicketState(ctx, ticket, from, nil); err != nil {
+		ticket.State = from
+		return err
+	}
+	return recordTicketState(ctx, rp, ticket.ID, from, to, reason)
+}
+
+func errIllegalTransition(from, to model.TicketState) error {
+	return ginext.NewError(http.StatusConflict, fmt.Sprintf("Ticket cannot move from %s to %s", from, to))
+}
+
+// transitionExtensions moves the extensions of an origin ticket that are not used yet along with it
+func transitionExtensions(ctx context.Context, rp repo.PGInterface, origin model.Ticket, to model.TicketState, reason *string) error {
+	extensions, err := rp.GetListExtendTicketByOrigin(ctx, origin.ID.String(), nil)
+	if err != nil {
+		return err
+	}
+	for i := range extensions {
+		if extensions[i].State != model.TicketStateExtend {
+			continue
+		}
+		if err := transitionTicket(ctx, rp, &extensions[i], to, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordTicketState(ctx context.Context, rp repo.PGInterface, ticketID uuid.UUID, from, to model.TicketState, reason *string) error {
+	history := &model.TicketStateHistory{
+		TicketID:  ticketID,
+		FromState: from,
+		ToState:   to,
+		Reason:    reason,
+		ActorType: utils.PRINCIPAL_SYSTEM,
+	}
+	if principal, ok := utils.PrincipalFromContext(ctx); ok {
+		history.ActorID = &principal.ID
+		history.ActorType = principal.Type
+	}
+	return rp.CreateTicketStateHistory(ctx, history, nil)
+}
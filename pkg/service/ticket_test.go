@@ -0,0 +1,156 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"parking-server/pkg/client"
+	"parking-server/pkg/handlers"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/service"
+	"parking-server/pkg/utils"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// TestCreateTicketConcurrent books the same slot for the same window from many goroutines at once: exactly one
+// booking wins, the others get a conflict. It runs against the Postgres database of PARKING_TEST_DSN and is
+// skipped without it.
+func TestCreateTicketConcurrent(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	f := newFixture(t, db)
+	lot, slot, frame, user, vehicle := f.lot, f.slot, f.frame, f.user, f.vehicle
+
+	rp := repo.NewPGRepo(db)
+	payments := service.NewPaymentService(rp, client.NewMockPaymentProvider("http://localhost", "test"), "VND", 15*time.Minute)
+	tickets := service.NewTicketService(rp, payments)
+	ctx = utils.WithPrincipal(ctx, &utils.Principal{ID: user.ID, Type: utils.PRINCIPAL_USER})
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	end := start.Add(2 * time.Hour)
+	const bookings = 10
+	errs := make([]error, bookings)
+	var wg sync.WaitGroup
+	for i := 0; i < bookings; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = tickets.CreateTicket(ctx, &model.TicketReq{
+				VehicleId:     &vehicle.ID,
+				UserId:        &user.ID,
+				ParkingSlotId: &slot.ID,
+				ParkingLotId:  &lot.ID,
+				TimeFrameId:   &frame.ID,
+				StartTime:     &start,
+				EndTime:       &end,
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	booked := 0
+	for _, err := range errs {
+		if err == nil {
+			booked++
+			continue
+		}
+		var apiErr ginext.ApiError
+		if !errors.As(err, &apiErr) || apiErr.Code() != http.StatusConflict || err.Error() != utils.MessageError()[http.StatusConflict] {
+			t.Errorf("booking failed with %v, want a conflict", err)
+		}
+	}
+	if booked != 1 {
+		t.Errorf("%d bookings succeeded, want exactly 1", booked)
+	}
+}
+
+// TestGetTicketsByPlateExtended looks up by plate a booking extended once: the gate gets the origin ticket,
+// flagged is_extend, and not its extension. It runs against PARKING_TEST_DSN like TestCreateTicketConcurrent.
+func TestGetTicketsByPlateExtended(t *testing.T) {
+	db := testDB(t)
+	f := newFixture(t, db)
+
+	start := time.Now().Add(time.Hour).Truncate(time.Hour)
+	end := start.Add(2 * time.Hour)
+	extendEnd := end.Add(time.Hour)
+	origin := model.Ticket{UserId: &f.user.ID, VehicleId: &f.vehicle.ID, ParkingLotId: &f.lot.ID, ParkingSlotId: &f.slot.ID,
+		StartTime: &start, EndTime: &end, State: model.TicketStateNew, IsExtend: true}
+	mustCreate(t, db, &origin)
+	extension := model.Ticket{UserId: &f.user.ID, VehicleId: &f.vehicle.ID, ParkingLotId: &f.lot.ID, ParkingSlotId: &f.slot.ID,
+		StartTime: &end, EndTime: &extendEnd, State: model.TicketStateExtend}
+	mustCreate(t, db, &extension)
+	mustCreate(t, db, &model.TicketExtend{TicketId: origin.ID, TicketExtendId: extension.ID})
+
+	tickets, err := repo.NewPGRepo(db).GetTicketsByPlate(context.Background(), utils.NormalizePlate(f.vehicle.Number), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 1 || tickets[0].ID != origin.ID {
+		t.Fatalf("got %d tickets, want only the origin %s", len(tickets), origin.ID)
+	}
+}
+
+// testDB opens and migrates the Postgres database of PARKING_TEST_DSN, e.g.
+// "host=localhost user=postgres password=1 dbname=parking_test sslmode=disable", and skips the test without it.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("PARKING_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PARKING_TEST_DSN is not set")
+	}
+	utils.LoadMessageError()
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handlers.NewMigrationHandler(db, nil).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+type fixture struct {
+	lot     model.ParkingLot
+	slot    model.ParkingSlot
+	frame   model.TimeFrame
+	user    model.User
+	vehicle model.Vehicle
+}
+
+// newFixture creates a lot of its own for every run, with one slot priced by the hour, and a user with a car
+func newFixture(t *testing.T, db *gorm.DB) fixture {
+	t.Helper()
+	suffix := uuid.NewString()
+	company := model.Company{Name: "test", PhoneNumber: suffix, Email: suffix + "@test.local", Password: "-", Status: "active"}
+	mustCreate(t, db, &company)
+	f := fixture{lot: model.ParkingLot{Name: "test " + suffix, CompanyID: company.ID, Status: "active"}}
+	mustCreate(t, db, &f.lot)
+	block := model.Block{Code: "A", Slot: 1, ParkingLotID: f.lot.ID}
+	mustCreate(t, db, &block)
+	f.slot = model.ParkingSlot{Name: "A1", BlockID: block.ID}
+	mustCreate(t, db, &f.slot)
+	f.frame = model.TimeFrame{Duration: 1, Cost: 10000, ParkingLotId: f.lot.ID}
+	mustCreate(t, db, &f.frame)
+	f.user = model.User{DisplayName: "test", Password: "-", PhoneNumber: suffix}
+	mustCreate(t, db, &f.user)
+	f.vehicle = model.Vehicle{Name: "test", Number: suffix[:8], Type: "car", UserID: &f.user.ID}
+	mustCreate(t, db, &f.vehicle)
+	return f
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,9 +3,12 @@ package service
 import (
 	"context"
 	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"net/http"
 	"parking-server/pkg/model"
 	"parking-server/pkg/repo"
 	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
 )
 
 type TimeFrameService struct {
@@ -32,10 +35,21 @@ func (s *TimeFrameService) GetAllTimeFrame(ctx context.Context, req model.GetLis
 	if err != nil {
 		return model.ListTimeFrame{}, err
 	}
+	if lotID, err := uuid.Parse(valid.String(req.ParkingLotId)); err == nil {
+		if res.PricingRules, err = s.repo.GetPricingRules(ctx, lotID, nil); err != nil {
+			return model.ListTimeFrame{}, err
+		}
+	}
 	return *res, nil
 }
 
 func (s *TimeFrameService) CreateTimeFrame(ctx context.Context, req model.TimeFrameReq) (*model.TimeFrame, error) {
+	if err := authorizeParkingLot(ctx, s.repo, req.ParkingLotId); err != nil {
+		return nil, err
+	}
+	if err := validateTimeFrame(req.Duration); err != nil {
+		return nil, err
+	}
 	time := &model.TimeFrame{Duration: req.Duration, Cost: req.Cost, ParkingLotId: req.ParkingLotId}
 
 	if err := s.repo.CreateTimeframe(ctx, time); err != nil {
@@ -53,8 +67,19 @@ func (s *TimeFrameService) UpdateTimeFrame(ctx context.Context, id uuid.UUID, re
 	if err != nil {
 		return time, err
 	}
+	if err := authorizeParkingLot(ctx, s.repo, time.ParkingLotId); err != nil {
+		return time, err
+	}
+	if req.ParkingLotId != nil && *req.ParkingLotId != time.ParkingLotId {
+		if err := authorizeParkingLot(ctx, s.repo, *req.ParkingLotId); err != nil {
+			return time, err
+		}
+	}
 
 	utils.Sync(req, &time)
+	if err := validateTimeFrame(time.Duration); err != nil {
+		return time, err
+	}
 	if err := s.repo.UpdateTimeframe(ctx, &time); err != nil {
 		return time, err
 	}
@@ -63,26 +88,116 @@ func (s *TimeFrameService) UpdateTimeFrame(ctx context.Context, id uuid.UUID, re
 }
 
 func (s *TimeFrameService) DeleteTimeFrame(ctx context.Context, id uuid.UUID) error {
+	time, err := s.repo.GetOneTimeframe(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := authorizeParkingLot(ctx, s.repo, time.ParkingLotId); err != nil {
+		return err
+	}
 	return s.repo.DeleteTimeframe(ctx, id)
 }
 
 func (s *TimeFrameService) CreateMultiTimeFrame(ctx context.Context, req model.ListTimeFrameReq) (err error) {
+	if err := s.authorizeTimeFrames(ctx, req); err != nil {
+		return err
+	}
+	return s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		return saveTimeFrames(ctx, rp, req)
+	})
+}
+
+func (s *TimeFrameService) UpdateMultiTimeFrame(ctx context.Context, req model.ListTimeFrameReq) (err error) {
+	if len(req.Data) == 0 {
+		return ginext.NewError(http.StatusBadRequest, "data is required")
+	}
+	if err := s.authorizeTimeFrames(ctx, req); err != nil {
+		return err
+	}
+	return s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		//detele all time fram by parking lot
+		if err := rp.DeleteTimeFrameByParkingLotID(ctx, req.Data[0].ParkingLotId.String(), nil); err != nil {
+			return err
+		}
+		return saveTimeFrames(ctx, rp, req)
+	})
+}
+
+// saveTimeFrames creates the time frames of the request. When rules are given, they replace the rules of
+// every lot of the request, lots left without a rule lose theirs.
+func saveTimeFrames(ctx context.Context, rp repo.PGInterface, req model.ListTimeFrameReq) error {
 	listUser := []model.TimeFrame{}
 	for _, item := range req.Data {
 		tmp := model.TimeFrame{}
 		utils.Sync(item, &tmp)
 		listUser = append(listUser, tmp)
 	}
-	err = s.repo.CreateMultiTimeFrame(ctx, listUser, nil)
-	return err
+	if len(listUser) > 0 {
+		if err := rp.CreateMultiTimeFrame(ctx, listUser, nil); err != nil {
+			return err
+		}
+	}
+	if req.PricingRules == nil {
+		return nil
+	}
+	lots := []uuid.UUID{}
+	rules := map[uuid.UUID][]model.PricingRule{}
+	for _, item := range req.Data {
+		if _, ok := rules[item.ParkingLotId]; !ok {
+			lots = append(lots, item.ParkingLotId)
+			rules[item.ParkingLotId] = nil
+		}
+	}
+	for _, rule := range req.PricingRules {
+		if _, ok := rules[rule.ParkingLotId]; !ok {
+			lots = append(lots, rule.ParkingLotId)
+		}
+		rules[rule.ParkingLotId] = append(rules[rule.ParkingLotId], rule)
+	}
+	for _, lotID := range lots {
+		if err := rp.DeletePricingRulesByParkingLotID(ctx, lotID, nil); err != nil {
+			return err
+		}
+		if err := rp.CreatePricingRules(ctx, rules[lotID], nil); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (s *TimeFrameService) UpdateMultiTimeFrame(ctx context.Context, req model.ListTimeFrameReq) (err error) {
-	//detele all time fram by parking lot
-	err = s.repo.DeleteTimeFrameByParkingLotID(ctx, req.Data[0].ParkingLotId.String(), nil)
-	if err != nil {
+// authorizeTimeFrames checks the time frames and rules of the request, and that the caller owns every lot of
+// it. Rules without a lot go to the lot of the time frames.
+func (s *TimeFrameService) authorizeTimeFrames(ctx context.Context, req model.ListTimeFrameReq) error {
+	if err := validatePricingRules(req.PricingRules); err != nil {
 		return err
 	}
-	err = s.CreateMultiTimeFrame(ctx, req)
-	return err
+	lots := []uuid.UUID{}
+	for _, item := range req.Data {
+		if err := validateTimeFrame(item.Duration); err != nil {
+			return err
+		}
+		lots = append(lots, item.ParkingLotId)
+	}
+	for i := range req.PricingRules {
+		rule := &req.PricingRules[i]
+		rule.ID = uuid.Nil
+		if rule.ParkingLotId == uuid.Nil && len(req.Data) > 0 {
+			rule.ParkingLotId = req.Data[0].ParkingLotId
+		}
+		if rule.ParkingLotId == uuid.Nil {
+			return ginext.NewError(http.StatusBadRequest, "parkingLotId of pricing rule is required")
+		}
+		lots = append(lots, rule.ParkingLotId)
+	}
+	checked := map[uuid.UUID]bool{}
+	for _, lotID := range lots {
+		if checked[lotID] {
+			continue
+		}
+		if err := authorizeParkingLot(ctx, s.repo, lotID); err != nil {
+			return err
+		}
+		checked[lotID] = true
+	}
+	return nil
 }
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// issueTokenPair signs an access token for the principal and stores a new refresh token of its session
+func issueTokenPair(ctx context.Context, rp repo.PGInterface, principal *utils.Principal, client model.ClientInfo) (*model.TokenRes, error) {
+	accessToken, err := utils.GenerateToken(principal)
+	if err != nil {
+		return nil, ginext.NewError(http.StatusInternalServerError, "Error when generate token: "+err.Error())
+	}
+	token, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, ginext.NewError(http.StatusInternalServerError, "Error when generate refresh token: "+err.Error())
+	}
+	refreshToken := &model.RefreshToken{
+		BaseModel: model.BaseModel{
+			CreatorID: &principal.ID,
+			UpdaterID: &principal.ID,
+		},
+		Token:         utils.HashToken(token),
+		UserId:        principal.ID,
+		PrincipalType: principal.Type,
+		SessionId:     principal.SessionID,
+		ExpiredDate:   valid.DayTimePointer(time.Now().Add(utils.REFRESH_EXPIRTE_TIME * time.Second)),
+		UserAgent:     client.UserAgent,
+		IpAddress:     client.IpAddress,
+	}
+	if err := rp.CreateRefreshToken(ctx, refreshToken, nil); err != nil {
+		return nil, err
+	}
+	return &model.TokenRes{
+		AccessToken:  accessToken,
+		RefreshToken: token,
+	}, nil
+}
+
+func companyPrincipal(company model.Company, sessionID uuid.UUID) *utils.Principal {
+	return &utils.Principal{
+		ID:        company.ID,
+		SessionID: sessionID,
+		Type:      utils.PRINCIPAL_COMPANY,
+		CompanyID: company.ID,
+		Role:      company.Role,
+	}
+}
+
+func adminPrincipal(admin model.Admin, sessionID uuid.UUID) *utils.Principal {
+	return &utils.Principal{
+		ID:        admin.ID,
+		SessionID: sessionID,
+		Type:      utils.PRINCIPAL_ADMIN,
+		Role:      utils.PRINCIPAL_ADMIN,
+	}
+}
+
+func employeePrincipal(employee model.Employee, sessionID uuid.UUID) *utils.Principal {
+	return &utils.Principal{
+		ID:        employee.ID,
+		SessionID: sessionID,
+		Type:      utils.PRINCIPAL_EMPLOYEE,
+		CompanyID: employee.CompanyID,
+		Role:      utils.PRINCIPAL_EMPLOYEE,
+	}
+}
+
+// loadPrincipal rebuilds the principal a refresh token was issued to from its current account
+func loadPrincipal(ctx context.Context, rp repo.PGInterface, refreshToken model.RefreshToken) (*utils.Principal, error) {
+	switch refreshToken.PrincipalType {
+	case utils.PRINCIPAL_ADMIN:
+		admin, err := rp.GetOneAdmin(ctx, refreshToken.UserId, nil)
+		if err != nil {
+			return nil, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+		}
+		if admin.Status != "active" {
+			return nil, ginext.NewError(http.StatusUnauthorized, "Your account is currently inactive")
+		}
+		return adminPrincipal(admin, refreshToken.SessionId), nil
+	case utils.PRINCIPAL_COMPANY:
+		company, err := rp.GetOneCompany(ctx, refreshToken.UserId)
+		if err != nil {
+			return nil, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+		}
+		if company.Status != "active" {
+			return nil, ginext.NewError(http.StatusUnauthorized, "Your account is currently inactive")
+		}
+		return companyPrincipal(company, refreshToken.SessionId), nil
+	case utils.PRINCIPAL_EMPLOYEE:
+		employee, err := rp.GetOneEmployee(ctx, refreshToken.UserId)
+		if err != nil {
+			return nil, ginext.NewError(http.StatusUnauthorized, utils.MessageError()[http.StatusUnauthorized])
+		}
+		if employee.Status != "active" {
+			return nil, ginext.NewError(http.StatusUnauthorized, "Your account is currently inactive")
+		}
+		return employeePrincipal(employee, refreshToken.SessionId), nil
+	default:
+		return &utils.Principal{ID: refreshToken.UserId, SessionID: refreshToken.SessionId, Type: utils.PRINCIPAL_USER}, nil
+	}
+}
@@ -71,6 +71,9 @@ func (s *UserService) CreateUser(ctx context.Context, req model.CreateUserReq) (
 }
 
 func (s *UserService) UpdateUser(ctx context.Context, userReq model.UserReq) (*model.User, error) {
+	if err := authorizeUser(ctx, valid.UUID(userReq.ID)); err != nil {
+		return nil, err
+	}
 	user, err := s.repo.GetOneUserById(ctx, valid.UUID(userReq.ID), nil)
 	if err != nil {
 		return nil, err
@@ -95,6 +98,9 @@ func (s *UserService) UpdateUser(ctx context.Context, userReq model.UserReq) (*m
 }
 
 func (s *UserService) GetUserById(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	if err := authorizeUser(ctx, id); err != nil {
+		return nil, err
+	}
 	rs, err := s.repo.GetOneUserById(ctx, id, nil)
 	if err != nil {
 		return nil, err
@@ -103,5 +109,12 @@ func (s *UserService) GetUserById(ctx context.Context, id uuid.UUID) (*model.Use
 }
 
 func (s *UserService) DeleteUser(ctx context.Context, id string) error {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return ginext.NewError(http.StatusBadRequest, "Wrong id")
+	}
+	if err := authorizeUser(ctx, userID); err != nil {
+		return err
+	}
 	return s.repo.DeleteUser(ctx, id, nil)
 }
@@ -30,7 +30,7 @@ func (s *VehicleService) CreateVehicle(ctx context.Context, req model.VehicleReq
 		Name:   valid.String(req.Name),
 		Number: valid.String(req.Number),
 		Type:   valid.String(req.Type),
-		UserID: valid.UUID(req.UserID),
+		UserID: req.UserID,
 	}
 
 	if err := s.repo.CreateVehicle(ctx, Vehicle); err != nil {
@@ -40,11 +40,25 @@ func (s *VehicleService) CreateVehicle(ctx context.Context, req model.VehicleReq
 }
 
 func (s *VehicleService) GetListVehicle(ctx context.Context, req model.ListVehicleReq) (model.ListVehicleRes, error) {
+	principal, err := currentPrincipal(ctx)
+	if err != nil {
+		return model.ListVehicleRes{}, err
+	}
+	if !principal.Is(utils.PRINCIPAL_ADMIN) {
+		req.UserID = valid.StringPointer(principal.ID.String())
+	}
 	return s.repo.GetListVehicle(ctx, req)
 }
 
 func (s *VehicleService) GetOneVehicle(ctx context.Context, id uuid.UUID) (model.Vehicle, error) {
-	return s.repo.GetOneVehicle(ctx, id)
+	Vehicle, err := s.repo.GetOneVehicle(ctx, id)
+	if err != nil {
+		return Vehicle, err
+	}
+	if err := authorizeUser(ctx, valid.UUID(Vehicle.UserID)); err != nil {
+		return model.Vehicle{}, err
+	}
+	return Vehicle, nil
 }
 
 func (s *VehicleService) UpdateVehicle(ctx context.Context, req model.VehicleReq) (model.Vehicle, error) {
@@ -52,6 +66,10 @@ func (s *VehicleService) UpdateVehicle(ctx context.Context, req model.VehicleReq
 	if err != nil {
 		return Vehicle, err
 	}
+	if err := authorizeUser(ctx, valid.UUID(Vehicle.UserID)); err != nil {
+		return Vehicle, err
+	}
+	req.UserID = nil
 
 	utils.Sync(req, &Vehicle)
 	if err := s.repo.UpdateVehicle(ctx, &Vehicle); err != nil {
@@ -62,5 +80,12 @@ func (s *VehicleService) UpdateVehicle(ctx context.Context, req model.VehicleReq
 }
 
 func (s *VehicleService) DeleteVehicle(ctx context.Context, id uuid.UUID) error {
+	Vehicle, err := s.repo.GetOneVehicle(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := authorizeUser(ctx, valid.UUID(Vehicle.UserID)); err != nil {
+		return err
+	}
 	return s.repo.DeleteVehicle(ctx, id)
 }
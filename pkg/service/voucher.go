@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/valid"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+type VoucherService struct {
+	repo repo.PGInterface
+}
+
+func NewVoucherService(repo repo.PGInterface) VoucherInterface {
+	return &VoucherService{repo: repo}
+}
+
+type VoucherInterface interface {
+	CreateVoucher(ctx context.Context, req model.VoucherReq) (model.Voucher, error)
+	GetListVoucher(ctx context.Context, req model.ListVoucherReq) (model.ListVoucherRes, error)
+	GetOneVoucher(ctx context.Context, id uuid.UUID) (model.Voucher, error)
+	UpdateVoucher(ctx context.Context, id uuid.UUID, req model.VoucherReq) (model.Voucher, error)
+	DeleteVoucher(ctx context.Context, id uuid.UUID) error
+	GetListRedemption(ctx context.Context, req model.ListRedemptionReq) (model.ListRedemptionRes, error)
+	GetVoucherReport(ctx context.Context, req model.VoucherReportReq) ([]model.VoucherReport, error)
+}
+
+func (s *VoucherService) CreateVoucher(ctx context.Context, req model.VoucherReq) (model.Voucher, error) {
+	companyID, err := scopeCompanyID(ctx, valid.UUID(req.CompanyID))
+	if err != nil {
+		return model.Voucher{}, err
+	}
+	if companyID == uuid.Nil {
+		return model.Voucher{}, ginext.NewError(http.StatusBadRequest, "companyId is required")
+	}
+	voucher := model.Voucher{CompanyID: companyID, Status: model.VOUCHER_ACTIVE}
+	syncVoucher(req, &voucher)
+	if err := s.checkVoucherTerms(ctx, voucher); err != nil {
+		return voucher, err
+	}
+	if err := s.repo.CreateVoucher(ctx, &voucher, nil); err != nil {
+		return voucher, err
+	}
+	return voucher, nil
+}
+
+func (s *VoucherService) GetListVoucher(ctx context.Context, req model.ListVoucherReq) (model.ListVoucherRes, error) {
+	requested, _ := uuid.Parse(valid.String(req.CompanyID))
+	companyID, err := scopeCompanyID(ctx, requested)
+	if err != nil {
+		return model.ListVoucherRes{}, err
+	}
+	if companyID != uuid.Nil {
+		req.CompanyID = valid.StringPointer(companyID.String())
+	}
+	return s.repo.GetListVoucher(ctx, req, nil)
+}
+
+func (s *VoucherService) GetOneVoucher(ctx context.Context, id uuid.UUID) (model.Voucher, error) {
+	voucher, err := s.repo.GetOneVoucher(ctx, id, nil)
+	if err != nil {
+		return voucher, err
+	}
+	if err := authorizeCompany(ctx, voucher.CompanyID); err != nil {
+		return model.Voucher{}, err
+	}
+	return voucher, nil
+}
+
+func (s *VoucherService) UpdateVoucher(ctx context.Context, id uuid.UUID, req model.VoucherReq) (model.Voucher, error) {
+	voucher, err := s.GetOneVoucher(ctx, id)
+	if err != nil {
+		return voucher, err
+	}
+	syncVoucher(req, &voucher)
+	if err := s.checkVoucherTerms(ctx, voucher); err != nil {
+		return voucher, err
+	}
+	if err := s.repo.UpdateVoucher(ctx, &voucher, nil); err != nil {
+		return voucher, err
+	}
+	return voucher, nil
+}
+
+// DeleteVoucher stops the voucher from being redeemed, its redemptions stay in the report
+func (s *VoucherService) DeleteVoucher(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.GetOneVoucher(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteVoucher(ctx, id, nil)
+}
+
+func (s *VoucherService) GetListRedemption(ctx context.Context, req model.ListRedemptionReq) (model.ListRedemptionRes, error) {
+	if req.VoucherID != nil {
+		id, err := uuid.Parse(valid.String(req.VoucherID))
+		if err != nil {
+			return model.ListRedemptionRes{}, ginext.NewError(http.StatusBadRequest, "Wrong voucherId")
+		}
+		if _, err := s.GetOneVoucher(ctx, id); err != nil {
+			return model.ListRedemptionRes{}, err
+		}
+	}
+	companyID, err := scopeCompanyID(ctx, uuid.Nil)
+	if err != nil {
+		return model.ListRedemptionRes{}, err
+	}
+	if companyID != uuid.Nil {
+		req.CompanyID = valid.StringPointer(companyID.String())
+	}
+	return s.repo.GetListRedemption(ctx, req, nil)
+}
+
+func (s *VoucherService) GetVoucherReport(ctx context.Context, req model.VoucherReportReq) ([]model.VoucherReport, error) {
+	requested, _ := uuid.Parse(valid.String(req.CompanyID))
+	companyID, err := scopeCompanyID(ctx, requested)
+	if err != nil {
+		return nil, err
+	}
+	if companyID == uuid.Nil {
+		return nil, ginext.NewError(http.StatusBadRequest, "companyId is required")
+	}
+	req.CompanyID = valid.StringPointer(companyID.String())
+	return s.repo.GetVoucherReport(ctx, req, nil)
+}
+
+// syncVoucher copies the fields set in req onto the voucher, its company never changes
+func syncVoucher(req model.VoucherReq, voucher *model.Voucher) {
+	if req.Code != nil {
+		voucher.Code = normalizeVoucherCode(*req.Code)
+	}
+	if req.Name != nil {
+		voucher.Name = *req.Name
+	}
+	if req.Type != nil {
+		voucher.Type = *req.Type
+	}
+	if req.Value != nil {
+		voucher.Value = *req.Value
+	}
+	if req.MaxDiscount != nil {
+		voucher.MaxDiscount = *req.MaxDiscount
+	}
+	if req.MinTotal != nil {
+		voucher.MinTotal = *req.MinTotal
+	}
+	if req.StartAt != nil {
+		voucher.StartAt = req.StartAt
+	}
+	if req.EndAt != nil {
+		voucher.EndAt = req.EndAt
+	}
+	if req.UsageLimit != nil {
+		voucher.UsageLimit = *req.UsageLimit
+	}
+	if req.PerUserLimit != nil {
+		voucher.PerUserLimit = *req.PerUserLimit
+	}
+	if req.ParkingLotIds != nil {
+		voucher.ParkingLotIds = joinIDs(req.ParkingLotIds)
+	}
+	if req.TimeFrameIds != nil {
+		voucher.TimeFrameIds = joinIDs(req.TimeFrameIds)
+	}
+	if req.Status != nil {
+		voucher.Status = *req.Status
+	}
+}
+
+// checkVoucherTerms rejects terms that make no sense, and lots or time frames of another company
+func (s *VoucherService) checkVoucherTerms(ctx context.Context, voucher model.Voucher) error {
+	if voucher.Code == "" {
+		return ginext.NewError(http.StatusBadRequest, "Code is required")
+	}
+	switch voucher.Type {
+	case model.VOUCHER_PERCENT:
+		if voucher.Value <= 0 || voucher.Value > 100 {
+			return ginext.NewError(http.StatusBadRequest, "Percent must be between 0 and 100")
+		}
+	case model.VOUCHER_AMOUNT:
+		if voucher.Value <= 0 {
+			return ginext.NewError(http.StatusBadRequest, "Amount must be positive")
+		}
+	case model.VOUCHER_FIRST_BOOKING:
+	default:
+		return ginext.NewError(http.StatusBadRequest, "Type must be percent, amount or first_booking")
+	}
+	if voucher.Status != model.VOUCHER_ACTIVE && voucher.Status != model.VOUCHER_INACTIVE {
+		return ginext.NewError(http.StatusBadRequest, "Status must be active or inactive")
+	}
+	if voucher.MaxDiscount < 0 || voucher.MinTotal < 0 || voucher.UsageLimit < 0 || voucher.PerUserLimit < 0 {
+		return ginext.NewError(http.StatusBadRequest, "Limits must not be negative")
+	}
+	if voucher.StartAt != nil && voucher.EndAt != nil && !voucher.StartAt.Before(*voucher.EndAt) {
+		return ginext.NewError(http.StatusBadRequest, "Start must be before end")
+	}
+	for _, id := range splitIDs(voucher.ParkingLotIds) {
+		if err := s.checkVoucherLot(ctx, voucher.CompanyID, id); err != nil {
+			return err
+		}
+	}
+	for _, id := range splitIDs(voucher.TimeFrameIds) {
+		frame, err := s.repo.GetOneTimeframe(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.checkVoucherLot(ctx, voucher.CompanyID, frame.ParkingLotId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *VoucherService) checkVoucherLot(ctx context.Context, companyID uuid.UUID, lotID uuid.UUID) error {
+	lot, err := s.repo.GetOneParkingLot(ctx, lotID)
+	if err != nil {
+		return err
+	}
+	if lot.CompanyID != companyID {
+		return ginext.NewError(http.StatusBadRequest, "Parking lot "+lotID.String()+" belongs to another company")
+	}
+	return nil
+}
+
+// applyVoucher takes the discount of the voucher off the ticket about to be booked. It must run inside the
+// booking transaction: the voucher stays locked until commit, so its limits hold under concurrent bookings.
+func applyVoucher(ctx context.Context, rp repo.PGInterface, ticket *model.Ticket, code *string) (*model.Voucher, error) {
+	if code == nil || normalizeVoucherCode(*code) == "" {
+		return nil, nil
+	}
+	lot, err := rp.GetOneParkingLot(ctx, valid.UUID(ticket.ParkingLotId))
+	if err != nil {
+		return nil, err
+	}
+	voucher, err := rp.GetVoucherByCode(ctx, lot.CompanyID, normalizeVoucherCode(*code), nil)
+	if hasCode(err, http.StatusNotFound) {
+		return nil, ginext.NewError(http.StatusBadRequest, "Invalid voucher code")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRedeemable(ctx, rp, voucher, *ticket, time.Now()); err != nil {
+		return nil, err
+	}
+	discount := voucherDiscount(voucher, ticket.Price)
+	if discount <= 0 {
+		return nil, ginext.NewError(http.StatusBadRequest, "Voucher does not apply to the time frames of this booking")
+	}
+	ticket.Discount = discount
+	ticket.Total -= discount
+	ticket.VoucherId = &voucher.ID
+	return &voucher, nil
+}
+
+// checkRedeemable tells why the user cannot redeem the voucher on the ticket now, if they cannot
+func checkRedeemable(ctx context.Context, rp repo.PGInterface, voucher model.Voucher, ticket model.Ticket, now time.Time) error {
+	if voucher.Status != model.VOUCHER_ACTIVE ||
+		(voucher.StartAt != nil && now.Before(*voucher.StartAt)) || (voucher.EndAt != nil && !now.Before(*voucher.EndAt)) {
+		return ginext.NewError(http.StatusBadRequest, "Voucher is not valid at this time")
+	}
+	if lots := splitIDs(voucher.ParkingLotIds); len(lots) > 0 && !containsID(lots, valid.UUID(ticket.ParkingLotId)) {
+		return ginext.NewError(http.StatusBadRequest, "Voucher is not valid on this parking lot")
+	}
+	if ticket.Total < voucher.MinTotal {
+		return ginext.NewError(http.StatusBadRequest, "Booking total is below the minimum of the voucher")
+	}
+	if voucher.UsageLimit > 0 && voucher.Used >= voucher.UsageLimit {
+		return ginext.NewError(http.StatusConflict, "Voucher has been used up")
+	}
+	userID := valid.UUID(ticket.UserId)
+	if voucher.PerUserLimit > 0 {
+		used, err := rp.CountUserRedemptions(ctx, voucher.ID, userID, nil)
+		if err != nil {
+			return err
+		}
+		if used >= int64(voucher.PerUserLimit) {
+			return ginext.NewError(http.StatusConflict, "Voucher already used the most times allowed per user")
+		}
+	}
+	if voucher.Type == model.VOUCHER_FIRST_BOOKING {
+		booked, err := rp.CountUserBookings(ctx, userID, voucher.CompanyID, nil)
+		if err != nil {
+			return err
+		}
+		if booked > 0 {
+			return ginext.NewError(http.StatusConflict, "Voucher is only for the first booking")
+		}
+	}
+	return nil
+}
+
+// voucherDiscount is what the voucher takes off the quote, only on the time frames it is limited to if any
+func voucherDiscount(voucher model.Voucher, quote *model.PriceQuote) float64 {
+	if quote == nil {
+		return 0
+	}
+	base := quote.Total
+	if frames := splitIDs(voucher.TimeFrameIds); len(frames) > 0 {
+		base = 0
+		for _, item := range quote.Items {
+			if containsID(frames, item.TimeFrameId) {
+				base += item.Amount
+			}
+		}
+		// the items are priced before the rules and the daily cap of the lot
+		base = math.Min(base, quote.Total)
+	}
+	switch voucher.Type {
+	case model.VOUCHER_PERCENT:
+		discount := math.Round(base*voucher.Value) / 100
+		if voucher.MaxDiscount > 0 {
+			discount = math.Min(discount, voucher.MaxDiscount)
+		}
+		return discount
+	case model.VOUCHER_AMOUNT:
+		return math.Min(voucher.Value, base)
+	case model.VOUCHER_FIRST_BOOKING:
+		return base
+	}
+	return 0
+}
+
+// redeemVoucher records the use of the voucher applied to the ticket, once the ticket is created
+func redeemVoucher(ctx context.Context, rp repo.PGInterface, voucher *model.Voucher, ticket model.Ticket) error {
+	if voucher == nil {
+		return nil
+	}
+	if err := rp.CreateVoucherRedemption(ctx, &model.VoucherRedemption{
+		VoucherID: voucher.ID,
+		UserID:    valid.UUID(ticket.UserId),
+		TicketID:  ticket.ID,
+		Code:      voucher.Code,
+		Discount:  ticket.Discount,
+		Status:    model.REDEMPTION_REDEEMED,
+	}, nil); err != nil {
+		return err
+	}
+	return rp.AddVoucherUse(ctx, voucher.ID, 1, nil)
+}
+
+// releaseVoucher gives back the use of the voucher of a ticket that is cancelled or expired unpaid
+func releaseVoucher(ctx context.Context, rp repo.PGInterface, ticket model.Ticket) error {
+	if ticket.VoucherId == nil {
+		return nil
+	}
+	released, err := rp.ReleaseVoucherRedemption(ctx, ticket.ID, nil)
+	if err != nil || !released {
+		return err
+	}
+	return rp.AddVoucherUse(ctx, *ticket.VoucherId, -1, nil)
+}
+
+func normalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func joinIDs(ids []uuid.UUID) string {
+	var res []string
+	for _, id := range ids {
+		res = append(res, id.String())
+	}
+	return strings.Join(res, ",")
+}
+
+func splitIDs(ids string) []uuid.UUID {
+	var res []uuid.UUID
+	for _, s := range strings.Split(ids, ",") {
+		if id, err := uuid.Parse(strings.TrimSpace(s)); err == nil {
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
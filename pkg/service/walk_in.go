@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/utils"
+	"parking-server/pkg/valid"
+	"time"
+
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// openEnd stands for the unknown end of a walk-in ticket when looking for a free slot: the car holds the
+// slot until it checks out
+var openEnd = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// stayEnd is the end of the ticket, openEnd for a walk-in that has not checked out
+func stayEnd(ticket model.Ticket) time.Time {
+	if ticket.EndTime == nil {
+		return openEnd
+	}
+	return *ticket.EndTime
+}
+
+// CreateWalkInTicket checks a car in at the gate: the plate gets a guest vehicle, the lot a free slot, and
+// the clock starts now. The ticket has no end and is priced at check-out.
+func (s *TicketService) CreateWalkInTicket(ctx context.Context, req model.WalkInTicketReq) (*model.Ticket, error) {
+	lotID := valid.UUID(req.ParkingLotId)
+	if err := authorizeParkingLot(ctx, s.repo, lotID); err != nil {
+		return nil, err
+	}
+	number := utils.NormalizePlate(valid.String(req.Number))
+	if number == "" {
+		return nil, ginext.NewError(http.StatusBadRequest, "Plate number is required")
+	}
+
+	var ticket *model.Ticket
+	err := s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		vehicle, err := guestVehicle(ctx, rp, number, valid.String(req.Type))
+		if err != nil {
+			return err
+		}
+		parked, err := rp.HasOngoingTicket(ctx, vehicle.ID, nil)
+		if err != nil {
+			return err
+		}
+		if parked {
+			return ginext.NewError(http.StatusConflict, "Vehicle "+number+" is already parked")
+		}
+
+		now := time.Now()
+		slot, err := rp.GetFreeParkingSlot(ctx, lotID, valid.UUID(req.BlockId), now, openEnd, nil)
+		if err != nil {
+			return err
+		}
+		if slot == nil {
+			return ginext.NewError(http.StatusConflict, "Parking lot is full")
+		}
+		ticket = &model.Ticket{
+			VehicleId:     &vehicle.ID,
+			ParkingLotId:  &lotID,
+			ParkingSlotId: &slot.ID,
+			StartTime:     &now,
+			EntryTime:     &now,
+			State:         model.TicketStateOngoing,
+		}
+		if err := bookParkingSlot(ctx, rp, ticket); err != nil {
+			return err
+		}
+		ticket.Vehicle = vehicle
+		ticket.ParkingSlot = slot
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// guestVehicle returns the vehicle without owner registered for the plate, creating it on its first visit.
+// It must run inside a transaction: the vehicle stays locked until commit, so two gates cannot park it twice.
+func guestVehicle(ctx context.Context, rp repo.PGInterface, number, vehicleType string) (*model.Vehicle, error) {
+	if err := rp.CreateGuestVehicle(ctx, &model.Vehicle{
+		Name:   number,
+		Number: number,
+		Type:   vehicleType,
+	}, nil); err != nil {
+		return nil, err
+	}
+	vehicle, err := rp.GetGuestVehicle(ctx, number, nil)
+	if err != nil {
+		return nil, err
+	}
+	if vehicle == nil {
+		return nil, ginext.NewError(http.StatusInternalServerError, "Guest vehicle "+number+" was not created")
+	}
+	if vehicleType != "" && vehicle.Type != vehicleType {
+		vehicle.Type = vehicleType
+		if err := rp.UpdateVehicle(ctx, vehicle); err != nil {
+			return nil, err
+		}
+	}
+	return vehicle, nil
+}
+
+// walkInCharge prices the whole stay of a walk-in ticket with the time frames of the lot
+func walkInCharge(ctx context.Context, rp repo.PGInterface, ticket model.Ticket, exit time.Time) (model.TicketCharge, error) {
+	start := valid.DayTime(ticket.StartTime)
+	quote, err := quoteStay(ctx, rp, valid.UUID(ticket.ParkingLotId), start, exit)
+	if err != nil {
+		return model.TicketCharge{}, err
+	}
+	return model.TicketCharge{
+		TicketID:    ticket.ID,
+		Type:        model.CHARGE_WALK_IN,
+		StartTime:   valid.DayTimePointer(start),
+		EndTime:     valid.DayTimePointer(exit),
+		Amount:      quote.Total,
+		Description: "Walk-in stay priced with the time frames of the lot",
+	}, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"parking-server/pkg/model"
+	"parking-server/pkg/repo"
+	"parking-server/pkg/valid"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+type WalletService struct {
+	repo     repo.PGInterface
+	payments *PaymentService
+}
+
+func NewWalletService(repo repo.PGInterface, payments *PaymentService) WalletServiceInterface {
+	return &WalletService{repo: repo, payments: payments}
+}
+
+type WalletServiceInterface interface {
+	GetWallet(ctx context.Context) (model.Wallet, error)
+	TopUp(ctx context.Context, req model.TopUpReq) (model.Payment, error)
+	GetStatement(ctx context.Context, req model.ListWalletTransactionReq) (model.ListWalletTransactionRes, error)
+}
+
+// GetWallet returns the wallet of the caller, opening it on first use
+func (s *WalletService) GetWallet(ctx context.Context) (model.Wallet, error) {
+	principal, err := currentPrincipal(ctx)
+	if err != nil {
+		return model.Wallet{}, err
+	}
+	return s.repo.GetOrCreateWallet(ctx, principal.ID, s.payments.currency, nil)
+}
+
+// TopUp asks the provider for the amount, the wallet is credited once the payment succeeds
+func (s *WalletService) TopUp(ctx context.Context, req model.TopUpReq) (model.Payment, error) {
+	if req.Amount <= 0 {
+		return model.Payment{}, ginext.NewError(http.StatusBadRequest, "Amount must be positive")
+	}
+	wallet, err := s.GetWallet(ctx)
+	if err != nil {
+		return model.Payment{}, err
+	}
+	payment := model.Payment{
+		UserID:  &wallet.UserID,
+		Purpose: model.PAYMENT_FOR_TOP_UP,
+		Amount:  req.Amount,
+	}
+	if err := s.payments.requestPayment(ctx, &payment); err != nil {
+		return payment, err
+	}
+	return payment, nil
+}
+
+func (s *WalletService) GetStatement(ctx context.Context, req model.ListWalletTransactionReq) (model.ListWalletTransactionRes, error) {
+	wallet, err := s.GetWallet(ctx)
+	if err != nil {
+		return model.ListWalletTransactionRes{}, err
+	}
+	req.WalletID = valid.StringPointer(wallet.ID.String())
+	return s.repo.GetListWalletTransaction(ctx, req, nil)
+}
+
+// moveWallet changes the balance of the wallet by delta and books it against the counter account, as a
+// transaction of two entries summing to zero. It must run inside a transaction; a spend larger than the
+// balance fails with 402 and leaves the wallet untouched.
+func moveWallet(ctx context.Context, rp repo.PGInterface, walletID uuid.UUID, delta float64, counter string, transaction model.WalletTransaction) (model.WalletTransaction, error) {
+	balance, err := rp.AddWalletBalance(ctx, walletID, delta, nil)
+	if err != nil {
+		return transaction, err
+	}
+	transaction.WalletID = walletID
+	transaction.Amount = delta
+	transaction.BalanceAfter = balance
+	transaction.Entries = []model.LedgerEntry{
+		{Account: model.LEDGER_ACCOUNT_WALLET, WalletID: &walletID, Amount: delta},
+		{Account: counter, Amount: -delta},
+	}
+	if err := rp.CreateWalletTransaction(ctx, &transaction, nil); err != nil {
+		return transaction, err
+	}
+	return transaction, nil
+}
+
+// spendWallet pays amount from the wallet of the user for a ticket or a series
+func spendWallet(ctx context.Context, rp repo.PGInterface, userID uuid.UUID, amount float64, transaction model.WalletTransaction) error {
+	if amount <= 0 {
+		return nil
+	}
+	wallet, err := rp.GetWallet(ctx, userID, nil)
+	if err != nil {
+		return err
+	}
+	if wallet == nil {
+		return repo.ErrInsufficientBalance
+	}
+	transaction.Type = model.WALLET_TX_SPEND
+	_, err = moveWallet(ctx, rp, wallet.ID, -amount, model.LEDGER_ACCOUNT_PARKING, transaction)
+	return err
+}
+
+// chargeWallet takes what it can of amount from the wallet of the user, without failing when it falls short:
+// the rest is left for the gate to collect. It returns the amount taken.
+func chargeWallet(ctx context.Context, rp repo.PGInterface, userID uuid.UUID, amount float64, transaction model.WalletTransaction) (float64, error) {
+	wallet, err := rp.GetWallet(ctx, userID, nil)
+	if err != nil || wallet == nil || amount <= 0 {
+		return 0, err
+	}
+	amount = math.Min(amount, wallet.Balance)
+	if amount <= 0 {
+		return 0, nil
+	}
+	transaction.Type = model.WALLET_TX_SPEND
+	if _, err := moveWallet(ctx, rp, wallet.ID, -amount, model.LEDGER_ACCOUNT_PARKING, transaction); err != nil {
+		if hasCode(err, http.StatusPaymentRequired) {
+			// spent meanwhile
+			return 0, nil
+		}
+		return 0, err
+	}
+	return amount, nil
+}
+
+// creditTopUp credits a paid top up to the wallet of its user
+func creditTopUp(ctx context.Context, rp repo.PGInterface, payment model.Payment) error {
+	wallet, err := rp.GetOrCreateWallet(ctx, valid.UUID(payment.UserID), payment.Currency, nil)
+	if err != nil {
+		return err
+	}
+	_, err = moveWallet(ctx, rp, wallet.ID, payment.Amount, model.LEDGER_ACCOUNT_TOP_UP, model.WalletTransaction{
+		Type:        model.WALLET_TX_TOP_UP,
+		PaymentID:   &payment.ID,
+		Description: "Nạp tiền vào ví",
+	})
+	return err
+}
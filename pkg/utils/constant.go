@@ -6,6 +6,15 @@ const (
 	SELLER_ROLE = 256
 )
 
+// principal types, they are also the roles checked by the route policy
+const (
+	PRINCIPAL_USER     = "user"
+	PRINCIPAL_COMPANY  = "company"
+	PRINCIPAL_EMPLOYEE = "employee"
+	PRINCIPAL_ADMIN    = "admin"
+	PRINCIPAL_SYSTEM   = "system" // background jobs, never authenticated
+)
+
 const (
 	LINK_IMAGE_RESIZE = "https://d3hr4eej8cfgwy.cloudfront.net/"
 )
@@ -24,6 +33,8 @@ const (
 )
 
 const (
-	EXPIRTE_TIME   = 3600 // s
-	JWT_SECRET_KEY = "PARKAR_SERCRET"
+	EXPIRTE_TIME             = 3600    // s
+	RESET_TOKEN_EXPIRTE_TIME = 600     // s
+	REFRESH_EXPIRTE_TIME     = 2592000 // s, 30 days
+	JWT_SECRET_KEY           = "PARKAR_SERCRET"
 )
@@ -1,14 +1,95 @@
 package utils
 
 import (
-	jwt2 "github.com/golang-jwt/jwt/v4"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"os"
+	"time"
+
+	jwt2 "github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
-func GenerateToken(userId string) (string, error) {
-	claims := jwt2.MapClaims{}
-	claims["id"] = userId
-	claims["exp"] = EXPIRTE_TIME
+type TokenClaims struct {
+	ID        string `json:"id"`
+	SessionID string `json:"sid"`
+	Type      string `json:"type"`
+	CompanyID string `json:"company_id,omitempty"`
+	Role      string `json:"role,omitempty"`
+	jwt2.RegisteredClaims
+}
+
+func GenerateToken(principal *Principal) (string, error) {
+	now := time.Now()
+	claims := TokenClaims{
+		ID:        principal.ID.String(),
+		SessionID: principal.SessionID.String(),
+		Type:      principal.Type,
+		Role:      principal.Role,
+		RegisteredClaims: jwt2.RegisteredClaims{
+			IssuedAt:  jwt2.NewNumericDate(now),
+			ExpiresAt: jwt2.NewNumericDate(now.Add(EXPIRTE_TIME * time.Second)),
+		},
+	}
+	secret, err := jwtSecret()
+	if err != nil {
+		return "", err
+	}
+	if principal.CompanyID != uuid.Nil {
+		claims.CompanyID = principal.CompanyID.String()
+	}
 	token := jwt2.NewWithClaims(jwt2.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv(JWT_SECRET_KEY)))
+	return token.SignedString(secret)
+}
+
+// ParseToken verifies signature, algorithm and expiry of a token minted by GenerateToken
+func ParseToken(tokenStr string) (*TokenClaims, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return nil, err
+	}
+	claims := &TokenClaims{}
+	parser := jwt2.NewParser(jwt2.WithValidMethods([]string{jwt2.SigningMethodHS256.Alg()}))
+	token, err := parser.ParseWithClaims(tokenStr, claims, func(token *jwt2.Token) (interface{}, error) {
+		return secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	// exp is optional in jwt, but every token we issue carries one
+	if !claims.VerifyExpiresAt(time.Now(), true) {
+		return nil, errors.New("token has no expiry")
+	}
+	if claims.ID == "" || claims.SessionID == "" || claims.Type == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return claims, nil
+}
+
+func jwtSecret() ([]byte, error) {
+	secret := os.Getenv(JWT_SECRET_KEY)
+	if secret == "" {
+		return nil, errors.New("missing " + JWT_SECRET_KEY + " env")
+	}
+	return []byte(secret), nil
+}
+
+// GenerateRefreshToken returns an opaque random token, only its HashToken is persisted
+func GenerateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -37,16 +39,48 @@ type Message struct {
 	MessageContent string `json:"message_content"`
 }
 
-func CurrentUser(c *http.Request) (uuid.UUID, error) {
-	userIdStr := c.Header.Get("x-user-id")
-	if strings.Contains(userIdStr, "|") {
-		userIdStr = strings.Split(userIdStr, "|")[0]
+// Principal is the authenticated caller, resolved from the access token by the auth middleware
+type Principal struct {
+	ID        uuid.UUID `json:"id"`
+	SessionID uuid.UUID `json:"sessionId"`
+	Type      string    `json:"type"`
+	CompanyID uuid.UUID `json:"companyId"`
+	Role      string    `json:"role"`
+}
+
+func (p *Principal) Is(types ...string) bool {
+	for _, t := range types {
+		if p.Type == t {
+			return true
+		}
 	}
-	res, err := uuid.Parse(userIdStr)
-	if err != nil {
-		return uuid.Nil, err
+	return false
+}
+
+const PrincipalKey = "x-principal"
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, PrincipalKey, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	if ctx == nil {
+		return nil, false
 	}
-	return res, nil
+	principal, ok := ctx.Value(PrincipalKey).(*Principal)
+	if !ok || principal == nil {
+		return nil, false
+	}
+	return principal, true
+}
+
+// CurrentUser returns the id of the authenticated caller. It never trusts the x-user-id header.
+func CurrentUser(c *http.Request) (uuid.UUID, error) {
+	principal, ok := PrincipalFromContext(c.Context())
+	if !ok {
+		return uuid.Nil, errors.New("missing authenticated principal")
+	}
+	return principal.ID, nil
 }
 
 func String(in string) *string {
@@ -107,6 +141,19 @@ func ConvertTimeFormatForReport(in time.Time) string {
 	)
 }
 
+// PlateNumberSQL is NormalizePlate of vehicle.number in SQL, to compare plate numbers in queries
+const PlateNumberSQL = `upper(regexp_replace(number, '[^[:alnum:]]', '', 'g'))`
+
+// NormalizePlate keeps the letters and digits of a plate number, upper cased: "51f-123.45" becomes "51F12345"
+func NormalizePlate(number string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, number)
+}
+
 func RemoveSpace(str string) string {
 	re := regexp.MustCompile(`\s+`)
 	out := re.ReplaceAllString(str, " ")